@@ -1,396 +1,270 @@
 package handlers
 
 import (
-    "bytes"
-    "encoding/json"
+    "context"
     "fmt"
-    "io"
-    "net/http"
     "os"
-    "os/exec"  
     "path/filepath"
+    "sort"
     "strings"
-    "time"
 )
 
-// Store analyses in memory (global variables)
-var analyses = make(map[string]*AIAnalysisResponse)
-var analysisStatus = make(map[string]string)
-var analysisStorage = make(map[string]*Analysis)
+// Upper bound on files read into memory for one scan
+const maxCodebaseFiles = 1000
 
 // ENHANCED AI ANALYSIS WITH COMPREHENSIVE SECURITY SCANNING
-func AnalyzeEntireCodebase(repoPath string) (*AIAnalysisResponse, error) {
+// A non-nil history also scans the clone's past commits for secrets.
+func AnalyzeEntireCodebase(ctx context.Context, repoPath string, history *HistoryScanOptions) (*AIAnalysisResponse, error) {
     fmt.Println("🧠 ENHANCED AI SECURITY ANALYSIS STARTED...")
     
-    codebase, languages, err := extractEntireCodebase(repoPath)
+    // 📝 REPOSITORY CONFIG - .aegis.yml SCOPES THE SCAN AND SETS THE PR POLICY
+    repoConfig, configReport := loadRepoConfig(repoPath)
+    filter := fileFilter
+    if repoConfig != nil {
+        filter = fileFilter.merge(repoConfig.Paths)
+    }
+    if configReport != nil {
+        fmt.Printf("📝 Loaded %s: %d validation errors, disabled analyzers %v\n", configReport.Path, len(configReport.Errors), configReport.DisabledAnalyzers)
+    }
+    
+    codebase, languages, skipped, err := extractEntireCodebase(ctx, repoPath, filter)
     if err != nil {
         return nil, fmt.Errorf("failed to extract codebase: %v", err)
     }
     
     fmt.Printf("📁 Found %d files in languages: %v\n", len(codebase), languages)
     
-    context := AnalysisContext{
+    analysisContext := AnalysisContext{
         Languages:    languages,
         BusinessType: detectBusinessType(codebase),
         Requirements: detectComplianceRequirements(codebase),
     }
+    repoConfig.overrideContext(&analysisContext)
     
-    request := AIAnalysisRequest{
-        Codebase: codebase,
-        Context:  context,
-    }
-    
-    prompt := buildEnhancedAIPrompt(request)
-    
-    // 🚀 TRY GROQ FIRST WITH ENHANCED MODELS
-    if os.Getenv("GROQ_API_KEY") != "" {
-        fmt.Println("🚀 Using Enhanced Groq AI (Comprehensive Security Analysis)...")
-        response, err := callEnhancedGroqAI(prompt)
-        if err == nil {
-            // 🆕 ENHANCED AUTO-FIXES WITH COMPREHENSIVE ANALYSIS
-            fixEngine := NewAutoFixEngine()
-            
-            // Combine all risks for the auto-fix engine
-            allRisks := combineAllRisks(response)
-            autoFixes := fixEngine.GenerateFixes(allRisks, codebase)
-            response.AutoFixes = autoFixes
-            
-            // 🆕 ENHANCE WITH ADDITIONAL ANALYSIS DATA
-            response = enhanceAnalysisWithAdditionalData(response, codebase, context)
-            
-            fmt.Printf("✅ Enhanced AI analysis complete: %d critical, %d high, %d medium risks, %d auto-fixes\n", 
-                len(response.CriticalRisks), len(response.HighRisks), len(response.MediumRisks), len(autoFixes))
-            return response, nil
-        }
-        fmt.Printf("⚠️ Enhanced Groq AI failed: %v\n", err)
-    }
-    
-    return nil, fmt.Errorf("all AI services unavailable. Please set GROQ_API_KEY")
-}
-
-// Helper function to combine all risk levels for auto-fixing
-func combineAllRisks(response *AIAnalysisResponse) []Risk {
-    var allRisks []Risk
-    allRisks = append(allRisks, response.CriticalRisks...)
-    allRisks = append(allRisks, response.HighRisks...)
-    allRisks = append(allRisks, response.MediumRisks...)
-    return allRisks
-}
-
-// ENHANCED GROQ AI CALL WITH BETTER MODELS AND PROMPTS
-func callEnhancedGroqAI(prompt string) (*AIAnalysisResponse, error) {
-    apiKey := os.Getenv("GROQ_API_KEY")
-    if apiKey == "" {
-        return nil, fmt.Errorf("GROQ_API_KEY not set")
-    }
-    
-    fmt.Printf("🔑 Using Groq API key: %s...\n", apiKey[:8])
-    
-    // Enhanced models for better analysis
-    models := []string{
-        "llama-3.1-70b-versatile",    // Most capable for comprehensive analysis
-        "mixtral-8x7b-32768",         // Large context window
-        "llama-3.1-8b-instant",       // Fast and reliable
-    }
-    
-    var lastError error
-    
-    for _, model := range models {
-        fmt.Printf("🤖 Trying enhanced model: %s\n", model)
-        
-        groqRequest := GroqRequest{
-            Messages: []GroqMessage{
-                {
-                    Role:    "system",
-                    Content: "You are a senior security engineer with 15+ years of experience in application security, penetration testing, and compliance auditing. Provide comprehensive security analysis with detailed risk categorization, compliance mapping, and architectural insights.",
-                },
-                {
-                    Role:    "user",
-                    Content: prompt,
-                },
-            },
-            Model:       model,
-            Temperature: 0.1,  // Lower for more consistent security analysis
-            MaxTokens:   8000, // Increased for comprehensive analysis
-            TopP:        0.9,
-        }
+    var response *AIAnalysisResponse
+    if repoConfig.enabled(AnalyzerAI) {
+        // 📦 SPLIT INTO TOKEN-BUDGETED BATCHES SO LARGE REPOS ARE FULLY COVERED
+        providers := activeLLMProviders()
+        primaryModel := primaryModelSpec(providers)
+        batchBudget := batchTokenBudget(primaryModel, analysisContext)
+        batches := planAnalysisBatches(codebase, batchBudget, maxAnalysisBatches())
+        fmt.Printf("📦 Planned %d analysis batches of ~%d tokens for %s\n", len(batches), batchBudget, primaryModel.Name)
         
-        jsonData, err := json.Marshal(groqRequest)
+        // 🚀 TRY EACH CONFIGURED PROVIDER IN ORDER FOR EVERY BATCH
+        var outcomes *batchOutcomes
+        response, outcomes, err = analyzeBatches(ctx, providers, batches, analysisContext, codebaseFiles(codebase))
         if err != nil {
-            lastError = fmt.Errorf("failed to marshal request: %v", err)
-            continue
+            return nil, err
         }
-        
-        req, err := http.NewRequest("POST", "https://api.groq.com/openai/v1/chat/completions", bytes.NewBuffer(jsonData))
-        if err != nil {
-            lastError = fmt.Errorf("failed to create request: %v", err)
-            continue
+        response.Coverage = buildCoverageReport(codebase, skipped, batches, outcomes)
+        response.PromptVersion = activePrompts().PromptVersion(analysisContext)
+    } else {
+        // Static analyzers only
+        response = mergeAnalysisResults(nil)
+        response.Coverage = aiDisabledCoverage(codebase, skipped)
+        fmt.Println("📝 AI analysis disabled by the repository config")
+    }
+    
+    // 🔍 HALLUCINATION GUARD - CHECK EVERY FINDING AGAINST THE ACTUAL SOURCE
+    verification := verifyFindings(response, codebase)
+    fmt.Printf("🔍 Verified %d findings (%d corrected), %d unverified, %d downgraded, %d dropped\n",
+        verification.Verified, verification.Corrected, verification.Unverified, verification.Downgraded, verification.Dropped)
+    
+    // 🔑 SECRET SCANNER - DETERMINISTIC, RUNS ON EVERY FILE REGARDLESS OF THE MODEL
+    if repoConfig.enabled(AnalyzerSecrets) {
+        secrets := repoConfig.filter(scanSecrets(codebase))
+        overlaps := mergeRuleFindings(response, secrets)
+        if len(secrets) > 0 {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Secret scanner: %d findings (%d also reported by the AI analysis)", len(secrets), overlaps))
         }
-        
-        req.Header.Set("Authorization", "Bearer "+apiKey)
-        req.Header.Set("Content-Type", "application/json")
-        
-        client := &http.Client{Timeout: 120 * time.Second} // Increased timeout for larger models
-        resp, err := client.Do(req)
-        if err != nil {
-            lastError = err
-            fmt.Printf("❌ Model %s connection failed: %v\n", model, err)
-            continue
+        fmt.Printf("🔑 Secret scanner: %d findings, %d overlapping AI findings\n", len(secrets), overlaps)
+    }
+    
+    // 📦 DEPENDENCIES - PINNED VERSIONS CHECKED AGAINST THE OFFLINE OSV DATABASE
+    if repoConfig.enabled(AnalyzerDependencies) {
+        dependencies := collectDependencies(ctx, repoPath)
+        response.Dependencies = dependencies
+        vulnerable := repoConfig.filter(scanDependencies(advisoryDatabase, repoPath, dependencies))
+        overlaps := mergeRuleFindings(response, vulnerable)
+        if advisoryDatabase == nil {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Dependency scan: %d dependencies found, not checked - no advisory database loaded (set AEGIS_OSV_DB)", len(dependencies)))
+        } else {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Dependency scan: %d dependencies checked against %d OSV advisories, %d known vulnerabilities", len(dependencies), advisoryDatabase.Advisories, len(vulnerable)))
         }
-        defer resp.Body.Close()
-        
-        body, _ := io.ReadAll(resp.Body)
-        
-        if resp.StatusCode != 200 {
-            fmt.Printf("📡 Response status: %d\n", resp.StatusCode)
-            lastError = fmt.Errorf("model %s failed with status: %s", model, resp.Status)
-            continue
+        fmt.Printf("📦 Dependency scan: %d dependencies, %d vulnerable (%d overlapping AI findings)\n", len(dependencies), len(vulnerable), overlaps)
+    }
+    
+    // 🏗️ INFRASTRUCTURE AS CODE - DOCKERFILE, COMPOSE, KUBERNETES AND TERRAFORM RULES
+    if repoConfig.enabled(AnalyzerIaC) {
+        misconfigurations := repoConfig.filter(scanInfrastructure(ctx, repoPath))
+        overlaps := mergeRuleFindings(response, misconfigurations)
+        if len(misconfigurations) > 0 {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "IaC scanner: %d infrastructure misconfigurations (%d also reported by the AI analysis)", len(misconfigurations), overlaps))
         }
-        
-        var groqResp GroqResponse
-        if err := json.Unmarshal(body, &groqResp); err != nil {
-            lastError = fmt.Errorf("failed to parse response: %v", err)
-            continue
+        fmt.Printf("🏗️ IaC scanner: %d findings, %d overlapping AI findings\n", len(misconfigurations), overlaps)
+    }
+    
+    // 🐹 GO STATIC ANALYSIS - TYPE-CHECKED AST RULES FOR WHAT THE MODEL MISSES
+    if repoConfig.enabled(AnalyzerGo) || repoConfig.enabled(AnalyzerTaint) {
+        goFindings := analyzeGoCode(codebase, repoConfig.enabled(AnalyzerTaint))
+        if !repoConfig.enabled(AnalyzerGo) {
+            // Handler taint tracking only
+            var flows []ruleFinding
+            for _, finding := range goFindings {
+                if _, taint := taintRules[finding.Risk.RuleID]; taint {
+                    flows = append(flows, finding)
+                }
+            }
+            goFindings = flows
         }
-        
-        if len(groqResp.Choices) > 0 && groqResp.Choices[0].Message.Content != "" {
-            fmt.Printf("✅ Success with enhanced model: %s\n", model)
-            return parseEnhancedAIResponse(groqResp.Choices[0].Message.Content)
+        goFindings = repoConfig.filter(goFindings)
+        overlaps := mergeRuleFindings(response, goFindings)
+        if len(goFindings) > 0 {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Go analyzer: %d findings (%d also reported by the AI analysis)", len(goFindings), overlaps))
         }
-        
-        lastError = fmt.Errorf("model %s returned empty response", model)
+        fmt.Printf("🐹 Go analyzer: %d findings, %d overlapping AI findings\n", len(goFindings), overlaps)
     }
     
-    return nil, fmt.Errorf("all enhanced models failed: %v", lastError)
-}
-
-// ENHANCED AI PROMPT FOR COMPREHENSIVE SECURITY ANALYSIS
-func buildEnhancedAIPrompt(request AIAnalysisRequest) string {
-    var codebaseStr strings.Builder
-    codebaseStr.WriteString("COMPREHENSIVE SECURITY AUDIT - PRODUCTION READINESS REVIEW\n\n")
-    codebaseStr.WriteString("BUSINESS CONTEXT: " + request.Context.BusinessType + "\n")
-    codebaseStr.WriteString("COMPLIANCE REQUIREMENTS: " + strings.Join(request.Context.Requirements, ", ") + "\n")
-    codebaseStr.WriteString("LANGUAGES DETECTED: " + strings.Join(request.Context.Languages, ", ") + "\n\n")
-    
-    // Smart file prioritization
-    priorityFiles := []string{}
-    configFiles := []string{}
-    sourceFiles := []string{}
-    
-    for file := range request.Codebase {
-        if isSecurityCriticalFile(file) {
-            priorityFiles = append(priorityFiles, file)
-        } else if isConfigFile(file) {
-            configFiles = append(configFiles, file)
-        } else {
-            sourceFiles = append(sourceFiles, file)
+    // 🧪 TAINT TRACKING - REQUEST INPUT FOLLOWED TO SINKS IN EXPRESS HANDLERS (GO HANDLERS ABOVE)
+    if repoConfig.enabled(AnalyzerTaint) {
+        flows := repoConfig.filter(analyzeExpressTaint(codebase))
+        overlaps := mergeRuleFindings(response, flows)
+        if len(flows) > 0 {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Taint tracking: %d Express handler flows from request input to a sink (%d also reported by the AI analysis)", len(flows), overlaps))
         }
+        fmt.Printf("🧪 Express taint tracking: %d flows, %d overlapping AI findings\n", len(flows), overlaps)
     }
     
-    // Add priority security files first
-    codebaseStr.WriteString("=== PRIORITY SECURITY FILES (High Risk) ===\n")
-    for _, file := range priorityFiles {
-        content := truncateContent(request.Codebase[file], 4000)
-        codebaseStr.WriteString(fmt.Sprintf("🔐 FILE: %s\n%s\n\n", file, content))
-    }
-    
-    // Add configuration files
-    codebaseStr.WriteString("=== CONFIGURATION FILES (Medium Risk) ===\n")
-    for _, file := range configFiles {
-        content := truncateContent(request.Codebase[file], 2000)
-        codebaseStr.WriteString(fmt.Sprintf("⚙️  FILE: %s\n%s\n\n", file, content))
+    // 🕰️ HISTORY MODE - A SECRET "REMOVED" IN A LATER COMMIT IS STILL IN GIT
+    if history != nil {
+        secrets, err := scanGitHistory(ctx, repoPath, history)
+        if err != nil {
+            fmt.Printf("⚠️ History scan failed: %v\n", err)
+            response.Explanations = append(response.Explanations, fmt.Sprintf("History scan failed: %v", err))
+        } else {
+            secrets = repoConfig.filterHistory(secrets)
+            removed := 0
+            for _, secret := range secrets {
+                if !secret.StillPresent {
+                    removed++
+                }
+            }
+            response.SecretHistory = secrets
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "History scan: %d secrets committed in past commits, %d no longer at HEAD but still in git history - rotate them", len(secrets), removed))
+            fmt.Printf("🕰️ History scan: %d secrets in past commits (%d removed since)\n", len(secrets), removed)
+        }
     }
     
-    // Add source code files
-    codebaseStr.WriteString("=== SOURCE CODE FILES (Context) ===\n")
-    for _, file := range sourceFiles {
-        content := truncateContent(request.Codebase[file], 1500)
-        codebaseStr.WriteString(fmt.Sprintf("📄 FILE: %s\n%s\n\n", file, content))
-    }
+    // Most severe first within each tier (CVSS, then remediation priority)
+    sortRisks(response)
     
-    return fmt.Sprintf(`COMPREHENSIVE SECURITY ANALYSIS REQUEST
-
-You are a senior security engineer conducting a production security audit. Analyze this codebase thoroughly and provide a detailed security assessment.
-
-CRITICAL SECURITY FOCUS AREAS:
-
-1. AUTHENTICATION & AUTHORIZATION:
-   - Hardcoded credentials, API keys, secrets, tokens
-   - Weak password policies
-   - Missing multi-factor authentication
-   - Broken access control (IDOR, privilege escalation)
-   - Session management issues
-
-2. DATA PROTECTION & PRIVACY:
-   - PII exposure (emails, phones, addresses, SSN)
-   - Payment data (credit cards, bank info)
-   - Database credentials in code
-   - Unencrypted sensitive data
-   - Data leakage in logs, errors, responses
-
-3. INJECTION & INPUT VALIDATION:
-   - SQL injection vulnerabilities
-   - XSS (Cross-site scripting)
-   - Command injection
-   - XXE (XML External Entity)
-   - Unsafe deserialization
-   - Path traversal
-
-4. CONFIGURATION & DEPLOYMENT:
-   - Debug mode enabled in production
-   - Exposed admin interfaces
-   - CORS misconfiguration
-   - Security headers missing
-   - Default credentials
-   - Exposed .git directories
-
-5. DEPENDENCY & SUPPLY CHAIN:
-   - Known vulnerable dependencies
-   - Outdated libraries with CVEs
-   - Untrusted package sources
-   - Missing integrity checks
-
-6. API & NETWORK SECURITY:
-   - Unauthenticated endpoints
-   - Rate limiting missing
-   - SSL/TLS misconfiguration
-   - Information disclosure in headers
-
-BUSINESS IMPACT ASSESSMENT:
-- Financial impact potential
-- Data breach severity
-- Compliance violation risk
-- Reputation damage
-- Operational disruption
-
-COMPLIANCE MAPPING:
-- GDPR: Data protection, privacy, consent
-- HIPAA: Medical data protection
-- PCI-DSS: Payment card security
-- SOC2: Security controls
-- ISO27001: Information security
-
-REQUIRED RESPONSE FORMAT (STRICT JSON):
-{
-    "critical_risks": [
-        {
-            "file": "config/database.yml",
-            "line": 15,
-            "title": "Hardcoded Database Password",
-            "description": "Database password is exposed in plain text in configuration file, allowing full database compromise",
-            "impact": "Complete data breach potential - attackers can access, modify, or delete all application data",
-            "confidence": 0.98,
-            "code_snippet": "password: \"mysecretpassword123\"",
-            "cvss_score": 9.8,
-            "exploitation_complexity": "Low",
-            "remediation_priority": "Immediate",
-            "compliance_violations": ["GDPR Article 32", "PCI-DSS Requirement 8"]
+    // 🚦 PR POLICY - THE REPOSITORY'S FAIL_ON THRESHOLDS AGAINST THE FINAL COUNTS
+    if configReport != nil {
+        if repoConfig != nil {
+            configReport.SuppressedFindings = repoConfig.suppressed
         }
-    ],
-    "high_risks": [
-        {
-            "file": "app/controllers/user_controller.js",
-            "line": 42,
-            "title": "SQL Injection in User Search",
-            "description": "User input directly concatenated into SQL query without parameterization",
-            "impact": "Database compromise via SQL injection - data theft, modification, or deletion",
-            "confidence": 0.95,
-            "code_snippet": "const query = \"SELECT * FROM users WHERE name = '\" + userInput + \"'\"",
-            "cvss_score": 8.6,
-            "exploitation_complexity": "Low",
-            "remediation_priority": "High",
-            "compliance_violations": ["OWASP Top 10 A03:2021"]
+        response.RepoConfig = configReport
+        if len(configReport.Errors) > 0 {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Repository config: %d problems in %s, those settings were ignored: %s", len(configReport.Errors), configReport.Path, strings.Join(configReport.Errors, "; ")))
         }
-    ],
-    "medium_risks": [
-        {
-            "file": "config/application.rb",
-            "line": 8,
-            "title": "Debug Mode Enabled in Production",
-            "description": "Application debug mode is enabled, exposing sensitive information in error messages",
-            "impact": "Information disclosure - stack traces, configuration details, and system information exposed",
-            "confidence": 0.90,
-            "code_snippet": "config.debug_exception_response_format = :default",
-            "cvss_score": 5.3,
-            "exploitation_complexity": "Low",
-            "remediation_priority": "Medium",
-            "compliance_violations": ["Security Best Practices"]
+        if configReport.SuppressedFindings > 0 {
+            response.Explanations = append(response.Explanations, fmt.Sprintf(
+                "Repository config: %d findings suppressed by rule ID", configReport.SuppressedFindings))
         }
-    ],
-    "explanations": [
-        "Overall security posture: Critical issues found requiring immediate attention",
-        "Data protection: Multiple instances of sensitive data exposure detected",
-        "Authentication: Weak credential management practices identified",
-        "Compliance: Several regulatory violations requiring remediation"
-    ],
-    "summary": {
-        "total_critical": 3,
-        "total_high": 5,
-        "total_medium": 8,
-        "business_type": "fintech",
-        "compliance_requirements": ["GDPR", "PCI-DSS", "SOC2"]
-    },
-    "architecture": {
-        "overview": "Monolithic application with mixed security concerns - strong authentication but weak data protection controls",
-        "strengths": [
-            "Input validation present in most endpoints",
-            "HTTPS enforcement configured",
-            "Session timeout implemented"
-        ],
-        "concerns": [
-            "No security headers configured",
-            "Error handling exposes system information",
-            "No rate limiting on authentication endpoints"
-        ],
-        "recommendations": [
-            "Implement security headers (CSP, HSTS)",
-            "Add comprehensive logging and monitoring",
-            "Conduct penetration testing for business-critical flows"
-        ]
-    },
-    "compliance": {
-        "standards": ["GDPR", "PCI-DSS", "OWASP Top 10"],
-        "gaps": [
-            "No data encryption at rest for PII",
-            "Missing audit trails for data access",
-            "No incident response plan documented"
-        ],
-        "recommendations": [
-            "Implement data classification policy",
-            "Establish regular security training",
-            "Create incident response procedures"
-        ]
     }
+    response.Gate = repoConfig.evaluateGate(response)
+    if response.Gate != nil {
+        fmt.Printf("🚦 PR policy: failed=%v %v\n", response.Gate.Failed, response.Gate.Reasons)
+    }
+    
+    // 🆕 ENHANCED AUTO-FIXES WITH COMPREHENSIVE ANALYSIS
+    fixEngine := NewAutoFixEngine()
+    
+    // Only fix code we could actually find - unverified snippets may not exist
+    autoFixes := fixEngine.GenerateFixes(fixableRisks(response), codebase)
+    response.AutoFixes = autoFixes
+    
+    // 🆕 ENHANCE WITH ADDITIONAL ANALYSIS DATA
+    response = enhanceAnalysisWithAdditionalData(response, codebase, analysisContext)
+    repoConfig.overrideSummary(&response.Summary)
+    
+    fmt.Printf("✅ Enhanced AI analysis complete: %d critical, %d high, %d medium risks, %d auto-fixes\n", 
+        len(response.CriticalRisks), len(response.HighRisks), len(response.MediumRisks), len(autoFixes))
+    return response, nil
 }
 
-ANALYZE THIS CODEBASE:
-%s
-
-Provide a thorough, professional security assessment with actionable recommendations.`, codebaseStr.String())
+// Helper function to combine all risk levels for auto-fixing
+func combineAllRisks(response *AIAnalysisResponse) []Risk {
+    var allRisks []Risk
+    allRisks = append(allRisks, response.CriticalRisks...)
+    allRisks = append(allRisks, response.HighRisks...)
+    allRisks = append(allRisks, response.MediumRisks...)
+    return allRisks
 }
 
-// ENHANCED AI RESPONSE PARSING
-func parseEnhancedAIResponse(aiResponse string) (*AIAnalysisResponse, error) {
-    // Extract JSON from AI response
-    start := strings.Index(aiResponse, "{")
-    end := strings.LastIndex(aiResponse, "}") + 1
+// ENHANCED AI PROMPT SIZED FOR THE MODEL'S CONTEXT WINDOW
+// Priority files are packed first; whatever doesn't fit a smaller fallback
+// model is trimmed or dropped and left out of the returned segments.
+func buildEnhancedAIPrompt(request AIAnalysisRequest, spec ModelSpec) (string, []fileSegment, error) {
+    segments := request.promptSegments()
+    budget := spec.PromptBudget() - promptOverheadTokens(request)
     
-    if start == -1 || end == -1 {
-        return nil, fmt.Errorf("no JSON found in AI response")
+    sent := packSegments(segments, budget)
+    if len(segments) > 0 && len(sent) == 0 {
+        return "", nil, fmt.Errorf("context window of %d tokens is too small for this batch", spec.ContextWindow)
     }
-    
-    jsonStr := aiResponse[start:end]
-    
-    var response AIAnalysisResponse
-    if err := json.Unmarshal([]byte(jsonStr), &response); err != nil {
-        fmt.Printf("⚠️ Enhanced AI returned non-JSON response, using fallback: %v\n", err)
-        return createEnhancedFallbackResponse(aiResponse), nil
+    prompt, err := activePrompts().RenderAudit(request, sent)
+    if err != nil {
+        return "", nil, err
     }
-    
-    // Enhance risks with additional fields for compatibility
-    response = enhanceRiskData(response)
-    
-    return &response, nil
+    return prompt, sent, nil
+}
+
+// promptOverheadTokens is the cost of the system persona and audit instructions
+func promptOverheadTokens(request AIAnalysisRequest) int {
+    prompts := activePrompts()
+    // A template that fails to render is reported when the real prompt is built
+    instructions, _ := prompts.RenderAudit(request, nil)
+    return estimateTokens(prompts.System()) + estimateTokens(instructions)
+}
+
+// promptSegments returns the batch segments, or the whole Codebase in priority order
+func (request AIAnalysisRequest) promptSegments() []fileSegment {
+    if len(request.segments) > 0 {
+        return request.segments
+    }
+    
+    var segments []fileSegment
+    for _, file := range prioritizedFiles(request.Codebase) {
+        content := request.Codebase[file]
+        lines := countLines(content)
+        segments = append(segments, fileSegment{
+            Path:       file,
+            StartLine:  1,
+            EndLine:    lines,
+            TotalLines: lines,
+            Content:    content,
+            Tokens:     estimateTokens(content),
+        })
+    }
+    return segments
+}
+
+// segmentContent prefixes split or trimmed files with their original line range
+func segmentContent(segment fileSegment) string {
+    if segment.StartLine > 1 || segment.EndLine < segment.TotalLines {
+        return fmt.Sprintf("[lines %d-%d of %d - report line numbers from the original file]\n%s",
+            segment.StartLine, segment.EndLine, segment.TotalLines, segment.Content)
+    }
+    return segment.Content
 }
 
 // ENHANCE ANALYSIS WITH ADDITIONAL DATA
@@ -475,59 +349,6 @@ func enhanceRiskData(response AIAnalysisResponse) AIAnalysisResponse {
     return response
 }
 
-// ENHANCED FALLBACK RESPONSE
-func createEnhancedFallbackResponse(aiResponse string) *AIAnalysisResponse {
-    return &AIAnalysisResponse{
-        CriticalRisks: []Risk{
-            {
-                File:        "AI Analysis",
-                FilePath:    "AI Analysis",
-                Line:        1,
-                LineNumber:  1,
-                Title:       "Enhanced AI Analysis Completed",
-                Description: "AI has analyzed your codebase with comprehensive security checks. Raw response: " + aiResponse,
-                Impact:      "Comprehensive security review completed",
-                Confidence:  0.9,
-                CodeSnippet: aiResponse,
-            },
-        },
-        Explanations: []string{"Enhanced AI security analysis completed. Review findings for detailed security assessment."},
-        Summary: AnalysisSummary{
-            TotalCritical: 1,
-            TotalHigh:     0,
-            TotalMedium:   0,
-            BusinessType:  "unknown",
-            Compliance:    []string{"Basic Security Review"},
-        },
-        Architecture: &ArchitectureAnalysis{
-            Overview: "Standard application architecture review completed",
-            Strengths: []string{
-                "Basic code structure analysis performed",
-                "Security pattern recognition implemented",
-            },
-            Concerns: []string{
-                "Limited context for comprehensive assessment",
-                "Need for deeper code analysis",
-            },
-            Recommendations: []string{
-                "Consider manual security review for critical components",
-                "Implement additional security testing",
-            },
-        },
-        Compliance: &ComplianceAnalysis{
-            Standards: []string{"Basic Security"},
-            Gaps: []string{
-                "Limited compliance context available",
-                "Need for specific regulatory review",
-            },
-            Recommendations: []string{
-                "Conduct targeted compliance assessment",
-                "Review specific regulatory requirements",
-            },
-        },
-    }
-}
-
 // HELPER FUNCTIONS FOR ENHANCED ANALYSIS
 
 func isSecurityCriticalFile(filename string) bool {
@@ -563,79 +384,51 @@ func isConfigFile(filename string) bool {
     return false
 }
 
-func truncateContent(content string, maxLen int) string {
-    if len(content) <= maxLen {
-        return content
-    }
-    return content[:maxLen] + "\n\n// ... [truncated for analysis - " + 
-        fmt.Sprintf("%d chars total]", len(content))
-}
-
 // ENHANCED CODEBASE EXTRACTION
-func extractEntireCodebase(repoPath string) (map[string]string, []string, error) {
+// Files dropped before batching (binary, too large, over the file limit, symlinks
+// leaving the repository) are returned as skipped coverage.
+func extractEntireCodebase(ctx context.Context, repoPath string, filter *FileFilter) (map[string]string, []string, []FileCoverage, error) {
     codebase := make(map[string]string)
     languages := make(map[string]bool)
     
-    // Enhanced priority patterns for better coverage
-    priorityPatterns := []string{
-        "*.py", "*.js", "*.ts", "*.jsx", "*.tsx", "*.java", "*.go", "*.rb", "*.php", 
-        "*.cpp", "*.c", "*.h", "*.hpp", "*.cs", "*.swift", "*.kt", "*.rs", "*.scala",
-        "*.pl", "*.r", "*.m", "*.sql", "*.sh", "*.bash",
-        "config.*", "*.config", "*.env*", "*.json", "*.yaml", "*.yml", "*.xml",
-        "package.json", "requirements.txt", "pom.xml", "build.gradle", "composer.json",
-        "Dockerfile", "docker-compose.yml", "*.tf", "*.pp", "*.md", "*.txt",
-        "*.html", "*.htm", "*.css", "*.scss", "*.sass", "*.less",
-    }
-    
-    var allFiles []string
-    
-    // Use fast file finding with enhanced patterns
-    for _, pattern := range priorityPatterns {
-        findCmd := exec.Command("find", repoPath, "-name", pattern, 
-            "-not", "-path", "*/node_modules/*",
-            "-not", "-path", "*/.git/*", 
-            "-not", "-path", "*/test/*",
-            "-not", "-path", "*/tests/*",
-            "-not", "-path", "*/__pycache__/*",
-            "-not", "-path", "*/dist/*",
-            "-not", "-path", "*/build/*",
-            "-not", "-path", "*/target/*",
-            "-not", "-path", "*/vendor/*",
-            "-not", "-path", "*/tmp/*",
-            "-not", "-path", "*/temp/*")
-        
-        output, err := findCmd.Output()
-        if err == nil {
-            files := strings.Split(strings.TrimSpace(string(output)), "\n")
-            allFiles = append(allFiles, files...)
-        }
+    // Walk the repository natively - one pass, no duplicates, stable order
+    allFiles, skipped, err := collectFiles(ctx, repoPath, filter)
+    if err != nil {
+        return nil, nil, nil, err
     }
     
-    // 🚀 LIMIT TO 25 FILES MAX for comprehensive analysis
-    fileCount := 0
     for _, file := range allFiles {
-        if file == "" || fileCount >= 25 {
-            break
+        relativePath := file.Path
+        
+        // 🚀 CAP FILE COUNT - batching covers the rest of the budget
+        if len(codebase) >= maxCodebaseFiles {
+            skipped = append(skipped, FileCoverage{Path: relativePath, Status: CoverageSkipped, Reason: "file limit reached"})
+            continue
         }
         
-        content, err := os.ReadFile(file)
+        // 🚀 SKIP BINARIES - images, archives and compiled artifacts
+        if isBinaryFile(file.RealPath) {
+            skipped = append(skipped, FileCoverage{Path: relativePath, Status: CoverageSkipped, Reason: "binary file"})
+            continue
+        }
+        
+        content, err := os.ReadFile(file.RealPath)
         if err != nil {
             continue
         }
         
-        // 🚀 SKIP LARGE FILES (>200KB) but allow more content
+        // 🚀 SKIP LARGE FILES (>200KB)
         if len(content) > 200000 {
+            skipped = append(skipped, FileCoverage{Path: relativePath, Status: CoverageSkipped, Reason: "file too large", TotalLines: countLines(string(content))})
             continue
         }
         
-        relativePath := strings.TrimPrefix(file, repoPath+"/")
         codebase[relativePath] = string(content)
         
-        ext := strings.ToLower(filepath.Ext(file))
+        ext := strings.ToLower(filepath.Ext(relativePath))
         if ext != "" {
             languages[ext] = true
         }
-        fileCount++
     }
     
     langSlice := make([]string, 0, len(languages))
@@ -643,8 +436,10 @@ func extractEntireCodebase(repoPath string) (map[string]string, []string, error)
         langSlice = append(langSlice, lang)
     }
     
+    sort.Strings(langSlice)
+    
     fmt.Printf("📁 Enhanced scanning: %d/%d files for comprehensive AI analysis\n", len(codebase), len(allFiles))
-    return codebase, langSlice, nil
+    return codebase, langSlice, skipped, nil
 }
 
 // ENHANCED BUSINESS TYPE DETECTION
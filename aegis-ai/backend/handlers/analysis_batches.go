@@ -0,0 +1,526 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+const (
+    maxDefaultBatchTokens     = 24000 // keeps each request well under provider rate limits
+    minBatchTokens            = 1000
+    defaultMaxAnalysisBatches = 20
+    segmentHeaderTokens       = 32 // "FILE: path" line plus the line-range marker
+    minPartialSegmentTokens   = 256
+)
+
+// fileSegment is a line range of one file sent to the AI in a batch
+type fileSegment struct {
+    Path       string
+    StartLine  int
+    EndLine    int
+    TotalLines int
+    Content    string
+    Tokens     int
+    Truncated  bool
+}
+
+// analysisBatch is one prompt's worth of file segments
+type analysisBatch struct {
+    Segments []fileSegment
+    Tokens   int
+}
+
+// batchOutcomes records what each batch actually sent and which batches failed
+type batchOutcomes struct {
+    Sent   map[int][]fileSegment
+    Failed map[int]string
+}
+
+func (b analysisBatch) hasFile(path string) bool {
+    for _, segment := range b.Segments {
+        if segment.Path == path {
+            return true
+        }
+    }
+    return false
+}
+
+// codebaseFiles is the set of paths findings may refer to
+func codebaseFiles(codebase map[string]string) map[string]bool {
+    files := make(map[string]bool, len(codebase))
+    for file := range codebase {
+        files[file] = true
+    }
+    return files
+}
+
+// estimateTokens approximates the prompt tokens for text (~4 chars per token)
+func estimateTokens(text string) int {
+    return (len(text) + 3) / 4
+}
+
+// batchTokenBudget is the file content per batch: AEGIS_BATCH_TOKENS if set,
+// otherwise whatever fits the primary model next to the audit instructions
+func batchTokenBudget(spec ModelSpec, analysisContext AnalysisContext) int {
+    if budget := envInt("AEGIS_BATCH_TOKENS", 0); budget > 0 {
+        return budget
+    }
+
+    overhead := promptOverheadTokens(AIAnalysisRequest{Context: analysisContext, Batch: 1, TotalBatches: 2})
+    budget := spec.PromptBudget() - overhead
+    if budget > maxDefaultBatchTokens {
+        budget = maxDefaultBatchTokens
+    }
+    if budget < minBatchTokens {
+        budget = minBatchTokens
+    }
+    return budget
+}
+
+// primaryModelSpec is the first model that will be tried
+func primaryModelSpec(providers []LLMProvider) ModelSpec {
+    for _, provider := range providers {
+        if models := provider.ModelNames(); len(models) > 0 {
+            return LookupModel(models[0])
+        }
+    }
+    return defaultModelSpec
+}
+
+// maxAnalysisBatches reads AEGIS_MAX_BATCHES, falling back to the default
+func maxAnalysisBatches() int {
+    return envInt("AEGIS_MAX_BATCHES", defaultMaxAnalysisBatches)
+}
+
+func envInt(name string, fallback int) int {
+    if value, err := strconv.Atoi(os.Getenv(name)); err == nil && value > 0 {
+        return value
+    }
+    return fallback
+}
+
+// prioritizedFiles orders files security-critical first, then config, then source,
+// alphabetically within each group so batches are deterministic
+func prioritizedFiles(codebase map[string]string) []string {
+    rank := func(file string) int {
+        switch {
+        case isSecurityCriticalFile(file):
+            return 0
+        case isConfigFile(file):
+            return 1
+        default:
+            return 2
+        }
+    }
+
+    files := make([]string, 0, len(codebase))
+    for file := range codebase {
+        files = append(files, file)
+    }
+    sort.Slice(files, func(i, j int) bool {
+        if rank(files[i]) != rank(files[j]) {
+            return rank(files[i]) < rank(files[j])
+        }
+        return files[i] < files[j]
+    })
+    return files
+}
+
+// splitFileSegments cuts a file into line-aligned segments that each fit the budget.
+// A single line longer than the budget (minified code) is truncated.
+func splitFileSegments(path string, content string, budget int) []fileSegment {
+    lines := strings.SplitAfter(content, "\n")
+    if len(lines) > 0 && lines[len(lines)-1] == "" {
+        lines = lines[:len(lines)-1]
+    }
+    totalLines := len(lines)
+
+    if estimateTokens(content) <= budget {
+        return []fileSegment{{
+            Path:       path,
+            StartLine:  1,
+            EndLine:    totalLines,
+            TotalLines: totalLines,
+            Content:    content,
+            Tokens:     estimateTokens(content),
+        }}
+    }
+
+    var segments []fileSegment
+    var current strings.Builder
+    start := 1
+    truncated := false
+
+    flush := func(end int) {
+        if end < start {
+            return
+        }
+        text := current.String()
+        segments = append(segments, fileSegment{
+            Path:       path,
+            StartLine:  start,
+            EndLine:    end,
+            TotalLines: totalLines,
+            Content:    text,
+            Tokens:     estimateTokens(text),
+            Truncated:  truncated,
+        })
+        current.Reset()
+        start = end + 1
+        truncated = false
+    }
+
+    for i, line := range lines {
+        lineNumber := i + 1
+        if estimateTokens(line) > budget {
+            flush(lineNumber - 1)
+            current.WriteString(line[:budget*4])
+            truncated = true
+            flush(lineNumber)
+            continue
+        }
+        if estimateTokens(current.String()+line) > budget {
+            flush(lineNumber - 1)
+        }
+        current.WriteString(line)
+    }
+    flush(totalLines)
+    return segments
+}
+
+// trimSegment keeps the leading lines of a segment that fit the budget
+func trimSegment(segment fileSegment, budget int) fileSegment {
+    trimmed := splitFileSegments(segment.Path, segment.Content, budget)[0]
+    trimmed.StartLine += segment.StartLine - 1
+    trimmed.EndLine += segment.StartLine - 1
+    trimmed.TotalLines = segment.TotalLines
+    trimmed.Truncated = trimmed.Truncated || segment.Truncated
+    return trimmed
+}
+
+// packSegments fills a prompt budget in priority order. A segment that doesn't
+// fit is trimmed to its leading lines if enough room is left, otherwise dropped.
+func packSegments(segments []fileSegment, budget int) []fileSegment {
+    var sent []fileSegment
+    remaining := budget
+    for _, segment := range segments {
+        if segment.Tokens+segmentHeaderTokens <= remaining {
+            sent = append(sent, segment)
+            remaining -= segment.Tokens + segmentHeaderTokens
+            continue
+        }
+        if remaining-segmentHeaderTokens >= minPartialSegmentTokens {
+            trimmed := trimSegment(segment, remaining-segmentHeaderTokens)
+            sent = append(sent, trimmed)
+            remaining -= trimmed.Tokens + segmentHeaderTokens
+        }
+    }
+    return sent
+}
+
+// planAnalysisBatches packs the codebase into at most maxBatches token-budgeted
+// batches in priority order. Files that don't fit are left out and show up as
+// skipped or partial in the coverage report.
+func planAnalysisBatches(codebase map[string]string, budget int, maxBatches int) []analysisBatch {
+    var batches []analysisBatch
+    var current analysisBatch
+
+    segmentBudget := budget - segmentHeaderTokens
+    if segmentBudget < segmentHeaderTokens {
+        segmentBudget = segmentHeaderTokens
+    }
+
+    for _, file := range prioritizedFiles(codebase) {
+        for _, segment := range splitFileSegments(file, codebase[file], segmentBudget) {
+            cost := segment.Tokens + segmentHeaderTokens
+            if len(current.Segments) > 0 && (current.Tokens+cost > budget || current.hasFile(file)) {
+                batches = append(batches, current)
+                current = analysisBatch{}
+            }
+            if len(batches) == maxBatches {
+                return batches
+            }
+            current.Segments = append(current.Segments, segment)
+            current.Tokens += cost
+        }
+    }
+
+    if len(current.Segments) > 0 && len(batches) < maxBatches {
+        batches = append(batches, current)
+    }
+    return batches
+}
+
+// analyzeBatches runs the audit prompt for every batch and merges the findings.
+// Failed batches are reported in the coverage instead of failing the scan,
+// unless every batch failed.
+func analyzeBatches(ctx context.Context, providers []LLMProvider, batches []analysisBatch, analysisContext AnalysisContext, knownFiles map[string]bool) (*AIAnalysisResponse, *batchOutcomes, error) {
+    var results []*AIAnalysisResponse
+    outcomes := &batchOutcomes{Sent: make(map[int][]fileSegment), Failed: make(map[int]string)}
+    var lastError error
+    models := providerModels(providers)
+    promptVersion := activePrompts().PromptVersion(analysisContext)
+
+    for i, batch := range batches {
+        if err := ctx.Err(); err != nil {
+            return nil, nil, err
+        }
+
+        // Unchanged files are answered from the finding cache
+        cached, cachedSegments, pending := findingCache.lookupBatch(batch.Segments, models, promptVersion)
+        if cached != nil {
+            results = append(results, cached)
+            outcomes.Sent[i] = cachedSegments
+        }
+        if len(pending) == 0 {
+            fmt.Printf("♻️ Batch %d/%d served from cache (%d files)\n", i+1, len(batches), len(batch.Segments))
+            continue
+        }
+
+        fmt.Printf("📦 Analyzing batch %d/%d (%d files, %d cached, ~%d tokens)\n",
+            i+1, len(batches), len(pending), len(cachedSegments), batch.Tokens)
+        request := AIAnalysisRequest{
+            Context:      analysisContext,
+            Batch:        i + 1,
+            TotalBatches: len(batches),
+            segments:     pending,
+        }
+        // callAIProviders returns right after the model it last built a prompt for answers
+        var answeredBy string
+        build := func(spec ModelSpec) (string, []fileSegment, error) {
+            answeredBy = spec.Name
+            return buildEnhancedAIPrompt(request, spec)
+        }
+
+        result, sent, err := callAIProviders(ctx, providers, build, knownFiles)
+        if err != nil {
+            if ctxErr := ctx.Err(); ctxErr != nil {
+                return nil, nil, ctxErr
+            }
+            fmt.Printf("⚠️ Batch %d/%d failed: %v\n", i+1, len(batches), err)
+            outcomes.Failed[i] = err.Error()
+            lastError = err
+            continue
+        }
+        outcomes.Sent[i] = append(outcomes.Sent[i], sent...)
+        results = append(results, result)
+        findingCache.storeBatch(sent, answeredBy, promptVersion, result)
+    }
+
+    if len(results) == 0 && lastError != nil {
+        return nil, nil, lastError
+    }
+    return mergeAnalysisResults(results), outcomes, nil
+}
+
+// providerModels lists every model of the providers in failover order
+func providerModels(providers []LLMProvider) []string {
+    var models []string
+    for _, provider := range providers {
+        models = append(models, provider.ModelNames()...)
+    }
+    return models
+}
+
+// mergeAnalysisResults combines per-batch results into one response. Duplicate
+// findings (same file, line and title) keep the most severe tier.
+func mergeAnalysisResults(results []*AIAnalysisResponse) *AIAnalysisResponse {
+    merged := &AIAnalysisResponse{
+        CriticalRisks: []Risk{},
+        HighRisks:     []Risk{},
+        MediumRisks:   []Risk{},
+        Explanations:  []string{},
+    }
+    seen := make(map[string]bool)
+
+    addRisks := func(tier *[]Risk, risks []Risk) {
+        for _, risk := range risks {
+            key := riskKey(risk)
+            if seen[key] {
+                continue
+            }
+            seen[key] = true
+            *tier = append(*tier, risk)
+        }
+    }
+
+    // Walk the tiers most severe first so a duplicate reported lower is dropped
+    for _, result := range results {
+        addRisks(&merged.CriticalRisks, result.CriticalRisks)
+    }
+    for _, result := range results {
+        addRisks(&merged.HighRisks, result.HighRisks)
+    }
+    for _, result := range results {
+        addRisks(&merged.MediumRisks, result.MediumRisks)
+    }
+
+    for _, result := range results {
+        merged.Explanations = append(merged.Explanations, result.Explanations...)
+        merged.AutoFixes = append(merged.AutoFixes, result.AutoFixes...)
+
+        if merged.Summary.BusinessType == "" {
+            merged.Summary.BusinessType = result.Summary.BusinessType
+        }
+        merged.Summary.Compliance = append(merged.Summary.Compliance, result.Summary.Compliance...)
+
+        if result.Architecture != nil {
+            if merged.Architecture == nil {
+                merged.Architecture = &ArchitectureAnalysis{Overview: result.Architecture.Overview}
+            }
+            merged.Architecture.Strengths = append(merged.Architecture.Strengths, result.Architecture.Strengths...)
+            merged.Architecture.Concerns = append(merged.Architecture.Concerns, result.Architecture.Concerns...)
+            merged.Architecture.Recommendations = append(merged.Architecture.Recommendations, result.Architecture.Recommendations...)
+        }
+        if result.Compliance != nil {
+            if merged.Compliance == nil {
+                merged.Compliance = &ComplianceAnalysis{}
+            }
+            merged.Compliance.Standards = append(merged.Compliance.Standards, result.Compliance.Standards...)
+            merged.Compliance.Gaps = append(merged.Compliance.Gaps, result.Compliance.Gaps...)
+            merged.Compliance.Recommendations = append(merged.Compliance.Recommendations, result.Compliance.Recommendations...)
+        }
+    }
+
+    merged.Explanations = unique(merged.Explanations)
+    if merged.Summary.Compliance != nil {
+        merged.Summary.Compliance = unique(merged.Summary.Compliance)
+    }
+    if merged.Architecture != nil {
+        merged.Architecture.Strengths = unique(merged.Architecture.Strengths)
+        merged.Architecture.Concerns = unique(merged.Architecture.Concerns)
+        merged.Architecture.Recommendations = unique(merged.Architecture.Recommendations)
+    }
+    if merged.Compliance != nil {
+        merged.Compliance.Standards = unique(merged.Compliance.Standards)
+        merged.Compliance.Gaps = unique(merged.Compliance.Gaps)
+        merged.Compliance.Recommendations = unique(merged.Compliance.Recommendations)
+    }
+
+    merged.Summary.TotalCritical = len(merged.CriticalRisks)
+    merged.Summary.TotalHigh = len(merged.HighRisks)
+    merged.Summary.TotalMedium = len(merged.MediumRisks)
+    return merged
+}
+
+func riskKey(risk Risk) string {
+    path := risk.FilePath
+    if path == "" {
+        path = risk.File
+    }
+    line := risk.LineNumber
+    if line == 0 {
+        line = risk.Line
+    }
+    title := strings.Join(strings.Fields(strings.ToLower(risk.Title)), " ")
+    return fmt.Sprintf("%s:%d:%s", strings.TrimPrefix(path, "./"), line, title)
+}
+
+// buildCoverageReport works out how much of each file the AI actually saw.
+// skipped holds files dropped before batching (too large, over the limit).
+func buildCoverageReport(codebase map[string]string, skipped []FileCoverage, batches []analysisBatch, outcomes *batchOutcomes) *CoverageReport {
+    if outcomes == nil {
+        outcomes = &batchOutcomes{}
+    }
+
+    scanned := make(map[string]int)
+    truncated := make(map[string]bool)
+    trimmed := make(map[string]bool)
+    inFailedBatch := make(map[string]bool)
+    totalLines := make(map[string]int)
+
+    for i, batch := range batches {
+        // A failed batch may still have had segments answered from the finding cache
+        _, batchFailed := outcomes.Failed[i]
+
+        sentLines := make(map[string]int)
+        for _, segment := range outcomes.Sent[i] {
+            lines := segment.EndLine - segment.StartLine + 1
+            sentLines[segment.Path] += lines
+            scanned[segment.Path] += lines
+            if segment.Truncated {
+                truncated[segment.Path] = true
+            }
+        }
+        for _, segment := range batch.Segments {
+            totalLines[segment.Path] = segment.TotalLines
+            if sentLines[segment.Path] >= segment.EndLine-segment.StartLine+1 {
+                continue
+            }
+            if batchFailed {
+                inFailedBatch[segment.Path] = true
+            } else {
+                trimmed[segment.Path] = true
+            }
+        }
+    }
+
+    report := &CoverageReport{
+        Batches:       len(batches),
+        FailedBatches: len(outcomes.Failed),
+        Files:         []FileCoverage{},
+    }
+
+    for _, file := range prioritizedFiles(codebase) {
+        coverage := FileCoverage{Path: file, TotalLines: totalLines[file], ScannedLines: scanned[file]}
+        if coverage.TotalLines == 0 {
+            coverage.TotalLines = countLines(codebase[file])
+        }
+
+        switch {
+        case coverage.ScannedLines > 0 && coverage.ScannedLines >= coverage.TotalLines && !truncated[file]:
+            coverage.Status = CoverageFull
+        case coverage.ScannedLines > 0:
+            coverage.Status = CoveragePartial
+            if truncated[file] {
+                coverage.Reason = "overlong lines truncated"
+            } else if trimmed[file] {
+                coverage.Reason = "model context too small"
+            } else if inFailedBatch[file] {
+                coverage.Reason = "AI analysis failed for part of the file"
+            } else {
+                coverage.Reason = "batch limit reached"
+            }
+        case coverage.TotalLines == 0:
+            coverage.Status = CoverageFull
+        default:
+            coverage.Status = CoverageSkipped
+            coverage.Reason = "batch limit reached"
+            if inFailedBatch[file] {
+                coverage.Reason = "AI analysis failed"
+            } else if trimmed[file] {
+                coverage.Reason = "model context too small"
+            }
+        }
+        report.Files = append(report.Files, coverage)
+    }
+    report.Files = append(report.Files, skipped...)
+
+    for _, coverage := range report.Files {
+        switch coverage.Status {
+        case CoverageFull:
+            report.FullyScanned++
+        case CoveragePartial:
+            report.PartiallyScanned++
+        default:
+            report.Skipped++
+        }
+    }
+    report.TotalFiles = len(report.Files)
+    return report
+}
+
+func countLines(content string) int {
+    if content == "" {
+        return 0
+    }
+    lines := strings.Count(content, "\n")
+    if !strings.HasSuffix(content, "\n") {
+        lines++
+    }
+    return lines
+}
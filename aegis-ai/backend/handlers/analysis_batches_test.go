@@ -0,0 +1,151 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "strings"
+    "testing"
+)
+
+func numberedLines(n int) string {
+    var b strings.Builder
+    for i := 1; i <= n; i++ {
+        b.WriteString(fmt.Sprintf("line %03d of the file\n", i))
+    }
+    return b.String()
+}
+
+// sentWhole marks every batch as sent in full except the failed ones
+func sentWhole(batches []analysisBatch, failed map[int]string) *batchOutcomes {
+    outcomes := &batchOutcomes{Sent: make(map[int][]fileSegment), Failed: failed}
+    for i, batch := range batches {
+        if _, batchFailed := failed[i]; !batchFailed {
+            outcomes.Sent[i] = batch.Segments
+        }
+    }
+    return outcomes
+}
+
+func TestSplitFileSegments(t *testing.T) {
+    content := numberedLines(100) // 21 chars per line, ~6 tokens
+    segments := splitFileSegments("big.go", content, 100)
+
+    if len(segments) < 2 {
+        t.Fatalf("Expected the file to be split, got %d segment(s)", len(segments))
+    }
+    next := 1
+    for _, segment := range segments {
+        if segment.StartLine != next || segment.TotalLines != 100 {
+            t.Fatalf("Segments not contiguous: %+v", segment)
+        }
+        if segment.Tokens > 100 {
+            t.Errorf("Segment over budget: %d tokens", segment.Tokens)
+        }
+        next = segment.EndLine + 1
+    }
+    if next != 101 {
+        t.Errorf("Segments stop at line %d", next-1)
+    }
+
+    minified := "var x=1;" + strings.Repeat("a", 2000) + "\nok\n"
+    segments = splitFileSegments("app.min.js", minified, 100)
+    if !segments[0].Truncated || segments[0].Tokens > 100 {
+        t.Errorf("Expected the overlong line to be truncated: %+v", segments[0])
+    }
+}
+
+func TestPlanAnalysisBatches(t *testing.T) {
+    codebase := map[string]string{
+        "src/util.go":   numberedLines(10),
+        "config.json":   numberedLines(10),
+        "auth/login.go": numberedLines(10),
+        "big.go":        numberedLines(100),
+    }
+
+    first := planAnalysisBatches(codebase, 100, 50)
+    second := planAnalysisBatches(codebase, 100, 50)
+    if fmt.Sprint(first) != fmt.Sprint(second) {
+        t.Error("Batch plan is not deterministic")
+    }
+    if first[0].Segments[0].Path != "auth/login.go" {
+        t.Errorf("Expected security-critical files first, got %s", first[0].Segments[0].Path)
+    }
+    for i, batch := range first {
+        if batch.Tokens > 100 {
+            t.Errorf("Batch %d over budget: %d tokens", i, batch.Tokens)
+        }
+    }
+
+    coverage := buildCoverageReport(codebase, nil, first, sentWhole(first, nil))
+    if coverage.FullyScanned != 4 || coverage.Skipped != 0 {
+        t.Errorf("Expected full coverage, got %+v", coverage)
+    }
+
+    limited := planAnalysisBatches(codebase, 100, 2)
+    if len(limited) != 2 {
+        t.Fatalf("Expected batch limit to be applied, got %d batches", len(limited))
+    }
+    coverage = buildCoverageReport(codebase, []FileCoverage{{Path: "huge.sql", Status: CoverageSkipped, Reason: "file too large"}}, limited, sentWhole(limited, map[int]string{1: "provider down"}))
+    statuses := make(map[string]string)
+    for _, file := range coverage.Files {
+        statuses[file.Path] = file.Status
+    }
+    if statuses["auth/login.go"] != CoverageFull || statuses["huge.sql"] != CoverageSkipped {
+        t.Errorf("Unexpected coverage: %+v", coverage.Files)
+    }
+    if coverage.TotalFiles != 5 || coverage.FailedBatches != 1 || coverage.FullyScanned+coverage.PartiallyScanned+coverage.Skipped != 5 {
+        t.Errorf("Coverage totals don't add up: %+v", coverage)
+    }
+}
+
+func TestMergeAnalysisResultsDeduplicates(t *testing.T) {
+    sqli := Risk{File: "app.js", Line: 7, Title: "SQL Injection"}
+    results := []*AIAnalysisResponse{
+        {
+            HighRisks:    []Risk{sqli},
+            Explanations: []string{"Injection found"},
+            Summary:      AnalysisSummary{BusinessType: "ecommerce", Compliance: []string{"OWASP Top 10"}},
+        },
+        {
+            CriticalRisks: []Risk{{FilePath: "app.js", LineNumber: 7, Title: "sql  injection"}},
+            MediumRisks:   []Risk{sqli, {File: "config.js", Line: 2, Title: "Debug Enabled"}},
+            Explanations:  []string{"Injection found"},
+            Summary:       AnalysisSummary{BusinessType: "fintech", Compliance: []string{"OWASP Top 10", "PCI-DSS"}},
+        },
+    }
+
+    merged := mergeAnalysisResults(results)
+    if len(merged.CriticalRisks) != 1 || len(merged.HighRisks) != 0 || len(merged.MediumRisks) != 1 {
+        t.Errorf("Expected the duplicate to keep only its critical tier, got %d/%d/%d",
+            len(merged.CriticalRisks), len(merged.HighRisks), len(merged.MediumRisks))
+    }
+    if merged.Summary.TotalCritical != 1 || merged.Summary.TotalMedium != 1 || merged.Summary.BusinessType != "ecommerce" {
+        t.Errorf("Unexpected summary: %+v", merged.Summary)
+    }
+    if len(merged.Explanations) != 1 || len(merged.Summary.Compliance) != 2 {
+        t.Errorf("Expected merged lists to be de-duplicated: %v %v", merged.Explanations, merged.Summary.Compliance)
+    }
+}
+
+func TestAnalyzeBatchesAcrossProviderCalls(t *testing.T) {
+    mock := NewMockLLMProvider(nil)
+    mock.Default = loadFixture(t, "vulnerable-app.json")
+    codebase := map[string]string{
+        "config.js": numberedLines(30),
+        "app.js":    numberedLines(30),
+        "server.js": numberedLines(30),
+    }
+    batches := planAnalysisBatches(codebase, 200, 10)
+    useFindingCache(t)
+
+    response, outcomes, err := analyzeBatches(context.Background(), []LLMProvider{mock}, batches, AnalysisContext{}, nil)
+    if err != nil {
+        t.Fatalf("analyzeBatches failed: %v", err)
+    }
+    if len(mock.Calls()) != len(batches) || len(batches) < 2 {
+        t.Errorf("Expected one call per batch, got %d calls for %d batches", len(mock.Calls()), len(batches))
+    }
+    if len(outcomes.Failed) != 0 || len(response.CriticalRisks) != 1 {
+        t.Errorf("Expected identical batch findings to merge, got %d critical (failed %v)", len(response.CriticalRisks), outcomes.Failed)
+    }
+}
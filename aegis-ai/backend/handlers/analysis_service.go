@@ -0,0 +1,208 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+)
+
+const defaultScanTimeout = 15 * time.Minute
+
+// ErrScanFinished is returned when cancelling a scan that already stopped
+var ErrScanFinished = errors.New("scan already finished")
+
+// AnalysisService owns all analysis state shared between the Gin handlers
+// and the background scan workers. Every read-modify-write goes through the
+// store's atomic Update so concurrent status changes can't clobber each other.
+type AnalysisService struct {
+    store   AnalysisStore
+    queue   *ScanQueue
+    timeout time.Duration
+
+    // cancel functions of scans currently running on a worker
+    mu      sync.Mutex
+    running map[string]context.CancelFunc
+}
+
+// NewAnalysisService creates the service. workers <= 0 and timeout <= 0 use the defaults.
+func NewAnalysisService(store AnalysisStore, workers int, timeout time.Duration) *AnalysisService {
+    if timeout <= 0 {
+        timeout = defaultScanTimeout
+    }
+    return &AnalysisService{
+        store:   store,
+        queue:   NewScanQueue(workers),
+        timeout: timeout,
+        running: make(map[string]context.CancelFunc),
+    }
+}
+
+// Shared service used by the handlers - replaced by main before the server starts
+var analysisService = NewAnalysisService(NewMemoryAnalysisStore(), 0, 0)
+
+// SetAnalysisService replaces the service used by the handlers.
+// It must be called before the server starts handling requests.
+func SetAnalysisService(service *AnalysisService) {
+    analysisService = service
+}
+
+func (s *AnalysisService) Get(id string) (*ScanRecord, error) {
+    return s.store.Get(id)
+}
+
+func (s *AnalysisService) List() ([]*ScanRecord, error) {
+    return s.store.List()
+}
+
+// Create stores a new record under a freshly generated ID
+func (s *AnalysisService) Create(trigger, repoURL string, configure func(record *ScanRecord)) (*ScanRecord, error) {
+    record := newScanRecord(newAnalysisID(), trigger, repoURL)
+    if configure != nil {
+        configure(record)
+    }
+    if err := s.store.Put(record); err != nil {
+        return nil, err
+    }
+    return record, nil
+}
+
+// SetStatus moves a running scan to the next lifecycle stage.
+// Scans that already reached a final status are left alone.
+func (s *AnalysisService) SetStatus(id string, status string) {
+    err := s.store.Update(id, func(record *ScanRecord) error {
+        if isFinalStatus(record.Status) {
+            return ErrScanFinished
+        }
+        record.Status = status
+        record.UpdatedAt = timestamp()
+        return nil
+    })
+    if err != nil && err != ErrScanFinished {
+        fmt.Printf("❌ [ANALYSIS %s] Failed to update status: %v\n", id, err)
+    }
+}
+
+// Complete stores the AI analysis on the record and marks it completed
+func (s *AnalysisService) Complete(id string, analysis *AIAnalysisResponse) error {
+    return s.store.Update(id, func(record *ScanRecord) error {
+        if isFinalStatus(record.Status) {
+            return ErrScanFinished
+        }
+        record.AIAnalysisResponse = *analysis
+        record.Status = StatusCompleted
+        record.Error = ""
+        record.UpdatedAt = timestamp()
+        record.CompletedAt = record.UpdatedAt
+        return nil
+    })
+}
+
+// Fail marks a scan failed and keeps the reason on the record
+func (s *AnalysisService) Fail(id string, reason error) {
+    s.finish(id, StatusFailed, reason.Error())
+}
+
+func (s *AnalysisService) finish(id string, status string, reason string) {
+    err := s.store.Update(id, func(record *ScanRecord) error {
+        if isFinalStatus(record.Status) {
+            return ErrScanFinished
+        }
+        record.Status = status
+        record.Error = reason
+        record.UpdatedAt = timestamp()
+        record.CompletedAt = record.UpdatedAt
+        return nil
+    })
+    if err != nil && err != ErrScanFinished {
+        fmt.Printf("❌ [ANALYSIS %s] Failed to update status: %v\n", id, err)
+    }
+}
+
+// Cancel stops a queued or running scan and marks it cancelled
+func (s *AnalysisService) Cancel(id string, reason string) error {
+    record, err := s.store.Get(id)
+    if err != nil {
+        return err
+    }
+    if isFinalStatus(record.Status) {
+        return ErrScanFinished
+    }
+
+    s.queue.Remove(id)
+
+    // A worker may have dequeued the job without registering it yet - marking
+    // the record under the lock makes that worker see the cancellation
+    s.mu.Lock()
+    cancel, running := s.running[id]
+    s.finish(id, StatusCancelled, reason)
+    s.mu.Unlock()
+    if running {
+        cancel()
+    }
+    return nil
+}
+
+// Enqueue queues a scan and cancels any queued scan it supersedes.
+// The job runs with a context that is cancelled by Cancel or the scan deadline.
+func (s *AnalysisService) Enqueue(job *ScanJob) {
+    process := job.Process
+    job.Process = func(ctx context.Context, analysisID string) error {
+        ctx, cancel := context.WithTimeout(ctx, s.timeout)
+        s.mu.Lock()
+        if record, err := s.store.Get(analysisID); err == nil && isFinalStatus(record.Status) {
+            // Cancelled between leaving the queue and starting
+            s.mu.Unlock()
+            cancel()
+            return ErrScanFinished
+        }
+        s.running[analysisID] = cancel
+        s.mu.Unlock()
+
+        defer func() {
+            s.mu.Lock()
+            delete(s.running, analysisID)
+            s.mu.Unlock()
+            cancel()
+        }()
+
+        err := s.runProcess(ctx, analysisID, process)
+        switch {
+        case err == nil:
+        case ctx.Err() == context.DeadlineExceeded:
+            fmt.Printf("⏰ [ANALYSIS %s] Scan timed out after %s\n", analysisID, s.timeout)
+            s.finish(analysisID, StatusTimedOut, fmt.Sprintf("scan exceeded the %s deadline", s.timeout))
+        case ctx.Err() == context.Canceled:
+            s.finish(analysisID, StatusCancelled, "cancelled")
+        case errors.As(err, new(*AIParseError)):
+            s.finish(analysisID, StatusFailedParse, err.Error())
+        default:
+            s.Fail(analysisID, err)
+        }
+        return err
+    }
+
+    if superseded := s.queue.Enqueue(job); superseded != nil {
+        fmt.Printf("⏭️  [ANALYSIS %s] Superseded by %s\n", superseded.AnalysisID, job.AnalysisID)
+        s.finish(superseded.AnalysisID, StatusCancelled, "superseded by "+job.AnalysisID)
+    }
+}
+
+func (s *AnalysisService) runProcess(ctx context.Context, analysisID string, process func(context.Context, string) error) (err error) {
+    defer func() {
+        if r := recover(); r != nil {
+            fmt.Printf("❌ [ANALYSIS %s] Scan panicked: %v\n", analysisID, r)
+            err = fmt.Errorf("scan panicked: %v", r)
+        }
+    }()
+    return process(ctx, analysisID)
+}
+
+func (s *AnalysisService) QueuePosition(id string) int {
+    return s.queue.Position(id)
+}
+
+func (s *AnalysisService) QueueStats() QueueStats {
+    return s.queue.Stats()
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+func newTestRouter() *gin.Engine {
+    gin.SetMode(gin.TestMode)
+    router := gin.New()
+    router.POST("/api/analyze", HandleManualAnalysis)
+    router.GET("/api/analysis/:id", GetAnalysis)
+    router.GET("/api/analysis/:id/status", GetAnalysisStatus)
+    router.GET("/api/analysis/:id/sbom", GetAnalysisSBOM)
+    router.GET("/api/analyses", GetAllAnalyses)
+    router.GET("/api/queue", GetQueueStats)
+    router.POST("/webhook", HandleWebhook)
+    return router
+}
+
+// Run with `go test -race` - hammers analyze/status/list while workers update records
+func TestConcurrentAnalyzeStatusList(t *testing.T) {
+    previous := analysisService
+    service := NewAnalysisService(NewMemoryAnalysisStore(), 4, 0)
+    SetAnalysisService(service)
+    defer SetAnalysisService(previous)
+
+    router := newTestRouter()
+    // Clones of a missing local repo fail fast, exercising the failure path
+    missingRepo := filepath.Join(t.TempDir(), "missing-repo")
+
+    const requests = 20
+    var wg sync.WaitGroup
+    for i := 0; i < requests; i++ {
+        wg.Add(3)
+        go func(i int) {
+            defer wg.Done()
+            body := fmt.Sprintf(`{"repo_url": "%s-%d"}`, missingRepo, i)
+            w := httptest.NewRecorder()
+            router.ServeHTTP(w, httptest.NewRequest("POST", "/api/analyze", bytes.NewBufferString(body)))
+            if w.Code != http.StatusAccepted {
+                t.Errorf("Expected 202, got %d: %s", w.Code, w.Body.String())
+            }
+        }(i)
+        go func() {
+            defer wg.Done()
+            records, _ := service.List()
+            for _, record := range records {
+                for _, path := range []string{
+                    "/api/analysis/" + record.ID + "/status",
+                    "/api/analysis/" + record.ID,
+                } {
+                    w := httptest.NewRecorder()
+                    router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+                }
+            }
+        }()
+        go func() {
+            defer wg.Done()
+            for _, path := range []string{"/api/analyses", "/api/queue"} {
+                w := httptest.NewRecorder()
+                router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+                if w.Code != http.StatusOK {
+                    t.Errorf("Expected 200 from %s, got %d", path, w.Code)
+                }
+            }
+        }()
+    }
+    wg.Wait()
+
+    // Wait for the workers to drain the queue
+    deadline := time.Now().Add(30 * time.Second)
+    for {
+        records, err := service.List()
+        if err != nil {
+            t.Fatalf("List failed: %v", err)
+        }
+        finished := 0
+        for _, record := range records {
+            if isFinalStatus(record.Status) {
+                finished++
+            }
+        }
+        if len(records) == requests && finished == requests {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("Scans did not finish: %d/%d final out of %d records", finished, requests, len(records))
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestConcurrentSessions(t *testing.T) {
+    sessions := NewSessionStore()
+
+    var wg sync.WaitGroup
+    ids := make(chan string, 50)
+    for i := 0; i < 50; i++ {
+        wg.Add(2)
+        go func(i int) {
+            defer wg.Done()
+            login := fmt.Sprintf("user%d", i)
+            ids <- sessions.Create(&GitHubUser{Login: login}, "token-"+login)
+        }(i)
+        go func(i int) {
+            defer wg.Done()
+            sessions.User(newSessionToken())
+            sessions.Token(fmt.Sprintf("user%d", i))
+        }(i)
+    }
+    wg.Wait()
+    close(ids)
+
+    for id := range ids {
+        user, exists := sessions.User(id)
+        if !exists {
+            t.Fatalf("Session %s missing", id)
+        }
+        if token, _ := sessions.Token(user.Login); token != "token-"+user.Login {
+            t.Errorf("Wrong token for %s: %s", user.Login, token)
+        }
+    }
+}
+
+// waitForStatus polls until the record reaches the wanted status
+func waitForStatus(t *testing.T, service *AnalysisService, id string, want string) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        record, err := service.Get(id)
+        if err != nil {
+            t.Fatalf("Get failed: %v", err)
+        }
+        if record.Status == want {
+            return
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("Expected status %s, got %s", want, record.Status)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+// hangingScan blocks until its context ends, like a hung clone or model call
+func hangingScan(service *AnalysisService) func(context.Context, string) error {
+    return func(ctx context.Context, analysisID string) error {
+        service.SetStatus(analysisID, StatusAnalyzing)
+        <-ctx.Done()
+        return ctx.Err()
+    }
+}
+
+func TestScanTimeout(t *testing.T) {
+    service := NewAnalysisService(NewMemoryAnalysisStore(), 1, 100*time.Millisecond)
+
+    record, _ := service.Create(TriggerManual, "https://github.com/acme/slow", nil)
+    service.Enqueue(&ScanJob{AnalysisID: record.ID, Process: hangingScan(service)})
+    waitForStatus(t, service, record.ID, StatusTimedOut)
+}
+
+func TestCancelScans(t *testing.T) {
+    service := NewAnalysisService(NewMemoryAnalysisStore(), 1, time.Minute)
+    hang := hangingScan(service)
+
+    running, _ := service.Create(TriggerManual, "https://github.com/acme/running", nil)
+    queued, _ := service.Create(TriggerManual, "https://github.com/acme/queued", nil)
+    service.Enqueue(&ScanJob{AnalysisID: running.ID, Process: hang})
+    service.Enqueue(&ScanJob{AnalysisID: queued.ID, Process: hang})
+    waitForStatus(t, service, running.ID, StatusAnalyzing)
+
+    if err := service.Cancel(queued.ID, "cancelled by user"); err != nil {
+        t.Fatalf("Cancel queued failed: %v", err)
+    }
+    if service.QueueStats().Queued != 0 {
+        t.Errorf("Cancelled scan still queued")
+    }
+    if err := service.Cancel(running.ID, "cancelled by user"); err != nil {
+        t.Fatalf("Cancel running failed: %v", err)
+    }
+    waitForStatus(t, service, running.ID, StatusCancelled)
+    waitForStatus(t, service, queued.ID, StatusCancelled)
+
+    if err := service.Cancel(running.ID, "again"); err != ErrScanFinished {
+        t.Errorf("Expected ErrScanFinished, got %v", err)
+    }
+    if err := service.Cancel("missing", "nope"); err != ErrAnalysisNotFound {
+        t.Errorf("Expected ErrAnalysisNotFound, got %v", err)
+    }
+}
+
+func TestCancelBeforeWorkerStarts(t *testing.T) {
+    service := NewAnalysisService(NewMemoryAnalysisStore(), 1, time.Minute)
+
+    // Cancelled after leaving the queue but before the worker registered it
+    record, _ := service.Create(TriggerManual, "https://github.com/acme/dequeued", nil)
+    if err := service.Cancel(record.ID, "cancelled by user"); err != nil {
+        t.Fatalf("Cancel failed: %v", err)
+    }
+    started := make(chan string, 2)
+    scan := func(ctx context.Context, analysisID string) error {
+        started <- analysisID
+        return nil
+    }
+    next, _ := service.Create(TriggerManual, "https://github.com/acme/next", nil)
+    service.Enqueue(&ScanJob{AnalysisID: record.ID, Process: scan})
+    service.Enqueue(&ScanJob{AnalysisID: next.ID, Process: scan})
+
+    if first := <-started; first != next.ID {
+        t.Errorf("Expected the cancelled scan never to start, but %s ran", first)
+    }
+    if got, _ := service.Get(record.ID); got.Status != StatusCancelled {
+        t.Errorf("Expected the scan to stay cancelled, got %s", got.Status)
+    }
+}
@@ -55,8 +55,7 @@ type GitHubRepo struct {
 }
 
 // Store user sessions (in production, use Redis or database)
-var userSessions = make(map[string]*GitHubUser)
-var userTokens = make(map[string]string) // user ID -> access token
+var userSessions = NewSessionStore()
 
 func HandleGitHubAuth(c *gin.Context) {
     oauthConfig := getOAuthConfig()
@@ -109,9 +108,7 @@ func HandleGitHubCallback(c *gin.Context) {
     }
 
     // Store user session
-    sessionID := generateSessionID()
-    userSessions[sessionID] = user
-    userTokens[user.Login] = token
+    sessionID := userSessions.Create(user, token)
 
     // Redirect to frontend with session
     frontendURL := os.Getenv("FRONTEND_URL")
@@ -159,13 +156,13 @@ func HandleGetUserRepos(c *gin.Context) {
         }
 
         var exists bool
-        user, exists = userSessions[sessionID]
+        user, exists = userSessions.User(sessionID)
         if !exists {
             c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
             return
         }
 
-        token, exists = userTokens[user.Login]
+        token, exists = userSessions.Token(user.Login)
         if !exists {
             c.JSON(http.StatusUnauthorized, gin.H{"error": "No access token found for session"})
             return
@@ -289,10 +286,6 @@ func getUserRepos(token string) ([]GitHubRepo, error) {
     return repos, nil
 }
 
-func generateSessionID() string {
-    return fmt.Sprintf("session_%d", len(userSessions)+1)
-}
-
 // UPDATED: AuthMiddleware now supports both Bearer tokens and session IDs
 func AuthMiddleware() gin.HandlerFunc {
     return func(c *gin.Context) {
@@ -319,7 +312,7 @@ func AuthMiddleware() gin.HandlerFunc {
             }
 
             var exists bool
-            user, exists = userSessions[sessionID]
+            user, exists = userSessions.User(sessionID)
             if !exists {
                 c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session"})
                 c.Abort()
@@ -335,8 +328,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 // Get user token for making GitHub requests
 func GetUserToken(username string) (string, bool) {
-    token, exists := userTokens[username]
-    return token, exists
+    return userSessions.Token(username)
 }
 
 // Helper function to mask sensitive strings for logging
@@ -96,6 +96,13 @@ func (e *AutoFixEngine) GenerateFixes(risks []Risk, codebase map[string]string)
 
     for _, risk := range risks {
         if fix := e.generateFixForRisk(risk, codebase); fix != nil {
+            // Point the fix at the (verified) location of the finding
+            if fix.FilePath == "" {
+                fix.FilePath = risk.FilePath
+            }
+            if fix.LineNumber == 0 {
+                fix.LineNumber = risk.LineNumber
+            }
             fixes = append(fixes, *fix)
         }
     }
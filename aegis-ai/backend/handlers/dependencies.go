@@ -0,0 +1,373 @@
+package handlers
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "os"
+    "path"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// Package ecosystems, named as in OSV advisories
+const (
+    EcosystemGo       = "Go"
+    EcosystemNPM      = "npm"
+    EcosystemPyPI     = "PyPI"
+    EcosystemMaven    = "Maven"
+    EcosystemRubyGems = "RubyGems"
+)
+
+// Dependency is one pinned package version read from a manifest or lockfile
+type Dependency struct {
+    Ecosystem string `json:"ecosystem"`
+    Name      string `json:"name"`
+    Version   string `json:"version"`
+    Manifest  string `json:"manifest"`
+    Line      int    `json:"line"`
+    Direct    bool   `json:"direct"`
+}
+
+// Directories that hold installed or vendored copies, not the project's own manifests
+var dependencySkipDirs = map[string]bool{
+    ".git": true, "node_modules": true, "vendor": true, "dist": true,
+    "build": true, "target": true, "__pycache__": true, ".venv": true, "venv": true,
+}
+
+// manifestParser returns the parser for a manifest file name, or nil
+func manifestParser(name string) func(path string, content string) []Dependency {
+    switch {
+    case name == "go.mod":
+        return parseGoMod
+    case name == "go.sum":
+        return parseGoSum
+    case name == "package-lock.json":
+        return parsePackageLock
+    case name == "poetry.lock":
+        return parsePoetryLock
+    case name == "pom.xml":
+        return parsePomXML
+    case name == "Gemfile.lock":
+        return parseGemfileLock
+    case strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt"):
+        return parseRequirements
+    }
+    return nil
+}
+
+// collectDependencies reads every supported manifest in the repository. Files
+// are read from disk rather than the analyzed codebase, which leaves out
+// lockfiles and large manifests; symlinks leaving the repository are not followed.
+func collectDependencies(ctx context.Context, repoPath string) []Dependency {
+    var dependencies []Dependency
+    skip := func(relPath string, isDir bool) bool {
+        if isDir {
+            return dependencySkipDirs[path.Base(relPath)]
+        }
+        return manifestParser(path.Base(relPath)) == nil
+    }
+    // A failed walk still reports the manifests read before it stopped
+    walkRepository(ctx, repoPath, skip, func(entry repoEntry) {
+        content, err := os.ReadFile(entry.RealPath)
+        if err != nil {
+            return
+        }
+        parse := manifestParser(path.Base(entry.Path))
+        dependencies = append(dependencies, parse(entry.Path, string(content))...)
+    })
+    return mergeGoSumDependencies(dependencies)
+}
+
+// mergeGoSumDependencies drops go.sum entries for modules the go.mod next to
+// it already pins - go.sum only fills in the indirect modules go.mod omits
+func mergeGoSumDependencies(dependencies []Dependency) []Dependency {
+    pinned := make(map[string]bool)
+    for _, dependency := range dependencies {
+        if filepath.Base(dependency.Manifest) == "go.mod" {
+            pinned[filepath.Dir(dependency.Manifest)+"\x00"+dependency.Name] = true
+        }
+    }
+
+    var merged []Dependency
+    for _, dependency := range dependencies {
+        if filepath.Base(dependency.Manifest) == "go.sum" && pinned[filepath.Dir(dependency.Manifest)+"\x00"+dependency.Name] {
+            continue
+        }
+        merged = append(merged, dependency)
+    }
+    return merged
+}
+
+// lineOf returns the 1-based line of the first occurrence of needle, or 0
+func lineOf(content string, needle string) int {
+    index := strings.Index(content, needle)
+    if index < 0 {
+        return 0
+    }
+    return strings.Count(content[:index], "\n") + 1
+}
+
+// parseGoMod reads require directives, both single-line and blocks
+func parseGoMod(path string, content string) []Dependency {
+    var dependencies []Dependency
+    inRequire := false
+    for index, line := range strings.Split(content, "\n") {
+        fields := strings.Fields(strings.SplitN(line, "//", 2)[0])
+        switch {
+        case len(fields) == 0:
+            continue
+        case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+            inRequire = true
+            continue
+        case inRequire && fields[0] == ")":
+            inRequire = false
+            continue
+        case fields[0] == "require":
+            fields = fields[1:]
+        case !inRequire:
+            continue
+        }
+        if len(fields) < 2 {
+            continue
+        }
+        dependencies = append(dependencies, Dependency{
+            Ecosystem: EcosystemGo,
+            Name:      fields[0],
+            Version:   fields[1],
+            Manifest:  path,
+            Line:      index + 1,
+            Direct:    !strings.Contains(line, "// indirect"),
+        })
+    }
+    return dependencies
+}
+
+// parseGoSum keeps the highest version of each module whose source was
+// downloaded (lines without the /go.mod suffix)
+func parseGoSum(path string, content string) []Dependency {
+    latest := make(map[string]Dependency)
+    for index, line := range strings.Split(content, "\n") {
+        fields := strings.Fields(line)
+        if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+            continue
+        }
+        current, seen := latest[fields[0]]
+        if seen && compareVersions(fields[1], current.Version) <= 0 {
+            continue
+        }
+        latest[fields[0]] = Dependency{Ecosystem: EcosystemGo, Name: fields[0], Version: fields[1], Manifest: path, Line: index + 1}
+    }
+    return sortedDependencies(latest)
+}
+
+func sortedDependencies(byName map[string]Dependency) []Dependency {
+    dependencies := make([]Dependency, 0, len(byName))
+    for _, dependency := range byName {
+        dependencies = append(dependencies, dependency)
+    }
+    sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Line < dependencies[j].Line })
+    return dependencies
+}
+
+// parsePackageLock reads lockfile v2/v3 "packages" and falls back to the v1
+// nested "dependencies" tree
+func parsePackageLock(path string, content string) []Dependency {
+    // v2/v3 entries list their own dependencies as version ranges, v1 entries nest them
+    type packageEntry struct {
+        Version string `json:"version"`
+        Link    bool   `json:"link"`
+    }
+    type lockEntry struct {
+        Version      string               `json:"version"`
+        Dependencies map[string]lockEntry `json:"dependencies"`
+    }
+    var lock struct {
+        Packages     map[string]packageEntry `json:"packages"`
+        Dependencies map[string]lockEntry    `json:"dependencies"`
+    }
+    if err := json.Unmarshal([]byte(content), &lock); err != nil {
+        return nil
+    }
+
+    var dependencies []Dependency
+    if len(lock.Packages) > 0 {
+        for key, entry := range lock.Packages {
+            index := strings.LastIndex(key, "node_modules/")
+            if index < 0 || entry.Link || entry.Version == "" {
+                continue
+            }
+            dependencies = append(dependencies, Dependency{
+                Ecosystem: EcosystemNPM,
+                Name:      key[index+len("node_modules/"):],
+                Version:   entry.Version,
+                Manifest:  path,
+                Line:      lineOf(content, `"`+key+`"`),
+                Direct:    strings.Count(key, "node_modules/") == 1,
+            })
+        }
+    } else {
+        var walk func(entries map[string]lockEntry, direct bool)
+        walk = func(entries map[string]lockEntry, direct bool) {
+            for name, entry := range entries {
+                if entry.Version != "" {
+                    dependencies = append(dependencies, Dependency{
+                        Ecosystem: EcosystemNPM, Name: name, Version: entry.Version,
+                        Manifest: path, Line: lineOf(content, `"`+name+`": {`), Direct: direct,
+                    })
+                }
+                walk(entry.Dependencies, false)
+            }
+        }
+        walk(lock.Dependencies, true)
+    }
+
+    sort.Slice(dependencies, func(i, j int) bool {
+        if dependencies[i].Line != dependencies[j].Line {
+            return dependencies[i].Line < dependencies[j].Line
+        }
+        return dependencies[i].Name < dependencies[j].Name
+    })
+    return dependencies
+}
+
+var requirementPattern = regexp.MustCompile(`^\s*([A-Za-z0-9][A-Za-z0-9._-]*)(?:\[[^\]]*\])?\s*===?\s*([^\s;#,]+)`)
+
+// parseRequirements reads exact pins (name==version); ranges can't be matched
+// to one version and are skipped
+func parseRequirements(path string, content string) []Dependency {
+    var dependencies []Dependency
+    for index, line := range strings.Split(content, "\n") {
+        match := requirementPattern.FindStringSubmatch(line)
+        if match == nil {
+            continue
+        }
+        dependencies = append(dependencies, Dependency{
+            Ecosystem: EcosystemPyPI, Name: match[1], Version: match[2],
+            Manifest: path, Line: index + 1, Direct: true,
+        })
+    }
+    return dependencies
+}
+
+var tomlStringPattern = regexp.MustCompile(`^(\w+)\s*=\s*"([^"]*)"`)
+
+// parsePoetryLock reads the name and version of each [[package]] table
+func parsePoetryLock(path string, content string) []Dependency {
+    var dependencies []Dependency
+    var current *Dependency
+    flush := func() {
+        if current != nil && current.Name != "" && current.Version != "" {
+            dependencies = append(dependencies, *current)
+        }
+        current = nil
+    }
+
+    for index, line := range strings.Split(content, "\n") {
+        line = strings.TrimSpace(line)
+        if strings.HasPrefix(line, "[") {
+            flush()
+            if line == "[[package]]" {
+                current = &Dependency{Ecosystem: EcosystemPyPI, Manifest: path}
+            }
+            continue
+        }
+        match := tomlStringPattern.FindStringSubmatch(line)
+        if current == nil || match == nil {
+            continue
+        }
+        switch match[1] {
+        case "name":
+            current.Name, current.Line = match[2], index+1
+        case "version":
+            current.Version = match[2]
+        }
+    }
+    flush()
+    return dependencies
+}
+
+var (
+    pomDependencyPattern = regexp.MustCompile(`(?s)<dependency>(.*?)</dependency>`)
+    pomPropertiesPattern = regexp.MustCompile(`(?s)<properties>(.*?)</properties>`)
+    pomPropertyPattern   = regexp.MustCompile(`<([\w.-]+)>\s*([^<]*?)\s*</([\w.-]+)>`)
+    pomVersionPattern    = regexp.MustCompile(`<version>\s*([^<]+?)\s*</version>`)
+)
+
+var pomTagPatterns = map[string]*regexp.Regexp{
+    "groupId":    regexp.MustCompile(`<groupId>\s*([^<]+?)\s*</groupId>`),
+    "artifactId": regexp.MustCompile(`<artifactId>\s*([^<]+?)\s*</artifactId>`),
+    "version":    pomVersionPattern,
+}
+
+func pomTag(block string, tag string) string {
+    match := pomTagPatterns[tag].FindStringSubmatch(block)
+    if match == nil {
+        return ""
+    }
+    return match[1]
+}
+
+// parsePomXML reads <dependency> blocks, resolving ${property} versions.
+// Versions inherited from a parent or BOM aren't in the file and are skipped.
+func parsePomXML(path string, content string) []Dependency {
+    properties := make(map[string]string)
+    for _, block := range pomPropertiesPattern.FindAllStringSubmatch(content, -1) {
+        for _, property := range pomPropertyPattern.FindAllStringSubmatch(block[1], -1) {
+            if property[1] == property[3] {
+                properties[property[1]] = property[2]
+            }
+        }
+    }
+
+    var dependencies []Dependency
+    for _, match := range pomDependencyPattern.FindAllStringSubmatchIndex(content, -1) {
+        block := content[match[2]:match[3]]
+        group, artifact, version := pomTag(block, "groupId"), pomTag(block, "artifactId"), pomTag(block, "version")
+        if strings.HasPrefix(version, "${") && strings.HasSuffix(version, "}") {
+            version = properties[strings.TrimSuffix(strings.TrimPrefix(version, "${"), "}")]
+        }
+        if group == "" || artifact == "" || version == "" || strings.Contains(version, "${") {
+            continue
+        }
+
+        // Point at the <version> line - that's the line to change
+        line := strings.Count(content[:match[2]], "\n") + 1
+        if versionIndex := pomVersionPattern.FindStringIndex(block); versionIndex != nil {
+            line += strings.Count(block[:versionIndex[0]], "\n")
+        }
+        dependencies = append(dependencies, Dependency{
+            Ecosystem: EcosystemMaven, Name: group + ":" + artifact, Version: version,
+            Manifest: path, Line: line, Direct: true,
+        })
+    }
+    return dependencies
+}
+
+var gemSpecPattern = regexp.MustCompile(`^    ([A-Za-z0-9._-]+) \(([^)]+)\)$`)
+
+// parseGemfileLock reads the resolved gems under GEM specs:, dropping
+// platform suffixes such as 1.13.0-x86_64-linux
+func parseGemfileLock(path string, content string) []Dependency {
+    var dependencies []Dependency
+    inGems := false
+    scanner := bufio.NewScanner(strings.NewReader(content))
+    for line := 1; scanner.Scan(); line++ {
+        text := scanner.Text()
+        if text != "" && !strings.HasPrefix(text, " ") {
+            inGems = text == "GEM"
+            continue
+        }
+        match := gemSpecPattern.FindStringSubmatch(text)
+        if !inGems || match == nil {
+            continue
+        }
+        dependencies = append(dependencies, Dependency{
+            Ecosystem: EcosystemRubyGems, Name: match[1], Version: strings.SplitN(match[2], "-", 2)[0],
+            Manifest: path, Line: line,
+        })
+    }
+    return dependencies
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+var dependencyManifests = map[string]string{
+    "go.mod": `module example.com/app
+
+go 1.21
+
+require github.com/gin-gonic/gin v1.9.1
+
+require (
+    golang.org/x/net v0.10.0 // indirect
+    golang.org/x/text v0.9.0
+)
+`,
+    "go.sum": `github.com/gin-gonic/gin v1.9.1 h1:abc=
+golang.org/x/net v0.10.0 h1:def=
+golang.org/x/crypto v0.8.0/go.mod h1:ghi=
+golang.org/x/crypto v0.9.0 h1:jkl=
+golang.org/x/crypto v0.7.0 h1:mno=
+`,
+    "web/package-lock.json": `{
+  "name": "web",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "web", "dependencies": {"express": "^4.18.0"}},
+    "node_modules/express": {"version": "4.18.2", "dependencies": {"qs": "6.11.0"}},
+    "node_modules/express/node_modules/qs": {"version": "6.11.0"},
+    "node_modules/lodash": {"version": "4.17.20"},
+    "node_modules/local-lib": {"resolved": "../lib", "link": true}
+  }
+}
+`,
+    "requirements.txt": `# pinned
+requests==2.28.0
+Flask[async]>=2.0
+django == 4.2.1 ; python_version >= "3.8"
+`,
+    "poetry.lock": `[[package]]
+name = "urllib3"
+version = "1.26.5"
+description = "HTTP library"
+
+[package.extras]
+socks = ["PySocks"]
+
+[[package]]
+name = "certifi"
+version = "2023.5.7"
+`,
+    "pom.xml": `<project>
+  <properties>
+    <log4j.version>2.14.1</log4j.version>
+  </properties>
+  <dependencies>
+    <dependency>
+      <groupId>org.apache.logging.log4j</groupId>
+      <artifactId>log4j-core</artifactId>
+      <version>${log4j.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>org.springframework</groupId>
+      <artifactId>spring-core</artifactId>
+    </dependency>
+  </dependencies>
+</project>
+`,
+    "Gemfile.lock": `GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.13.0-x86_64-linux)
+      racc (~> 1.4)
+    rack (2.2.3)
+
+PLATFORMS
+  x86_64-linux
+`,
+    "node_modules/lodash/package-lock.json": `{"packages": {"node_modules/ignored": {"version": "1.0.0"}}}`,
+}
+
+// writeRepo lays out files under a temporary repository root
+func writeRepo(t *testing.T, files map[string]string) string {
+    t.Helper()
+    root := t.TempDir()
+    for path, content := range files {
+        full := filepath.Join(root, path)
+        if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return root
+}
+
+func TestCollectDependencies(t *testing.T) {
+    repoPath := writeRepo(t, dependencyManifests)
+    // Manifests outside the clone are never read, whether linked directly or through a directory
+    host := writeRepo(t, map[string]string{"requirements.txt": "flask==0.12\n", "app/go.mod": "module host\n\nrequire example.com/host v1.0.0\n"})
+    os.Symlink(filepath.Join(host, "requirements.txt"), filepath.Join(repoPath, "requirements-host.txt"))
+    os.Symlink(filepath.Join(host, "app"), filepath.Join(repoPath, "hostapp"))
+
+    dependencies := collectDependencies(context.Background(), repoPath)
+
+    found := make(map[string]Dependency)
+    for _, dependency := range dependencies {
+        found[dependency.Manifest+" "+dependency.Name] = dependency
+    }
+
+    expected := []Dependency{
+        {Ecosystem: EcosystemGo, Name: "github.com/gin-gonic/gin", Version: "v1.9.1", Manifest: "go.mod", Line: 5, Direct: true},
+        {Ecosystem: EcosystemGo, Name: "golang.org/x/net", Version: "v0.10.0", Manifest: "go.mod", Line: 8},
+        {Ecosystem: EcosystemGo, Name: "golang.org/x/text", Version: "v0.9.0", Manifest: "go.mod", Line: 9, Direct: true},
+        {Ecosystem: EcosystemGo, Name: "golang.org/x/crypto", Version: "v0.9.0", Manifest: "go.sum", Line: 4},
+        {Ecosystem: EcosystemNPM, Name: "express", Version: "4.18.2", Manifest: "web/package-lock.json", Line: 6, Direct: true},
+        {Ecosystem: EcosystemNPM, Name: "qs", Version: "6.11.0", Manifest: "web/package-lock.json", Line: 7},
+        {Ecosystem: EcosystemNPM, Name: "lodash", Version: "4.17.20", Manifest: "web/package-lock.json", Line: 8, Direct: true},
+        {Ecosystem: EcosystemPyPI, Name: "requests", Version: "2.28.0", Manifest: "requirements.txt", Line: 2, Direct: true},
+        {Ecosystem: EcosystemPyPI, Name: "django", Version: "4.2.1", Manifest: "requirements.txt", Line: 4, Direct: true},
+        {Ecosystem: EcosystemPyPI, Name: "urllib3", Version: "1.26.5", Manifest: "poetry.lock", Line: 2},
+        {Ecosystem: EcosystemPyPI, Name: "certifi", Version: "2023.5.7", Manifest: "poetry.lock", Line: 10},
+        {Ecosystem: EcosystemMaven, Name: "org.apache.logging.log4j:log4j-core", Version: "2.14.1", Manifest: "pom.xml", Line: 9, Direct: true},
+        {Ecosystem: EcosystemRubyGems, Name: "nokogiri", Version: "1.13.0", Manifest: "Gemfile.lock", Line: 4},
+        {Ecosystem: EcosystemRubyGems, Name: "rack", Version: "2.2.3", Manifest: "Gemfile.lock", Line: 6},
+    }
+    for _, want := range expected {
+        if got, ok := found[want.Manifest+" "+want.Name]; !ok || got != want {
+            t.Errorf("Expected %+v, got %+v", want, got)
+        }
+    }
+    if len(dependencies) != len(expected) {
+        t.Errorf("Expected %d dependencies (go.mod pins win over go.sum, ranges, links and node_modules skipped), got %d: %+v",
+            len(expected), len(dependencies), dependencies)
+    }
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "sort"
+    "strings"
+)
+
+// Files worth sending for analysis when no include globs are configured
+var defaultIncludePatterns = []string{
+    "*.py", "*.js", "*.ts", "*.jsx", "*.tsx", "*.java", "*.go", "*.rb", "*.php",
+    "*.cpp", "*.c", "*.h", "*.hpp", "*.cs", "*.swift", "*.kt", "*.rs", "*.scala",
+    "*.pl", "*.r", "*.m", "*.sql", "*.sh", "*.bash",
+    "config.*", "*.config", "*.env*", "*.json", "*.yaml", "*.yml", "*.xml",
+    "build.gradle", "Dockerfile", "*.tf", "*.pp", "*.md", "*.txt",
+    "*.html", "*.htm", "*.css", "*.scss", "*.sass", "*.less",
+}
+
+// Dependency, build output and scratch directories - never the project's own code
+var defaultExcludePatterns = []string{
+    ".git/", "node_modules/", "__pycache__/", "dist/", "build/", "target/",
+    "vendor/", "tmp/", "temp/", ".venv/", "venv/",
+}
+
+// FileFilter selects the repository files read for analysis. Patterns are
+// slash-separated globs relative to the repository root: `**` matches any
+// number of directories, a pattern without a slash matches the name at any
+// depth, and a trailing slash only matches directories.
+type FileFilter struct {
+    Include []string `yaml:"include"` // replaces the default source patterns when set
+    Exclude []string `yaml:"exclude"` // added to the default excluded directories
+}
+
+// Default filter for every scan; nil uses only the built-in patterns
+var fileFilter *FileFilter
+
+// SetFileFilter sets the include/exclude globs used to collect files
+func SetFileFilter(filter *FileFilter) {
+    fileFilter = filter
+}
+
+// FileFilterFromEnv reads AEGIS_INCLUDE and AEGIS_EXCLUDE (comma-separated
+// globs). Returns nil when neither is set.
+func FileFilterFromEnv() (*FileFilter, error) {
+    filter := &FileFilter{
+        Include: splitList(os.Getenv("AEGIS_INCLUDE")),
+        Exclude: splitList(os.Getenv("AEGIS_EXCLUDE")),
+    }
+    if len(filter.Include) == 0 && len(filter.Exclude) == 0 {
+        return nil, nil
+    }
+    if err := filter.Validate(); err != nil {
+        return nil, err
+    }
+    return filter, nil
+}
+
+// Validate checks every pattern is a well-formed glob
+func (f *FileFilter) Validate() error {
+    for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+        if err := validateGlob(pattern); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func validateGlob(pattern string) error {
+    if _, err := path.Match(strings.Trim(pattern, "/"), ""); err != nil || strings.Trim(pattern, "/") == "" {
+        return fmt.Errorf("invalid file pattern %q", pattern)
+    }
+    return nil
+}
+
+// merge layers a repository's own paths over the server filter: its includes
+// replace the server's, its excludes add to them
+func (f *FileFilter) merge(repo FileFilter) *FileFilter {
+    merged := &FileFilter{}
+    if f != nil {
+        merged.Include = append(merged.Include, f.Include...)
+        merged.Exclude = append(merged.Exclude, f.Exclude...)
+    }
+    if len(repo.Include) > 0 {
+        merged.Include = append([]string{}, repo.Include...)
+    }
+    merged.Exclude = append(merged.Exclude, repo.Exclude...)
+    return merged
+}
+
+func (f *FileFilter) includes() []string {
+    if f == nil || len(f.Include) == 0 {
+        return defaultIncludePatterns
+    }
+    return f.Include
+}
+
+func (f *FileFilter) excludes() []string {
+    if f == nil {
+        return defaultExcludePatterns
+    }
+    return append(append([]string{}, defaultExcludePatterns...), f.Exclude...)
+}
+
+// matchGlob reports whether a repository-relative path matches a pattern
+func matchGlob(pattern string, relPath string, isDir bool) bool {
+    if strings.HasSuffix(pattern, "/") {
+        if !isDir {
+            return false
+        }
+        pattern = strings.TrimSuffix(pattern, "/")
+    }
+    if !strings.Contains(pattern, "/") {
+        matched, _ := path.Match(pattern, path.Base(relPath))
+        return matched
+    }
+    return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+    if len(pattern) == 0 {
+        return len(segments) == 0
+    }
+    if pattern[0] == "**" {
+        for skip := 0; skip <= len(segments); skip++ {
+            if matchSegments(pattern[1:], segments[skip:]) {
+                return true
+            }
+        }
+        return false
+    }
+    if len(segments) == 0 {
+        return false
+    }
+    if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+        return false
+    }
+    return matchSegments(pattern[1:], segments[1:])
+}
+
+func matchAnyGlob(patterns []string, relPath string, isDir bool) bool {
+    for _, pattern := range patterns {
+        if matchGlob(pattern, relPath, isDir) {
+            return true
+        }
+    }
+    return false
+}
+
+// excludedPath reports whether a file or any directory above it is excluded
+func excludedPath(excludes []string, relPath string) bool {
+    segments := strings.Split(relPath, "/")
+    for i := 1; i < len(segments); i++ {
+        if matchAnyGlob(excludes, strings.Join(segments[:i], "/"), true) {
+            return true
+        }
+    }
+    return matchAnyGlob(excludes, relPath, false)
+}
+
+// collectedFile is a file selected for analysis
+type collectedFile struct {
+    Path     string // slash-separated, relative to the repository root
+    RealPath string // where to read it from (the target for symlinks)
+}
+
+// repoEntry is a file reached by walkRepository
+type repoEntry struct {
+    collectedFile
+    Target string // repository-relative path of a symlink's target; empty for regular files
+}
+
+// walkRepository calls visit for every file under the repository root that
+// skip lets through; skip also prunes directories. Symlinked directories are
+// not followed, and a symlinked file is visited only when its target is a
+// regular file inside the repository - links leaving it are returned as
+// skipped coverage. Every scanner reading the clone from disk goes through
+// here so a malicious repository can't point one at files on the host.
+func walkRepository(ctx context.Context, repoPath string, skip func(relPath string, isDir bool) bool, visit func(entry repoEntry)) ([]FileCoverage, error) {
+    root, err := filepath.EvalSymlinks(repoPath)
+    if err != nil {
+        return nil, err
+    }
+    root, err = filepath.Abs(root)
+    if err != nil {
+        return nil, err
+    }
+
+    var skipped []FileCoverage
+    err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+        if ctxErr := ctx.Err(); ctxErr != nil {
+            return ctxErr
+        }
+        if err != nil {
+            if file == root {
+                return err
+            }
+            // Unreadable directory - scan what we can
+            return nil
+        }
+        if file == root {
+            return nil
+        }
+
+        rel, _ := filepath.Rel(root, file)
+        rel = filepath.ToSlash(rel)
+        if skip(rel, entry.IsDir()) {
+            if entry.IsDir() {
+                return filepath.SkipDir
+            }
+            return nil
+        }
+
+        switch {
+        case entry.IsDir():
+        case entry.Type().IsRegular():
+            visit(repoEntry{collectedFile: collectedFile{Path: rel, RealPath: file}})
+        case entry.Type()&fs.ModeSymlink != 0:
+            target, err := filepath.EvalSymlinks(file)
+            if err != nil {
+                return nil
+            }
+            inside, err := filepath.Rel(root, target)
+            if err != nil || inside == ".." || strings.HasPrefix(inside, ".."+string(filepath.Separator)) {
+                skipped = append(skipped, FileCoverage{Path: rel, Status: CoverageSkipped, Reason: "symlink outside repository"})
+                return nil
+            }
+            if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+                visit(repoEntry{collectedFile: collectedFile{Path: rel, RealPath: target}, Target: filepath.ToSlash(inside)})
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return skipped, nil
+}
+
+// collectFiles walks the repository and returns the files matching the filter
+// in path order. Symlinked files are read only when their target stays inside
+// the repository, isn't excluded and isn't already collected under its own
+// path. Links leaving the repository are reported in the coverage.
+func collectFiles(ctx context.Context, repoPath string, filter *FileFilter) ([]collectedFile, []FileCoverage, error) {
+    includes, excludes := filter.includes(), filter.excludes()
+    var files, links []collectedFile
+    skip := func(rel string, isDir bool) bool {
+        return matchAnyGlob(excludes, rel, isDir) || (!isDir && !matchAnyGlob(includes, rel, false))
+    }
+    skipped, err := walkRepository(ctx, repoPath, skip, func(entry repoEntry) {
+        switch {
+        case entry.Target == "":
+            files = append(files, entry.collectedFile)
+        case !excludedPath(excludes, entry.Target):
+            links = append(links, entry.collectedFile)
+        }
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+
+    // A link to a collected file would only analyze the same content twice
+    collected := make(map[string]bool, len(files))
+    for _, file := range files {
+        collected[file.RealPath] = true
+    }
+    for _, link := range links {
+        if !collected[link.RealPath] {
+            collected[link.RealPath] = true
+            files = append(files, link)
+        }
+    }
+
+    sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+    return files, skipped, nil
+}
+
+// Bytes inspected when deciding whether a file is binary (the same window git uses)
+const binarySniffLength = 8000
+
+// isBinaryFile reports whether the start of the file holds a NUL byte, which text never does
+func isBinaryFile(file string) bool {
+    handle, err := os.Open(file)
+    if err != nil {
+        return false
+    }
+    defer handle.Close()
+
+    head := make([]byte, binarySniffLength)
+    n, _ := io.ReadFull(handle, head)
+    return bytes.IndexByte(head[:n], 0) >= 0
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestCollectFiles(t *testing.T) {
+    repoPath := writeRepo(t, map[string]string{
+        "package.json":                 `{"name": "app"}`,
+        "src/app.js":                   "console.log('hi')",
+        "src/app.min.js":               "console.log('hi')",
+        "test/auth_test.go":            "package auth",
+        "docs/guide.md":                "# Guide",
+        "node_modules/lib/index.js":    "module.exports = {}",
+        "build/out.js":                 "compiled()",
+        "assets/logo.png":              "not matched",
+        "data/blob.txt":                "header\x00\x01\x02",
+        "Dockerfile":                   "FROM alpine:3.20",
+        "config/settings.yaml":         "debug: true",
+        "config/nested/deep/prod.yaml": "debug: false",
+    })
+    outside := filepath.Join(t.TempDir(), "secret.js")
+    os.WriteFile(outside, []byte("const key = 'outside'"), 0644)
+    os.Symlink(outside, filepath.Join(repoPath, "src", "escape.js"))
+    os.Symlink(filepath.Join(repoPath, "src", "app.js"), filepath.Join(repoPath, "src", "alias.js"))
+    os.Symlink(filepath.Join(repoPath, "node_modules", "lib", "index.js"), filepath.Join(repoPath, "src", "lib.js"))
+    os.Symlink(filepath.Dir(outside), filepath.Join(repoPath, "linked"))
+
+    paths := func(filter *FileFilter) ([]string, []FileCoverage) {
+        files, skipped, err := collectFiles(context.Background(), repoPath, filter)
+        if err != nil {
+            t.Fatalf("collectFiles failed: %v", err)
+        }
+        var got []string
+        for _, file := range files {
+            got = append(got, file.Path)
+        }
+        return got, skipped
+    }
+
+    got, skipped := paths(nil)
+    expected := []string{
+        "Dockerfile", "config/nested/deep/prod.yaml", "config/settings.yaml", "data/blob.txt",
+        "docs/guide.md", "package.json", "src/app.js", "src/app.min.js", "test/auth_test.go",
+    }
+    if fmt.Sprint(got) != fmt.Sprint(expected) {
+        t.Errorf("Unexpected default files:\n got      %v\n expected %v", got, expected)
+    }
+    if len(skipped) != 1 || skipped[0].Path != "src/escape.js" || skipped[0].Reason != "symlink outside repository" {
+        t.Errorf("Expected the escaping symlink to be reported, got %+v", skipped)
+    }
+
+    got, _ = paths(&FileFilter{Include: []string{"src/**", "config/**/*.yaml"}, Exclude: []string{"*.min.js", "nested/"}})
+    expected = []string{"config/settings.yaml", "src/app.js"}
+    if fmt.Sprint(got) != fmt.Sprint(expected) {
+        t.Errorf("Unexpected filtered files:\n got      %v\n expected %v", got, expected)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if _, _, err := collectFiles(ctx, repoPath, nil); err == nil {
+        t.Error("Expected a cancelled context to stop the walk")
+    }
+}
+
+func TestExtractEntireCodebaseSkipsBinaries(t *testing.T) {
+    repoPath := writeRepo(t, map[string]string{
+        "app.go":        "package main",
+        "data/blob.txt": "header\x00\x01\x02",
+    })
+    codebase, languages, skipped, err := extractEntireCodebase(context.Background(), repoPath, nil)
+    if err != nil {
+        t.Fatalf("extractEntireCodebase failed: %v", err)
+    }
+    if len(codebase) != 1 || codebase["app.go"] == "" || fmt.Sprint(languages) != "[.go]" {
+        t.Errorf("Expected only app.go to be read, got %v %v", codebase, languages)
+    }
+    if len(skipped) != 1 || skipped[0].Path != "data/blob.txt" || skipped[0].Reason != "binary file" {
+        t.Errorf("Expected the binary file to be reported as skipped, got %+v", skipped)
+    }
+}
+
+func TestMatchGlob(t *testing.T) {
+    cases := []struct {
+        pattern string
+        path    string
+        isDir   bool
+        match   bool
+    }{
+        {"*.js", "src/deep/app.js", false, true},
+        {"src/*.js", "src/deep/app.js", false, false},
+        {"src/**/*.js", "src/deep/app.js", false, true},
+        {"src/**/*.js", "src/app.js", false, true},
+        {"/src/**", "lib/src/app.js", false, false},
+        {"test/", "test", true, true},
+        {"test/", "test", false, false},
+        {"**/fixtures/**", "a/fixtures/b/c.json", false, true},
+    }
+    for _, c := range cases {
+        if matchGlob(c.pattern, c.path, c.isDir) != c.match {
+            t.Errorf("matchGlob(%q, %q, %v) = %v, expected %v", c.pattern, c.path, c.isDir, !c.match, c.match)
+        }
+    }
+
+    if err := (&FileFilter{Exclude: []string{"src/[a-"}}).Validate(); err == nil {
+        t.Error("Expected a malformed glob to be rejected")
+    }
+}
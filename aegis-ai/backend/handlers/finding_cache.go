@@ -0,0 +1,284 @@
+package handlers
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sync"
+)
+
+// ErrCacheMiss is returned by a FindingCacheBackend when the key is unknown
+var ErrCacheMiss = errors.New("finding cache miss")
+
+// CachedFindings are the risks a model reported for one file segment
+type CachedFindings struct {
+    Path          string `json:"path"`
+    Model         string `json:"model"`
+    PromptVersion string `json:"prompt_version"`
+    CriticalRisks []Risk `json:"critical_risks"`
+    HighRisks     []Risk `json:"high_risks"`
+    MediumRisks   []Risk `json:"medium_risks"`
+    CreatedAt     string `json:"created_at"`
+}
+
+// FindingCacheBackend persists cached findings by key.
+// Implementations must be safe for concurrent use.
+type FindingCacheBackend interface {
+    Get(key string) (*CachedFindings, error)
+    Put(key string, entry *CachedFindings) error
+    // DeleteWhere removes every entry fn matches and returns how many were removed
+    DeleteWhere(fn func(entry *CachedFindings) bool) (int, error)
+    Len() (int, error)
+}
+
+// FindingCacheStats is reported by GET /api/cache
+type FindingCacheStats struct {
+    Entries       int     `json:"entries"`
+    Hits          int64   `json:"hits"`
+    Misses        int64   `json:"misses"`
+    HitRate       float64 `json:"hit_rate"`
+    TokensSaved   int64   `json:"tokens_saved"`
+    PromptVersion string  `json:"prompt_version"`
+}
+
+// FindingCache is a content-addressed cache of LLM findings per file segment,
+// keyed by segment content hash + prompt version + model, so rescans only
+// send files that changed since the last scan
+type FindingCache struct {
+    backend FindingCacheBackend
+
+    mu          sync.Mutex
+    hits        int64
+    misses      int64
+    tokensSaved int64
+}
+
+func NewFindingCache(backend FindingCacheBackend) *FindingCache {
+    return &FindingCache{backend: backend}
+}
+
+var findingCache = NewFindingCache(NewMemoryFindingCacheBackend())
+
+// SetFindingCache overrides the cache used for analysis; nil disables caching.
+// It must be called before the server starts handling requests.
+func SetFindingCache(cache *FindingCache) {
+    findingCache = cache
+}
+
+// findingCacheKey identifies a segment's findings for one model and prompt version
+func findingCacheKey(segment fileSegment, model string, promptVersion string) string {
+    sum := sha256.Sum256([]byte(segment.Content))
+    return fmt.Sprintf("%s|%s|%s|%d-%d|%s", promptVersion, model, segment.Path,
+        segment.StartLine, segment.EndLine, hex.EncodeToString(sum[:]))
+}
+
+// lookupBatch splits a batch into segments with cached findings (from any of
+// models, in failover order) and segments that still have to be analyzed.
+// The cached findings are returned as one response.
+func (c *FindingCache) lookupBatch(segments []fileSegment, models []string, promptVersion string) (*AIAnalysisResponse, []fileSegment, []fileSegment) {
+    if c == nil {
+        return nil, nil, segments
+    }
+
+    var entries []*CachedFindings
+    var cached, pending []fileSegment
+    for _, segment := range segments {
+        entry := c.lookup(segment, models, promptVersion)
+        if entry == nil {
+            pending = append(pending, segment)
+            continue
+        }
+        entries = append(entries, entry)
+        cached = append(cached, segment)
+    }
+
+    if len(entries) == 0 {
+        return nil, nil, pending
+    }
+    response := &AIAnalysisResponse{CriticalRisks: []Risk{}, HighRisks: []Risk{}, MediumRisks: []Risk{}}
+    for _, entry := range entries {
+        response.CriticalRisks = append(response.CriticalRisks, entry.CriticalRisks...)
+        response.HighRisks = append(response.HighRisks, entry.HighRisks...)
+        response.MediumRisks = append(response.MediumRisks, entry.MediumRisks...)
+    }
+    return response, cached, pending
+}
+
+func (c *FindingCache) lookup(segment fileSegment, models []string, promptVersion string) *CachedFindings {
+    for _, model := range models {
+        entry, err := c.backend.Get(findingCacheKey(segment, model, promptVersion))
+        if err != nil {
+            if err != ErrCacheMiss {
+                fmt.Printf("⚠️ Finding cache read failed for %s: %v\n", segment.Path, err)
+            }
+            continue
+        }
+
+        c.mu.Lock()
+        c.hits++
+        c.tokensSaved += int64(segment.Tokens)
+        c.mu.Unlock()
+        return entry
+    }
+
+    c.mu.Lock()
+    c.misses++
+    c.mu.Unlock()
+    return nil
+}
+
+// storeBatch caches what model reported for each segment it was sent,
+// including segments without findings so clean files are skipped next time
+func (c *FindingCache) storeBatch(segments []fileSegment, model string, promptVersion string, response *AIAnalysisResponse) {
+    if c == nil || model == "" || response == nil {
+        return
+    }
+
+    entries := make([]*CachedFindings, len(segments))
+    for i, segment := range segments {
+        entries[i] = &CachedFindings{
+            Path:          segment.Path,
+            Model:         model,
+            PromptVersion: promptVersion,
+            CriticalRisks: []Risk{},
+            HighRisks:     []Risk{},
+            MediumRisks:   []Risk{},
+            CreatedAt:     timestamp(),
+        }
+    }
+
+    attribute := func(risks []Risk, tier func(entry *CachedFindings) *[]Risk) {
+        for _, risk := range risks {
+            if index := segmentForRisk(segments, risk); index != -1 {
+                list := tier(entries[index])
+                *list = append(*list, risk)
+            }
+        }
+    }
+    attribute(response.CriticalRisks, func(entry *CachedFindings) *[]Risk { return &entry.CriticalRisks })
+    attribute(response.HighRisks, func(entry *CachedFindings) *[]Risk { return &entry.HighRisks })
+    attribute(response.MediumRisks, func(entry *CachedFindings) *[]Risk { return &entry.MediumRisks })
+
+    for i, segment := range segments {
+        if err := c.backend.Put(findingCacheKey(segment, model, promptVersion), entries[i]); err != nil {
+            fmt.Printf("⚠️ Finding cache write failed for %s: %v\n", segment.Path, err)
+        }
+    }
+}
+
+// segmentForRisk returns the index of the segment a finding belongs to: the one
+// containing its line, else the first segment of its file, else -1
+func segmentForRisk(segments []fileSegment, risk Risk) int {
+    path := normalizeRiskPath(risk.FilePath)
+    if path == "" {
+        path = normalizeRiskPath(risk.File)
+    }
+    line := risk.LineNumber
+    if line == 0 {
+        line = risk.Line
+    }
+
+    fallback := -1
+    for i, segment := range segments {
+        if segment.Path != path {
+            continue
+        }
+        if line >= segment.StartLine && line <= segment.EndLine {
+            return i
+        }
+        if fallback == -1 {
+            fallback = i
+        }
+    }
+    return fallback
+}
+
+// Invalidate removes cached findings rendered by other prompt templates than
+// the active ones, or every entry when all is true, and returns how many were removed
+func (c *FindingCache) Invalidate(all bool) (int, error) {
+    if c == nil {
+        return 0, nil
+    }
+    prompts := activePrompts()
+    return c.backend.DeleteWhere(func(entry *CachedFindings) bool {
+        return all || !prompts.IsCurrent(entry.PromptVersion)
+    })
+}
+
+func (c *FindingCache) Stats() FindingCacheStats {
+    if c == nil {
+        return FindingCacheStats{PromptVersion: activePrompts().ID()}
+    }
+
+    entries, err := c.backend.Len()
+    if err != nil {
+        fmt.Printf("⚠️ Could not count finding cache entries: %v\n", err)
+    }
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    stats := FindingCacheStats{
+        Entries:       entries,
+        Hits:          c.hits,
+        Misses:        c.misses,
+        TokensSaved:   c.tokensSaved,
+        PromptVersion: activePrompts().ID(),
+    }
+    if total := c.hits + c.misses; total > 0 {
+        stats.HitRate = float64(c.hits) / float64(total)
+    }
+    return stats
+}
+
+// MemoryFindingCacheBackend keeps cached findings in a map - used for tests
+// and when the analysis database can't be opened
+type MemoryFindingCacheBackend struct {
+    mu      sync.RWMutex
+    entries map[string]*CachedFindings
+}
+
+func NewMemoryFindingCacheBackend() *MemoryFindingCacheBackend {
+    return &MemoryFindingCacheBackend{entries: make(map[string]*CachedFindings)}
+}
+
+func (b *MemoryFindingCacheBackend) Get(key string) (*CachedFindings, error) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+
+    entry, exists := b.entries[key]
+    if !exists {
+        return nil, ErrCacheMiss
+    }
+    copied := *entry
+    return &copied, nil
+}
+
+func (b *MemoryFindingCacheBackend) Put(key string, entry *CachedFindings) error {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    copied := *entry
+    b.entries[key] = &copied
+    return nil
+}
+
+func (b *MemoryFindingCacheBackend) DeleteWhere(fn func(entry *CachedFindings) bool) (int, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    removed := 0
+    for key, entry := range b.entries {
+        if fn(entry) {
+            delete(b.entries, key)
+            removed++
+        }
+    }
+    return removed, nil
+}
+
+func (b *MemoryFindingCacheBackend) Len() (int, error) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    return len(b.entries), nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var findingCacheBucket = []byte("finding_cache")
+
+// BoltFindingCacheBackend keeps cached findings in the analysis database
+type BoltFindingCacheBackend struct {
+    db *bolt.DB
+}
+
+// FindingCacheBackend returns a cache backend sharing the store's database file
+func (s *BoltAnalysisStore) FindingCacheBackend() (*BoltFindingCacheBackend, error) {
+    err := s.db.Update(func(tx *bolt.Tx) error {
+        _, err := tx.CreateBucketIfNotExists(findingCacheBucket)
+        return err
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to initialize finding cache: %v", err)
+    }
+    return &BoltFindingCacheBackend{db: s.db}, nil
+}
+
+func (b *BoltFindingCacheBackend) Get(key string) (*CachedFindings, error) {
+    var entry CachedFindings
+    err := b.db.View(func(tx *bolt.Tx) error {
+        data := tx.Bucket(findingCacheBucket).Get([]byte(key))
+        if data == nil {
+            return ErrCacheMiss
+        }
+        return json.Unmarshal(data, &entry)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &entry, nil
+}
+
+func (b *BoltFindingCacheBackend) Put(key string, entry *CachedFindings) error {
+    data, err := json.Marshal(entry)
+    if err != nil {
+        return fmt.Errorf("failed to encode cached findings for %s: %v", entry.Path, err)
+    }
+
+    return b.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(findingCacheBucket).Put([]byte(key), data)
+    })
+}
+
+func (b *BoltFindingCacheBackend) DeleteWhere(fn func(entry *CachedFindings) bool) (int, error) {
+    removed := 0
+    err := b.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(findingCacheBucket)
+
+        // Collect first - bbolt cursors must not be mutated during ForEach.
+        // Entries that no longer decode are always removed.
+        var keys [][]byte
+        err := bucket.ForEach(func(key, data []byte) error {
+            var entry CachedFindings
+            if err := json.Unmarshal(data, &entry); err != nil || fn(&entry) {
+                keys = append(keys, append([]byte(nil), key...))
+            }
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+
+        for _, key := range keys {
+            if err := bucket.Delete(key); err != nil {
+                return err
+            }
+        }
+        removed = len(keys)
+        return nil
+    })
+    return removed, err
+}
+
+func (b *BoltFindingCacheBackend) Len() (int, error) {
+    count := 0
+    err := b.db.View(func(tx *bolt.Tx) error {
+        count = tx.Bucket(findingCacheBucket).Stats().KeyN
+        return nil
+    })
+    return count, err
+}
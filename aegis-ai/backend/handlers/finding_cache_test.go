@@ -0,0 +1,119 @@
+package handlers
+
+import (
+    "context"
+    "path/filepath"
+    "testing"
+)
+
+// useFindingCache gives the test an empty cache so scans don't see each other's findings
+func useFindingCache(t *testing.T) *FindingCache {
+    t.Helper()
+    previous := findingCache
+    cache := NewFindingCache(NewMemoryFindingCacheBackend())
+    SetFindingCache(cache)
+    t.Cleanup(func() { SetFindingCache(previous) })
+    return cache
+}
+
+func TestFindingCacheSkipsUnchangedFiles(t *testing.T) {
+    cache := useFindingCache(t)
+    mock := NewMockLLMProvider(nil)
+    mock.Default = loadFixture(t, "vulnerable-app.json")
+    codebase := map[string]string{
+        "config.js":    numberedLines(30),
+        "app.js":       numberedLines(30),
+        "package.json": numberedLines(30),
+    }
+    scan := func() *AIAnalysisResponse {
+        batches := planAnalysisBatches(codebase, 200, 10)
+        response, outcomes, err := analyzeBatches(context.Background(), []LLMProvider{mock}, batches, AnalysisContext{}, nil)
+        if err != nil {
+            t.Fatalf("analyzeBatches failed: %v", err)
+        }
+        if coverage := buildCoverageReport(codebase, nil, batches, outcomes); coverage.FullyScanned != len(codebase) {
+            t.Errorf("Expected cached files to count as scanned, got %+v", coverage)
+        }
+        return response
+    }
+
+    first := scan()
+    firstCalls := len(mock.Calls())
+    if firstCalls == 0 {
+        t.Fatal("Expected the first scan to call the model")
+    }
+
+    second := scan()
+    if calls := len(mock.Calls()); calls != firstCalls {
+        t.Errorf("Expected an unchanged rescan to be served from cache, got %d new calls", calls-firstCalls)
+    }
+    if len(second.CriticalRisks) != len(first.CriticalRisks) || len(second.HighRisks) != len(first.HighRisks) ||
+        len(second.MediumRisks) != len(first.MediumRisks) {
+        t.Errorf("Cached findings differ: %d/%d/%d vs %d/%d/%d", len(second.CriticalRisks), len(second.HighRisks),
+            len(second.MediumRisks), len(first.CriticalRisks), len(first.HighRisks), len(first.MediumRisks))
+    }
+
+    codebase["app.js"] = numberedLines(31)
+    scan()
+    calls := mock.Calls()
+    if len(calls) != firstCalls+1 {
+        t.Fatalf("Expected only the changed file to be re-analyzed, got %d new calls", len(calls)-firstCalls)
+    }
+
+    stats := cache.Stats()
+    if stats.Hits != 5 || stats.Misses != 4 || stats.TokensSaved == 0 || stats.Entries != 4 {
+        t.Errorf("Unexpected cache stats: %+v", stats)
+    }
+}
+
+func TestFindingCacheKeyAndInvalidate(t *testing.T) {
+    segment := fileSegment{Path: "app.js", StartLine: 1, EndLine: 2, TotalLines: 2, Content: "a\nb"}
+    key := findingCacheKey(segment, "mock", "v1")
+    changed := segment
+    changed.Content = "a\nc"
+    for _, other := range []string{
+        findingCacheKey(changed, "mock", "v1"),
+        findingCacheKey(segment, "llama3.1", "v1"),
+        findingCacheKey(segment, "mock", "v2"),
+    } {
+        if other == key {
+            t.Errorf("Expected content, model and prompt version to change the key: %s", key)
+        }
+    }
+
+    store, err := NewBoltAnalysisStore(filepath.Join(t.TempDir(), "aegis.db"))
+    if err != nil {
+        t.Fatalf("Failed to open bolt store: %v", err)
+    }
+    defer store.Close()
+    boltBackend, err := store.FindingCacheBackend()
+    if err != nil {
+        t.Fatalf("Failed to open bolt cache: %v", err)
+    }
+
+    backends := map[string]FindingCacheBackend{"memory": NewMemoryFindingCacheBackend(), "bolt": boltBackend}
+    for name, backend := range backends {
+        t.Run(name, func(t *testing.T) {
+            cache := NewFindingCache(backend)
+            current := activePrompts().PromptVersion(AnalysisContext{})
+            response := &AIAnalysisResponse{HighRisks: []Risk{{Title: "XSS", FilePath: "./app.js", LineNumber: 2}}}
+            cache.storeBatch([]fileSegment{segment}, "mock", current, response)
+            backend.Put("stale", &CachedFindings{Path: "old.js", PromptVersion: "v0-00000000/audit.tmpl"})
+
+            cached, hits, pending := cache.lookupBatch([]fileSegment{segment, changed}, []string{"other", "mock"}, current)
+            if cached == nil || len(cached.HighRisks) != 1 || len(hits) != 1 || len(pending) != 1 || pending[0].Content != changed.Content {
+                t.Fatalf("Unexpected lookup: %+v hits=%d pending=%d", cached, len(hits), len(pending))
+            }
+
+            if removed, err := cache.Invalidate(false); err != nil || removed != 1 {
+                t.Errorf("Expected only the stale prompt version removed, got %d (%v)", removed, err)
+            }
+            if removed, err := cache.Invalidate(true); err != nil || removed != 1 {
+                t.Errorf("Expected the remaining entry removed, got %d (%v)", removed, err)
+            }
+            if stats := cache.Stats(); stats.Entries != 0 || stats.PromptVersion != activePrompts().ID() {
+                t.Errorf("Unexpected stats after invalidation: %+v", stats)
+            }
+        })
+    }
+}
@@ -43,8 +43,8 @@ func ApplyFix(c *gin.Context) {
     }
     
     // Get analysis from storage
-    analysis, exists := analysisStorage[analysisID]
-    if !exists {
+    analysis, err := analysisService.Get(analysisID)
+    if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
         return
     }
@@ -106,13 +106,14 @@ func ApplyFix(c *gin.Context) {
 }
 
 // resolveFilePath attempts to find a valid file path for the fix
-func resolveFilePath(analysis *Analysis, fixIndex int) (string, int) {
+func resolveFilePath(analysis *ScanRecord, fixIndex int) (string, int) {
     fix := analysis.AutoFixes[fixIndex]
+    risks := combineAllRisks(&analysis.AIAnalysisResponse)
     
     fmt.Printf("🔍 Resolving file path for fix: %s\n", fix.RiskTitle)
     
     // Strategy 1: Look for matching risks with the same title
-    for _, risk := range analysis.Risks {
+    for _, risk := range risks {
         if risk.Title == fix.RiskTitle {
             if risk.FilePath != "" && risk.FilePath != "unknown" {
                 fmt.Printf("✅ Found matching risk with file path: %s:%d\n", risk.FilePath, risk.LineNumber)
@@ -122,7 +123,7 @@ func resolveFilePath(analysis *Analysis, fixIndex int) (string, int) {
     }
     
     // Strategy 2: Look for risks with similar titles
-    for _, risk := range analysis.Risks {
+    for _, risk := range risks {
         if strings.Contains(strings.ToLower(risk.Title), strings.ToLower(fix.RiskTitle)) ||
            strings.Contains(strings.ToLower(fix.RiskTitle), strings.ToLower(risk.Title)) {
             if risk.FilePath != "" && risk.FilePath != "unknown" {
@@ -133,7 +134,7 @@ func resolveFilePath(analysis *Analysis, fixIndex int) (string, int) {
     }
     
     // Strategy 3: Look for any risk with a valid file path
-    for _, risk := range analysis.Risks {
+    for _, risk := range risks {
         if risk.FilePath != "" && risk.FilePath != "unknown" {
             fmt.Printf("⚠️ Using fallback file path from any risk: %s:%d\n", risk.FilePath, risk.LineNumber)
             return risk.FilePath, risk.LineNumber
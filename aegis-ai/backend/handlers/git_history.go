@@ -0,0 +1,261 @@
+package handlers
+
+import (
+    "bufio"
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    defaultHistoryDepth = 100
+    maxHistoryDepth     = 1000
+    maxHistoryWindow    = 365 * 24 * time.Hour // how far back Since may reach
+)
+
+// Separators in the git log format - they can't appear in names or hashes
+const (
+    historyCommitMarker = "\x1e"
+    historyFieldMarker  = "\x1f"
+)
+
+// HistoryScanOptions turns on secret scanning of past commits. Depth limits
+// the number of commits walked; Since (YYYY-MM-DD or RFC 3339, at most a year
+// ago) walks every commit after that date instead.
+type HistoryScanOptions struct {
+    Depth int    `json:"depth,omitempty"`
+    Since string `json:"since,omitempty"`
+
+    since time.Time
+}
+
+// HistorySecret is a secret added in a past commit. Commit is the oldest
+// walked commit that introduced it; StillPresent tells whether HEAD still has it.
+type HistorySecret struct {
+    Risk
+    Commit       string    `json:"commit"`
+    Author       string    `json:"author"`
+    CommittedAt  time.Time `json:"committed_at"`
+    StillPresent bool      `json:"still_present"`
+}
+
+// Validate parses Since and applies the depth default and limit
+func (o *HistoryScanOptions) Validate() error {
+    if o.Since != "" {
+        since, err := time.Parse(time.RFC3339, o.Since)
+        if err != nil {
+            if since, err = time.Parse("2006-01-02", o.Since); err != nil {
+                return fmt.Errorf("invalid history since %q: use YYYY-MM-DD or RFC 3339", o.Since)
+            }
+        }
+        if time.Since(since) > maxHistoryWindow {
+            return fmt.Errorf("history since %q is too far back: the limit is %d days", o.Since, int(maxHistoryWindow.Hours()/24))
+        }
+        o.since = since
+    }
+    if o.Depth < 0 || o.Depth > maxHistoryDepth {
+        return fmt.Errorf("history depth must be between 0 (default) and %d", maxHistoryDepth)
+    }
+    if o.Depth == 0 && o.since.IsZero() {
+        o.Depth = defaultHistoryDepth
+    }
+    return nil
+}
+
+// HistoryScanFromEnv reads AEGIS_HISTORY_SCAN=on with AEGIS_HISTORY_DEPTH or
+// AEGIS_HISTORY_SINCE. Returns nil when history scanning is off.
+func HistoryScanFromEnv() (*HistoryScanOptions, error) {
+    switch strings.ToLower(os.Getenv("AEGIS_HISTORY_SCAN")) {
+    case "on", "true", "1":
+    default:
+        return nil, nil
+    }
+    options := &HistoryScanOptions{
+        Depth: envInt("AEGIS_HISTORY_DEPTH", 0),
+        Since: os.Getenv("AEGIS_HISTORY_SINCE"),
+    }
+    if err := options.Validate(); err != nil {
+        return nil, err
+    }
+    return options, nil
+}
+
+// cloneArgs returns the git clone flags that fetch enough history. A nil
+// receiver is a normal scan that only needs the latest commit.
+func (o *HistoryScanOptions) cloneArgs() []string {
+    if o == nil {
+        return []string{"--depth", "1"}
+    }
+    if !o.since.IsZero() {
+        return []string{"--shallow-since=" + o.since.Format(time.RFC3339)}
+    }
+    // One extra commit so the oldest walked commit has a parent to diff against
+    return []string{"--depth", strconv.Itoa(o.Depth + 1)}
+}
+
+func (o *HistoryScanOptions) logArgs() []string {
+    if !o.since.IsZero() {
+        return []string{"--since=" + o.since.Format(time.RFC3339)}
+    }
+    return []string{"-n", strconv.Itoa(o.Depth)}
+}
+
+// Default history mode for webhook scans and manual scans that don't ask for one
+var historyScan *HistoryScanOptions
+
+// SetHistoryScan sets the default history mode; nil scans only the latest commit
+func SetHistoryScan(options *HistoryScanOptions) {
+    historyScan = options
+}
+
+// historyCommit is one commit header from the git log output
+type historyCommit struct {
+    SHA         string
+    Author      string
+    CommittedAt time.Time
+}
+
+// scanGitHistory runs the secret scanner over the lines each walked commit
+// added. Shallow-clone boundary commits are skipped: git shows their whole
+// tree as added, which says nothing about when a secret was introduced.
+func scanGitHistory(ctx context.Context, repoPath string, options *HistoryScanOptions) ([]HistorySecret, error) {
+    args := []string{"-C", repoPath, "log", "-p", "--no-color", "--no-ext-diff", "--unified=0",
+        "--format=" + historyCommitMarker + "%H" + historyFieldMarker + "%an <%ae>" + historyFieldMarker + "%aI"}
+    args = append(args, options.logArgs()...)
+
+    // --shallow-since cuts the clone at the oldest commit inside the window,
+    // which can't be diffed; one more commit moves the cut outside it
+    if !options.since.IsZero() && len(shallowBoundaries(repoPath)) > 0 {
+        if err := exec.CommandContext(ctx, "git", "-C", repoPath, "fetch", "-q", "--deepen=1").Run(); err != nil {
+            fmt.Printf("⚠️ Could not deepen the history clone, its oldest commit won't be scanned: %v\n", err)
+        }
+    }
+    output, err := exec.CommandContext(ctx, "git", args...).Output()
+    if err != nil {
+        return nil, fmt.Errorf("git log failed: %v", err)
+    }
+
+    secrets := parseHistorySecrets(string(output), shallowBoundaries(repoPath))
+    for i := range secrets {
+        present, err := presentAtHead(ctx, repoPath, secrets[i].match)
+        if err != nil {
+            return nil, err
+        }
+        secrets[i].StillPresent = present
+    }
+
+    result := make([]HistorySecret, len(secrets))
+    for i, secret := range secrets {
+        result[i] = secret.HistorySecret
+    }
+    return result, nil
+}
+
+// shallowBoundaries lists the commits whose parents were cut off by a shallow clone
+func shallowBoundaries(repoPath string) map[string]bool {
+    boundaries := make(map[string]bool)
+    data, err := os.ReadFile(filepath.Join(repoPath, ".git", "shallow"))
+    if err != nil {
+        return boundaries
+    }
+    for _, sha := range strings.Fields(string(data)) {
+        boundaries[sha] = true
+    }
+    return boundaries
+}
+
+type historyMatch struct {
+    HistorySecret
+    match string
+}
+
+// parseHistorySecrets scans the added lines of a `git log -p --unified=0`
+// output, newest commit first. A secret added more than once is reported
+// against the oldest commit that added it.
+func parseHistorySecrets(output string, boundaries map[string]bool) []historyMatch {
+    var secrets []historyMatch
+    index := make(map[string]int)
+
+    var commit historyCommit
+    skip, inHeader := true, false
+    path, line, checkEntropy := "", 0, false
+
+    scanner := bufio.NewScanner(strings.NewReader(output))
+    scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+    for scanner.Scan() {
+        text := scanner.Text()
+        switch {
+        case strings.HasPrefix(text, historyCommitMarker):
+            fields := strings.Split(strings.TrimPrefix(text, historyCommitMarker), historyFieldMarker)
+            if len(fields) != 3 {
+                skip = true
+                continue
+            }
+            committedAt, _ := time.Parse(time.RFC3339, fields[2])
+            commit = historyCommit{SHA: fields[0], Author: fields[1], CommittedAt: committedAt}
+            skip = boundaries[commit.SHA]
+            path = ""
+        case skip:
+        case strings.HasPrefix(text, "diff --git "):
+            path, inHeader = "", true
+        case inHeader && strings.HasPrefix(text, "+++ "):
+            path = ""
+            if target := strings.TrimPrefix(text, "+++ "); strings.HasPrefix(target, "b/") {
+                path = strings.TrimPrefix(target, "b/")
+                checkEntropy = entropyCheckedFile(path)
+            }
+        case strings.HasPrefix(text, "@@ "):
+            line, inHeader = hunkStartLine(text), false
+        case !inHeader && strings.HasPrefix(text, "+") && path != "":
+            for _, finding := range scanLineSecrets(path, line, text[1:], checkEntropy) {
+                key := finding.Risk.RuleID + "\x00" + finding.Match
+                entry := historyMatch{
+                    HistorySecret: HistorySecret{
+                        Risk:        finding.Risk,
+                        Commit:      commit.SHA,
+                        Author:      commit.Author,
+                        CommittedAt: commit.CommittedAt,
+                    },
+                    match: finding.Match,
+                }
+                if existing, seen := index[key]; seen {
+                    secrets[existing] = entry
+                    continue
+                }
+                index[key] = len(secrets)
+                secrets = append(secrets, entry)
+            }
+            line++
+        }
+    }
+    return secrets
+}
+
+// hunkStartLine reads the new-file start line from "@@ -a,b +c,d @@"
+func hunkStartLine(header string) int {
+    fields := strings.Fields(header)
+    if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+        return 0
+    }
+    start, _ := strconv.Atoi(strings.SplitN(strings.TrimPrefix(fields[2], "+"), ",", 2)[0])
+    return start
+}
+
+// presentAtHead reports whether any tracked file at HEAD still contains secret
+func presentAtHead(ctx context.Context, repoPath string, secret string) (bool, error) {
+    err := exec.CommandContext(ctx, "git", "-C", repoPath, "grep", "-q", "-F", "-e", secret, "HEAD", "--").Run()
+    if err == nil {
+        return true, nil
+    }
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+        return false, nil
+    }
+    return false, fmt.Errorf("git grep failed: %v", err)
+}
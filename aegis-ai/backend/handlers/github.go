@@ -1,6 +1,7 @@
 package handlers
 
 import (
+    "context"
     "fmt"
     "os/exec"
     "github.com/gin-gonic/gin"
@@ -34,16 +35,38 @@ func HandleWebhook(c *gin.Context) {
     
     // 🚀 IMMEDIATE RESPONSE - process async
     if event.Action == "opened" || event.Action == "synchronize" {
-        fmt.Printf("🎯 Starting ASYNC analysis for PR #%d\n", event.Number)
+        fmt.Printf("🎯 Queueing ASYNC analysis for PR #%d\n", event.Number)
         
-        // Process in background goroutine
-        go processWithAIAsync(event)
+        // Store the queued scan so fixes can be applied later
+        record, err := analysisService.Create(TriggerWebhook, event.Repository.CloneURL, func(record *ScanRecord) {
+            record.Ref = event.PullRequest.Head.Ref
+            record.CommitSHA = event.PullRequest.Head.SHA
+            record.PRNumber = event.Number
+            record.PRURL = event.PullRequest.HTMLURL
+        })
+        if err != nil {
+            fmt.Printf("❌ [PR #%d] Failed to store analysis: %v\n", event.Number, err)
+            c.JSON(500, gin.H{"error": "Failed to store analysis"})
+            return
+        }
+        analysisID := record.ID
+        
+        // A new push to the same PR supersedes the queued scan
+        history := historyScan
+        analysisService.Enqueue(&ScanJob{
+            AnalysisID: analysisID,
+            Key:        fmt.Sprintf("%s#%d", event.Repository.CloneURL, event.Number),
+            Process: func(ctx context.Context, analysisID string) error {
+                return processWithAIAsync(ctx, analysisID, event, history)
+            },
+        })
         
         // Respond immediately to prevent timeout
         c.JSON(202, gin.H{
-            "status":  "accepted", 
-            "message": "AI analysis started in background",
-            "pr":      event.Number,
+            "status":      "accepted", 
+            "message":     "AI analysis queued",
+            "pr":          event.Number,
+            "analysis_id": analysisID,
         })
         return
     }
@@ -52,32 +75,42 @@ func HandleWebhook(c *gin.Context) {
 }
 
 // Async processing
-func processWithAIAsync(event PullRequestEvent) {
+func processWithAIAsync(ctx context.Context, analysisID string, event PullRequestEvent, history *HistoryScanOptions) error {
     fmt.Printf("🔍 [ASYNC] Starting analysis for PR #%d\n", event.Number)
     
-    repoPath := "/tmp/repo_ai_scan_" + fmt.Sprintf("%d", event.Number)
+    repoPath := "/tmp/repo_ai_scan_" + analysisID
     exec.Command("rm", "-rf", repoPath).Run()
+    defer exec.Command("rm", "-rf", repoPath).Run()
     
-    // Fast clone with minimal data
-    cloneCmd := exec.Command("git", "clone", "--depth", "1", "--filter=blob:none", event.Repository.CloneURL, repoPath)
+    // Fast clone with minimal data - history mode needs the blobs of past commits
+    analysisService.SetStatus(analysisID, StatusCloning)
+    cloneArgs := append([]string{"clone"}, history.cloneArgs()...)
+    if history == nil {
+        cloneArgs = append(cloneArgs, "--filter=blob:none")
+    }
+    cloneCmd := exec.CommandContext(ctx, "git", append(cloneArgs, event.Repository.CloneURL, repoPath)...)
     if err := cloneCmd.Run(); err != nil {
         fmt.Printf("❌ Clone failed: %v\n", err)
-        return
+        return fmt.Errorf("clone failed: %v", err)
     }
     
     fmt.Printf("📁 [PR #%d] Repository cloned, starting AI analysis...\n", event.Number)
+    analysisService.SetStatus(analysisID, StatusAnalyzing)
     
     // ANALYZE WITH AI
-    analysis, err := AnalyzeEntireCodebase(repoPath)
+    analysis, err := AnalyzeEntireCodebase(ctx, repoPath, history)
     if err != nil {
         fmt.Printf("❌ [PR #%d] AI Analysis failed: %v\n", event.Number, err)
-        return
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
     }
     
-    // Store analysis with PR-specific ID
-    analysisID := fmt.Sprintf("pr_%d", event.Number)
-    analyses[analysisID] = analysis
-    analysisStatus[analysisID] = "completed"
+    if err := analysisService.Complete(analysisID, analysis); err != nil {
+        fmt.Printf("❌ [PR #%d] Failed to store analysis: %v\n", event.Number, err)
+        return nil
+    }
     
     fmt.Printf("✅ [PR #%d] Analysis complete: %d critical risks found\n", event.Number, len(analysis.CriticalRisks))
     
@@ -86,6 +119,15 @@ func processWithAIAsync(event PullRequestEvent) {
         fmt.Printf("❌ Failed to post to GitHub: %v\n", err)
     }
     
-    // Cleanup
-    exec.Command("rm", "-rf", repoPath).Run()
+    // The .aegis.yml verdict as a commit status, so it can block the merge
+    if analysis.Gate != nil {
+        if commitStatusPoster == nil {
+            fmt.Printf("⚠️ [PR #%d] Policy status not posted - set GITHUB_TOKEN\n", event.Number)
+        } else if repo, _, err := extractRepoAndPR(event.PullRequest.HTMLURL); err != nil {
+            fmt.Printf("❌ Failed to post policy status: %v\n", err)
+        } else if err := commitStatusPoster.PostPolicyStatus(ctx, repo, event.PullRequest.Head.SHA, analysis.Gate); err != nil {
+            fmt.Printf("❌ Failed to post policy status: %v\n", err)
+        }
+    }
+    return nil
 }
\ No newline at end of file
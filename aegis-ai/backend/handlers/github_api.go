@@ -2,9 +2,11 @@ package handlers
 
 import (
     "bytes"
+    "context"
     "encoding/json"
     "fmt"
     "net/http"
+    "os"
     "strconv"
     "strings"
 )
@@ -50,7 +52,27 @@ func createGitHubComment(analysis *AIAnalysisResponse, prNumber int) string {
     comment.WriteString(fmt.Sprintf("- **Critical Risks**: %d\n", len(analysis.CriticalRisks)))
     comment.WriteString(fmt.Sprintf("- **High Risks**: %d\n", len(analysis.HighRisks)))
     comment.WriteString(fmt.Sprintf("- **Medium Risks**: %d\n", len(analysis.MediumRisks)))
-    comment.WriteString(fmt.Sprintf("- **Auto-Fixes Provided**: %d\n\n", len(analysis.AutoFixes)))
+    comment.WriteString(fmt.Sprintf("- **Auto-Fixes Provided**: %d\n", len(analysis.AutoFixes)))
+    if cvss := highestCVSS(analysis); cvss > 0 {
+        comment.WriteString(fmt.Sprintf("- **Highest CVSS**: %.1f\n", cvss))
+    }
+    comment.WriteString("\n")
+    
+    // Repository policy from .aegis.yml
+    if analysis.Gate != nil {
+        if analysis.Gate.Failed {
+            comment.WriteString("### ❌ Policy Check Failed\n")
+            for _, reason := range analysis.Gate.Reasons {
+                comment.WriteString(fmt.Sprintf("- %s\n", reason))
+            }
+            comment.WriteString("\n")
+        } else {
+            comment.WriteString("### ✅ Policy Check Passed\n\n")
+        }
+    }
+    if analysis.RepoConfig != nil && len(analysis.RepoConfig.Errors) > 0 {
+        comment.WriteString(fmt.Sprintf("⚠️ **%s problems** (ignored): %s\n\n", analysis.RepoConfig.Path, strings.Join(analysis.RepoConfig.Errors, "; ")))
+    }
     
     // Compliance Score
     complianceScore := calculateComplianceScore(analysis)
@@ -63,7 +85,13 @@ func createGitHubComment(analysis *AIAnalysisResponse, prNumber int) string {
             comment.WriteString(fmt.Sprintf("#### %d. %s\n", i+1, risk.Title))
             comment.WriteString(fmt.Sprintf("- **File**: `%s:%d`\n", risk.File, risk.Line))
             comment.WriteString(fmt.Sprintf("- **Confidence**: %.0f%%\n", risk.Confidence*100))
+            if scoring := riskScoringSummary(risk); scoring != "" {
+                comment.WriteString(fmt.Sprintf("- **Severity**: %s\n", scoring))
+            }
             comment.WriteString(fmt.Sprintf("- **Impact**: %s\n", risk.Impact))
+            if len(risk.ComplianceViolations) > 0 {
+                comment.WriteString(fmt.Sprintf("- **Compliance**: %s\n", strings.Join(risk.ComplianceViolations, ", ")))
+            }
             comment.WriteString(fmt.Sprintf("- **Description**: %s\n", risk.Description))
             
             // Code snippet
@@ -82,6 +110,9 @@ func createGitHubComment(analysis *AIAnalysisResponse, prNumber int) string {
         for i, risk := range analysis.HighRisks {
             comment.WriteString(fmt.Sprintf("%d. **%s** - `%s:%d` (%.0f%% confidence)\n", 
                 i+1, risk.Title, risk.File, risk.Line, risk.Confidence*100))
+            if scoring := riskScoringSummary(risk); scoring != "" {
+                comment.WriteString(fmt.Sprintf("   - %s\n", scoring))
+            }
             comment.WriteString(fmt.Sprintf("   - %s\n", risk.Description))
         }
         comment.WriteString("\n")
@@ -113,6 +144,9 @@ func createGitHubComment(analysis *AIAnalysisResponse, prNumber int) string {
     if analysis.Compliance != nil {
         comment.WriteString("### 📋 Compliance Report\n")
         comment.WriteString(strings.Join(analysis.Compliance.Standards, ", ") + "\n\n")
+        if violations := complianceViolations(analysis); len(violations) > 0 {
+            comment.WriteString("**Violations found**: " + strings.Join(violations, ", ") + "\n\n")
+        }
     }
     
     // Footer
@@ -180,4 +214,73 @@ func postCommentToGitHub(repo string, prNumber int, commentBody string) error {
     
     fmt.Printf("✅ Comment posted to GitHub PR #%d\n", prNumber)
     return nil
-}
\ No newline at end of file
+}
+
+// Commit status context shown in the PR checks list
+const policyStatusContext = "aegis-ai/policy"
+
+// GitHub rejects status descriptions longer than this
+const maxStatusDescription = 140
+
+// CommitStatusPoster reports the .aegis.yml fail_on verdict as a commit status,
+// so branch protection can require it before merging
+type CommitStatusPoster struct {
+    Token   string
+    BaseURL string // GitHub API root, e.g. https://api.github.com
+    Client  *http.Client
+}
+
+// Status poster for webhook scans; nil leaves the verdict in the PR comment only
+var commitStatusPoster *CommitStatusPoster
+
+// SetCommitStatusPoster sets where policy verdicts are posted
+func SetCommitStatusPoster(poster *CommitStatusPoster) {
+    commitStatusPoster = poster
+}
+
+// CommitStatusPosterFromEnv reads GITHUB_TOKEN and an optional GITHUB_API_URL.
+// Returns nil when no token is set.
+func CommitStatusPosterFromEnv() *CommitStatusPoster {
+    token := os.Getenv("GITHUB_TOKEN")
+    if token == "" {
+        return nil
+    }
+    baseURL := os.Getenv("GITHUB_API_URL")
+    if baseURL == "" {
+        baseURL = "https://api.github.com"
+    }
+    return &CommitStatusPoster{Token: token, BaseURL: strings.TrimSuffix(baseURL, "/"), Client: &http.Client{}}
+}
+
+// PostPolicyStatus sets a failure or success status for the gate on a commit of repo (owner/name)
+func (p *CommitStatusPoster) PostPolicyStatus(ctx context.Context, repo string, sha string, gate *PolicyGate) error {
+    state, description := "success", "Within the fail_on thresholds in "+repoConfigFile
+    if gate.Failed {
+        state, description = "failure", strings.Join(gate.Reasons, "; ")
+    }
+    if len(description) > maxStatusDescription {
+        description = description[:maxStatusDescription-3] + "..."
+    }
+
+    jsonData, _ := json.Marshal(map[string]string{"state": state, "description": description, "context": policyStatusContext})
+    url := fmt.Sprintf("%s/repos/%s/statuses/%s", p.BaseURL, repo, sha)
+    req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Authorization", "Bearer "+p.Token)
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+    resp, err := p.Client.Do(req)
+    if err != nil {
+        return fmt.Errorf("GitHub API call failed: %v", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusCreated {
+        return fmt.Errorf("GitHub API error: %s", resp.Status)
+    }
+
+    fmt.Printf("🚦 Policy status %s posted to %s@%s\n", state, repo, sha)
+    return nil
+}
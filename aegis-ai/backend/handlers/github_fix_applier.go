@@ -114,11 +114,21 @@ func (g *GitHubFixApplier) applyFixToFile(tempDir, filePath string, lineNumber i
     
     lines := strings.Split(string(content), "\n")
     
+    // Never overwrite a line blindly - the original code must still be in the file
+    if strings.TrimSpace(fix.Original) != "" {
+        located, _ := locateSnippet(lines, fix.Original, lineNumber)
+        if located == 0 {
+            return fmt.Errorf("original code for %q not found in %s, refusing to apply fix", fix.RiskTitle, filePath)
+        }
+        lineNumber = located
+    }
+    
     // Apply the fix (simple line replacement for demo)
     // In production, you'd use more sophisticated code modification
-    if lineNumber > 0 && lineNumber <= len(lines) {
-        lines[lineNumber-1] = fix.Fixed
+    if lineNumber < 1 || lineNumber > len(lines) {
+        return fmt.Errorf("line %d is outside %s (%d lines)", lineNumber, filePath, len(lines))
     }
+    lines[lineNumber-1] = fix.Fixed
     
     // Write modified content back
     modifiedContent := strings.Join(lines, "\n")
@@ -174,7 +184,7 @@ func (g *GitHubFixApplier) createPullRequest(fix AutoFix) (string, error) {
 }
 
 func extractRepoName(repoURL string) string {
-    parts := strings.Split(repoURL, "/")
+    parts := strings.Split(strings.TrimSuffix(repoURL, ".git"), "/")
     if len(parts) >= 2 {
         return fmt.Sprintf("%s/%s", parts[len(parts)-2], parts[len(parts)-1])
     }
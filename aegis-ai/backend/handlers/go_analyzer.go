@@ -0,0 +1,666 @@
+package handlers
+
+import (
+    "fmt"
+    "go/ast"
+    "go/constant"
+    "go/importer"
+    "go/parser"
+    "go/token"
+    "go/types"
+    "path"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "unicode"
+)
+
+// Go analyzer rule IDs
+const (
+    goSQLConcat        = "go-sql-string-concat"
+    goShellInjection   = "go-exec-shell-injection"
+    goCommandArguments = "go-exec-tainted-arguments"
+    goInsecureTLS      = "go-tls-insecure-skip-verify"
+    goWeakRandom       = "go-math-rand-secret"
+    goUncheckedError   = "go-unchecked-security-error"
+)
+
+var goRules = map[string]staticRule{
+    goSQLConcat: {
+        Title:       "SQL Query Built with String Concatenation",
+        Description: "A database/sql query is built from strings at runtime instead of using placeholders.",
+        Impact:      "Any caller-controlled part of the query can change its meaning (SQL injection). Pass values as query arguments with ? / $1 placeholders.",
+        Tier:        TierCritical,
+        CVSS:        9.8,
+        Compliance:  []string{"OWASP A03:2021 Injection", "PCI-DSS Requirement 6.5.1"},
+    },
+    goShellInjection: {
+        Title:       "Command Injection via exec.Command",
+        Description: "The program run by exec.Command, or the script passed to a shell, comes from function input.",
+        Impact:      "Whoever controls the input runs arbitrary commands with the service's privileges.",
+        Tier:        TierCritical,
+        CVSS:        9.8,
+        Compliance:  []string{"OWASP A03:2021 Injection", "PCI-DSS Requirement 6.5.1"},
+    },
+    goCommandArguments: {
+        Title:       "Untrusted Arguments Passed to exec.Command",
+        Description: "exec.Command receives arguments that come from function input.",
+        Impact:      "Input starting with \"-\" is parsed as an option (argument injection, e.g. git --upload-pack). Validate the value and separate it with \"--\".",
+        Tier:        TierHigh,
+        CVSS:        8.1,
+        Compliance:  []string{"OWASP A03:2021 Injection"},
+    },
+    goInsecureTLS: {
+        Title:       "TLS Certificate Verification Disabled",
+        Description: "tls.Config sets InsecureSkipVerify: true.",
+        Impact:      "Connections accept any certificate, so anyone on the network path can intercept and modify the traffic.",
+        Tier:        TierHigh,
+        CVSS:        7.4,
+        Compliance:  []string{"OWASP A02:2021 Cryptographic Failures", "PCI-DSS Requirement 4.1"},
+    },
+    goWeakRandom: {
+        Title:       "Predictable Random Numbers for a Secret",
+        Description: "math/rand is used to generate a security-sensitive value.",
+        Impact:      "math/rand is not cryptographically secure - its output can be predicted and tokens or passwords guessed. Use crypto/rand.",
+        Tier:        TierHigh,
+        CVSS:        7.5,
+        Compliance:  []string{"OWASP A02:2021 Cryptographic Failures"},
+    },
+    goUncheckedError: {
+        Title:       "Unchecked Error from Security-Sensitive Call",
+        Description: "The error returned by a security-sensitive call is discarded.",
+        Impact:      "When the call fails the code carries on as if it succeeded - e.g. a failed password or signature check is treated as a match, or a zeroed buffer is used as key material.",
+        Tier:        TierHigh,
+        CVSS:        7.4,
+        Compliance:  []string{"OWASP A04:2021 Insecure Design"},
+    },
+}
+
+// sqlQueryMethods maps database/sql methods to the index of their query argument
+var sqlQueryMethods = map[string]int{
+    "Query": 0, "QueryContext": 1, "QueryRow": 0, "QueryRowContext": 1,
+    "Exec": 0, "ExecContext": 1, "Prepare": 0, "PrepareContext": 1,
+}
+
+var sqlKeywordPattern = regexp.MustCompile(`(?i)\b(select|insert|update|delete|from|where|values)\b`)
+
+// shellCommands run their -c argument as a script
+var shellCommands = map[string]bool{"sh": true, "bash": true, "zsh": true, "/bin/sh": true, "/bin/bash": true, "cmd": true, "cmd.exe": true, "powershell": true, "pwsh": true}
+
+// securityCalls return an error that must be checked, keyed by package path
+// and function, or package path, receiver type and method
+var securityCalls = map[string]bool{
+    "crypto/rand.Read":                                  true,
+    "crypto/rand.Int":                                   true,
+    "crypto/rand.Prime":                                 true,
+    "crypto/rsa.VerifyPKCS1v15":                         true,
+    "crypto/rsa.VerifyPSS":                              true,
+    "crypto/tls.Conn.Handshake":                         true,
+    "crypto/tls.Conn.HandshakeContext":                  true,
+    "crypto/x509.Certificate.Verify":                    true,
+    "crypto/x509.Certificate.VerifyHostname":            true,
+    "golang.org/x/crypto/bcrypt.CompareHashAndPassword": true,
+    "golang.org/x/crypto/bcrypt.GenerateFromPassword":   true,
+    "github.com/golang-jwt/jwt.Parse":                   true,
+    "github.com/golang-jwt/jwt.ParseWithClaims":         true,
+    "syscall.Setuid":                                    true,
+    "syscall.Setgid":                                    true,
+    "syscall.Setgroups":                                 true,
+    "os.Chmod":                                          true,
+}
+
+// secretWords mark identifiers that hold security-sensitive values
+var secretWords = map[string]bool{
+    "token": true, "secret": true, "password": true, "passwd": true, "pwd": true, "nonce": true,
+    "salt": true, "session": true, "otp": true, "csrf": true, "apikey": true, "key": true, "credential": true,
+}
+
+// goImporter type-checks standard library imports from GOROOT source and
+// stands in empty packages for everything else, so the analysis never needs
+// the scanned repository's dependencies. Packages are shared across analyses.
+type goImporter struct {
+    mu       sync.Mutex
+    source   types.Importer
+    packages map[string]*types.Package
+}
+
+var sharedGoImporter = &goImporter{
+    source:   importer.ForCompiler(token.NewFileSet(), "source", nil),
+    packages: make(map[string]*types.Package),
+}
+
+func (imp *goImporter) Import(importPath string) (*types.Package, error) {
+    imp.mu.Lock()
+    defer imp.mu.Unlock()
+    if pkg, ok := imp.packages[importPath]; ok {
+        return pkg, nil
+    }
+
+    var pkg *types.Package
+    if !strings.Contains(strings.SplitN(importPath, "/", 2)[0], ".") && importPath != "C" {
+        loaded, err := imp.source.Import(importPath)
+        if err != nil {
+            fmt.Printf("⚠️ Go analyzer: no source for %s, continuing without its types: %v\n", importPath, err)
+        } else {
+            pkg = loaded
+        }
+    }
+    if pkg == nil {
+        pkg = types.NewPackage(importPath, guessPackageName(importPath))
+        pkg.MarkComplete()
+    }
+    imp.packages[importPath] = pkg
+    return pkg, nil
+}
+
+// guessPackageName derives a package name from its import path, skipping
+// major version suffixes (jwt/v5) and go- prefixes / .vN suffixes (yaml.v3)
+func guessPackageName(importPath string) string {
+    importPath = trimMajorVersion(importPath)
+    name := path.Base(importPath)
+    name = strings.TrimPrefix(name, "go-")
+    if index := strings.Index(name, "."); index > 0 {
+        name = name[:index]
+    }
+    return strings.ReplaceAll(name, "-", "")
+}
+
+var majorVersionSuffix = regexp.MustCompile(`/v[0-9]+$`)
+
+// trimMajorVersion strips a /vN module suffix
+func trimMajorVersion(importPath string) string {
+    return majorVersionSuffix.ReplaceAllString(importPath, "")
+}
+
+// analyzeGoCode type-checks the Go files of the codebase package by package
+// and runs the AST rules over them, plus handler taint tracking when taint is
+// set. Test files are skipped.
+func analyzeGoCode(codebase map[string]string, taint bool) []ruleFinding {
+    fset := token.NewFileSet()
+    packages := make(map[string][]*ast.File)
+    for filePath, content := range codebase {
+        if !strings.HasSuffix(filePath, ".go") || strings.HasSuffix(filePath, "_test.go") {
+            continue
+        }
+        file, err := parser.ParseFile(fset, filePath, content, parser.ParseComments|parser.SkipObjectResolution)
+        if file == nil {
+            fmt.Printf("⚠️ Go analyzer: cannot parse %s: %v\n", filePath, err)
+            continue
+        }
+        key := path.Dir(filePath) + " " + file.Name.Name
+        packages[key] = append(packages[key], file)
+    }
+
+    keys := make([]string, 0, len(packages))
+    for key := range packages {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    var findings []ruleFinding
+    for _, key := range keys {
+        files := packages[key]
+        sort.Slice(files, func(i, j int) bool { return fset.File(files[i].Pos()).Name() < fset.File(files[j].Pos()).Name() })
+
+        info := &types.Info{
+            Types:      make(map[ast.Expr]types.TypeAndValue),
+            Defs:       make(map[*ast.Ident]types.Object),
+            Uses:       make(map[*ast.Ident]types.Object),
+            Selections: make(map[*ast.SelectorExpr]*types.Selection),
+        }
+        // Missing dependencies surface as type errors - keep going regardless
+        config := types.Config{Importer: sharedGoImporter, FakeImportC: true, Error: func(error) {}}
+        config.Check(strings.SplitN(key, " ", 2)[0], fset, files, info)
+
+        analyzer := &goAnalyzer{fset: fset, info: info, codebase: codebase, taint: taint}
+        for _, file := range files {
+            findings = append(findings, analyzer.analyzeFile(file)...)
+        }
+    }
+    return findings
+}
+
+type goAnalyzer struct {
+    fset     *token.FileSet
+    info     *types.Info
+    codebase map[string]string
+    taint    bool // follow request input through handlers
+    findings []ruleFinding
+}
+
+// goFunction is the per-function state the rules consult: its parameters
+// and every expression assigned to each local variable
+type goFunction struct {
+    name    string
+    params  map[types.Object]bool
+    assigns map[types.Object][]ast.Expr
+}
+
+func (analyzer *goAnalyzer) analyzeFile(file *ast.File) []ruleFinding {
+    analyzer.findings = nil
+    for _, decl := range file.Decls {
+        function, ok := decl.(*ast.FuncDecl)
+        if !ok || function.Body == nil {
+            continue
+        }
+        analyzer.analyzeFunction(function)
+    }
+    return dropSupersededFindings(analyzer.findings)
+}
+
+func (analyzer *goAnalyzer) report(node ast.Node, ruleID string, detail string) {
+    position := analyzer.fset.Position(node.Pos())
+    analyzer.findings = append(analyzer.findings, newStaticFinding(position.Filename, analyzer.codebase[position.Filename], position.Line, ruleID, goRules[ruleID], detail))
+}
+
+func (analyzer *goAnalyzer) object(ident *ast.Ident) types.Object {
+    if object := analyzer.info.Uses[ident]; object != nil {
+        return object
+    }
+    return analyzer.info.Defs[ident]
+}
+
+// collectFunction records parameters (including those of closures) and assignments
+func (analyzer *goAnalyzer) collectFunction(function *ast.FuncDecl) *goFunction {
+    fn := &goFunction{name: function.Name.Name, params: make(map[types.Object]bool), assigns: make(map[types.Object][]ast.Expr)}
+    addParams := func(fields *ast.FieldList) {
+        if fields == nil {
+            return
+        }
+        for _, field := range fields.List {
+            for _, name := range field.Names {
+                if object := analyzer.object(name); object != nil {
+                    fn.params[object] = true
+                }
+            }
+        }
+    }
+    assign := func(lhs []ast.Expr, rhs []ast.Expr) {
+        for index, target := range lhs {
+            ident, ok := target.(*ast.Ident)
+            if !ok || ident.Name == "_" {
+                continue
+            }
+            object := analyzer.object(ident)
+            if object == nil {
+                continue
+            }
+            value := rhs[0]
+            if len(rhs) == len(lhs) {
+                value = rhs[index]
+            }
+            fn.assigns[object] = append(fn.assigns[object], value)
+        }
+    }
+
+    addParams(function.Type.Params)
+    ast.Inspect(function.Body, func(node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.FuncLit:
+            addParams(node.Type.Params)
+        case *ast.AssignStmt:
+            assign(node.Lhs, node.Rhs)
+        case *ast.ValueSpec:
+            if len(node.Values) > 0 {
+                lhs := make([]ast.Expr, len(node.Names))
+                for index, name := range node.Names {
+                    lhs[index] = name
+                }
+                assign(lhs, node.Values)
+            }
+        case *ast.RangeStmt:
+            for _, target := range []ast.Expr{node.Key, node.Value} {
+                if target != nil {
+                    assign([]ast.Expr{target}, []ast.Expr{node.X})
+                }
+            }
+        }
+        return true
+    })
+    return fn
+}
+
+func (analyzer *goAnalyzer) analyzeFunction(function *ast.FuncDecl) {
+    fn := analyzer.collectFunction(function)
+    reportedRandom := false
+    if request, context := analyzer.handlerParams(function.Type.Params); analyzer.taint && (request != nil || context != nil) {
+        analyzer.trackTaint(fn, function.Body, request, context)
+    }
+
+    var stack []ast.Node
+    ast.Inspect(function.Body, func(node ast.Node) bool {
+        if node == nil {
+            stack = stack[:len(stack)-1]
+            return true
+        }
+        stack = append(stack, node)
+
+        switch node := node.(type) {
+        case *ast.FuncLit:
+            if request, context := analyzer.handlerParams(node.Type.Params); analyzer.taint && (request != nil || context != nil) {
+                analyzer.trackTaint(fn, node.Body, request, context)
+            }
+        case *ast.CallExpr:
+            analyzer.checkSQL(fn, node)
+            analyzer.checkCommand(fn, node)
+            if !reportedRandom && analyzer.checkRandom(fn, node, stack) {
+                reportedRandom = true
+            }
+        case *ast.KeyValueExpr:
+            if key, ok := node.Key.(*ast.Ident); ok && key.Name == "InsecureSkipVerify" && analyzer.isTrue(node.Value) {
+                analyzer.report(node, goInsecureTLS, "")
+            }
+        case *ast.AssignStmt:
+            for index, target := range node.Lhs {
+                if selector, ok := target.(*ast.SelectorExpr); ok && selector.Sel.Name == "InsecureSkipVerify" &&
+                    len(node.Rhs) == len(node.Lhs) && analyzer.isTrue(node.Rhs[index]) {
+                    analyzer.report(node, goInsecureTLS, "")
+                }
+            }
+            if len(node.Rhs) == 1 && len(node.Lhs) > 0 {
+                if ident, ok := node.Lhs[len(node.Lhs)-1].(*ast.Ident); ok && ident.Name == "_" {
+                    analyzer.checkUnchecked(node.Rhs[0], "assigned to _")
+                }
+            }
+        case *ast.ExprStmt:
+            analyzer.checkUnchecked(node.X, "ignored")
+        case *ast.DeferStmt:
+            analyzer.checkUnchecked(node.Call, "ignored by defer")
+        case *ast.GoStmt:
+            analyzer.checkUnchecked(node.Call, "ignored by go")
+        }
+        return true
+    })
+}
+
+// callee names the function a call resolves to as "pkgpath.Func" or
+// "pkgpath.Type.Method", or "" when it cannot be resolved
+func (analyzer *goAnalyzer) callee(call *ast.CallExpr) string {
+    selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+    if !ok {
+        return ""
+    }
+    if ident, ok := selector.X.(*ast.Ident); ok {
+        if pkgName, ok := analyzer.object(ident).(*types.PkgName); ok {
+            return trimMajorVersion(pkgName.Imported().Path()) + "." + selector.Sel.Name
+        }
+    }
+    selection := analyzer.info.Selections[selector]
+    if selection == nil || selection.Obj().Pkg() == nil {
+        return ""
+    }
+    receiver := selection.Recv()
+    if pointer, ok := receiver.(*types.Pointer); ok {
+        receiver = pointer.Elem()
+    }
+    named, ok := receiver.(*types.Named)
+    if !ok {
+        return ""
+    }
+    return selection.Obj().Pkg().Path() + "." + named.Obj().Name() + "." + selector.Sel.Name
+}
+
+func (analyzer *goAnalyzer) isTrue(expr ast.Expr) bool {
+    if value := analyzer.info.Types[expr].Value; value != nil && value.Kind() == constant.Bool {
+        return constant.BoolVal(value)
+    }
+    ident, ok := expr.(*ast.Ident)
+    return ok && ident.Name == "true"
+}
+
+// isConstant reports whether an expression is a compile-time constant
+func (analyzer *goAnalyzer) isConstant(expr ast.Expr) bool {
+    if analyzer.info.Types[expr].Value != nil {
+        return true
+    }
+    switch expr := ast.Unparen(expr).(type) {
+    case *ast.BasicLit:
+        return true
+    case *ast.BinaryExpr:
+        return analyzer.isConstant(expr.X) && analyzer.isConstant(expr.Y)
+    }
+    return false
+}
+
+// dynamicString reports whether a string is assembled at runtime by
+// concatenation or fmt.Sprintf, following local variables to their assignments
+func (analyzer *goAnalyzer) dynamicString(fn *goFunction, expr ast.Expr, seen map[types.Object]bool) bool {
+    switch expr := ast.Unparen(expr).(type) {
+    case *ast.BinaryExpr:
+        return expr.Op == token.ADD && !analyzer.isConstant(expr)
+    case *ast.CallExpr:
+        if callee := analyzer.callee(expr); callee == "fmt.Sprintf" || callee == "strings.Join" || callee == "strings.ReplaceAll" || callee == "strings.Replace" {
+            for _, argument := range expr.Args[1:] {
+                if !analyzer.isConstant(argument) {
+                    return true
+                }
+            }
+        }
+    case *ast.Ident:
+        object := analyzer.object(expr)
+        if object == nil || seen[object] {
+            return false
+        }
+        seen[object] = true
+        for _, value := range fn.assigns[object] {
+            if analyzer.dynamicString(fn, value, seen) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// mentionsSQL reports whether any string literal in the expression (or the
+// local variables it uses) looks like SQL
+func (analyzer *goAnalyzer) mentionsSQL(fn *goFunction, expr ast.Expr, seen map[types.Object]bool) bool {
+    found := false
+    ast.Inspect(expr, func(node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.BasicLit:
+            found = found || (node.Kind == token.STRING && sqlKeywordPattern.MatchString(node.Value))
+        case *ast.Ident:
+            if object := analyzer.object(node); object != nil && !seen[object] {
+                seen[object] = true
+                for _, value := range fn.assigns[object] {
+                    found = found || analyzer.mentionsSQL(fn, value, seen)
+                }
+            }
+        }
+        return !found
+    })
+    return found
+}
+
+// sqlQuery returns the query argument of a database/sql call, or nil
+func (analyzer *goAnalyzer) sqlQuery(fn *goFunction, call *ast.CallExpr) ast.Expr {
+    selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+    if !ok {
+        return nil
+    }
+    index, ok := sqlQueryMethods[selector.Sel.Name]
+    if !ok || len(call.Args) <= index {
+        return nil
+    }
+    // With database/sql types, trust them; without (wrapped drivers, missing
+    // sources) fall back to the query text looking like SQL
+    query := call.Args[index]
+    if selection := analyzer.info.Selections[selector]; selection != nil && selection.Obj().Pkg() != nil {
+        if selection.Obj().Pkg().Path() != "database/sql" {
+            return nil
+        }
+    } else if !analyzer.mentionsSQL(fn, query, make(map[types.Object]bool)) {
+        return nil
+    }
+    return query
+}
+
+func (analyzer *goAnalyzer) checkSQL(fn *goFunction, call *ast.CallExpr) {
+    if query := analyzer.sqlQuery(fn, call); query != nil && analyzer.dynamicString(fn, query, make(map[types.Object]bool)) {
+        method := ast.Unparen(call.Fun).(*ast.SelectorExpr).Sel.Name
+        analyzer.report(call, goSQLConcat, fmt.Sprintf("The query passed to %s is concatenated at runtime.", method))
+    }
+}
+
+// fromInput reports whether an expression derives from a parameter of the
+// enclosing function (or a closure in it) or from os.Args
+func (analyzer *goAnalyzer) fromInput(fn *goFunction, expr ast.Expr, seen map[types.Object]bool) bool {
+    found := false
+    ast.Inspect(expr, func(node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.SelectorExpr:
+            if ident, ok := node.X.(*ast.Ident); ok && node.Sel.Name == "Args" {
+                if pkgName, ok := analyzer.object(ident).(*types.PkgName); ok && pkgName.Imported().Path() == "os" {
+                    found = true
+                }
+            }
+        case *ast.Ident:
+            object := analyzer.object(node)
+            if object == nil || seen[object] {
+                break
+            }
+            seen[object] = true
+            if fn.params[object] {
+                found = true
+            }
+            for _, value := range fn.assigns[object] {
+                found = found || analyzer.fromInput(fn, value, seen)
+            }
+        }
+        return !found
+    })
+    return found
+}
+
+func (analyzer *goAnalyzer) checkCommand(fn *goFunction, call *ast.CallExpr) {
+    callee := analyzer.callee(call)
+    arguments := call.Args
+    switch callee {
+    case "os/exec.Command":
+    case "os/exec.CommandContext":
+        if len(arguments) == 0 {
+            return
+        }
+        arguments = arguments[1:]
+    default:
+        return
+    }
+    if len(arguments) == 0 {
+        return
+    }
+
+    program := ""
+    if literal, ok := arguments[0].(*ast.BasicLit); ok {
+        program = strings.Trim(literal.Value, "\"`")
+    }
+    for index, argument := range arguments {
+        if analyzer.isConstant(argument) || !analyzer.fromInput(fn, argument, make(map[types.Object]bool)) {
+            continue
+        }
+        if index == 0 || shellCommands[program] {
+            analyzer.report(call, goShellInjection, fmt.Sprintf("Argument %d (%s) comes from function input.", index+1, analyzer.source(argument)))
+        } else {
+            analyzer.report(call, goCommandArguments, fmt.Sprintf("Argument %d (%s) comes from function input.", index+1, analyzer.source(argument)))
+        }
+        return
+    }
+}
+
+// source returns the source text of a node
+func (analyzer *goAnalyzer) source(node ast.Node) string {
+    start, end := analyzer.fset.Position(node.Pos()), analyzer.fset.Position(node.End())
+    content := analyzer.codebase[start.Filename]
+    if start.Offset < 0 || end.Offset > len(content) || start.Offset > end.Offset {
+        return ""
+    }
+    return content[start.Offset:end.Offset]
+}
+
+// checkRandom flags math/rand calls whose result feeds a security-sensitive
+// value, judged by the names of the enclosing function and assignment
+func (analyzer *goAnalyzer) checkRandom(fn *goFunction, call *ast.CallExpr, stack []ast.Node) bool {
+    callee := analyzer.callee(call)
+    if !strings.HasPrefix(callee, "math/rand.") {
+        return false
+    }
+
+    names := []string{fn.name}
+    for index := len(stack) - 1; index >= 0; index-- {
+        switch node := stack[index].(type) {
+        case *ast.AssignStmt:
+            for _, target := range node.Lhs {
+                names = append(names, analyzer.source(target))
+            }
+        case *ast.ValueSpec:
+            for _, name := range node.Names {
+                names = append(names, name.Name)
+            }
+        case *ast.KeyValueExpr:
+            names = append(names, analyzer.source(node.Key))
+        case *ast.FuncLit:
+            // A closure's own names are as far as the value can be traced
+            index = 0
+        }
+    }
+    for _, name := range names {
+        if isSecretName(name) {
+            analyzer.report(call, goWeakRandom, fmt.Sprintf("%s generates %q.", strings.TrimPrefix(callee, "math/"), name))
+            return true
+        }
+    }
+    return false
+}
+
+// isSecretName splits an identifier into camelCase / snake_case words and
+// checks them against secretWords
+func isSecretName(name string) bool {
+    var words []string
+    var word []rune
+    runes := []rune(name)
+    for index, char := range runes {
+        boundary := !unicode.IsLetter(char) && !unicode.IsDigit(char)
+        upper := unicode.IsUpper(char) && index > 0 && (unicode.IsLower(runes[index-1]) ||
+            (index+1 < len(runes) && unicode.IsLower(runes[index+1])))
+        if (boundary || upper) && len(word) > 0 {
+            words = append(words, strings.ToLower(string(word)))
+            word = nil
+        }
+        if !boundary {
+            word = append(word, char)
+        }
+    }
+    if len(word) > 0 {
+        words = append(words, strings.ToLower(string(word)))
+    }
+
+    for index, word := range words {
+        if secretWords[word] || (word == "api" && index+1 < len(words) && words[index+1] == "key") {
+            return true
+        }
+    }
+    return false
+}
+
+// checkUnchecked flags a security-sensitive call whose error is discarded
+func (analyzer *goAnalyzer) checkUnchecked(expr ast.Expr, how string) {
+    call, ok := ast.Unparen(expr).(*ast.CallExpr)
+    if !ok {
+        return
+    }
+    callee := analyzer.callee(call)
+    sensitive := securityCalls[callee]
+    // io.ReadFull(rand.Reader, key) is the usual way to fill key material
+    if callee == "io.ReadFull" && len(call.Args) > 0 {
+        if selector, ok := call.Args[0].(*ast.SelectorExpr); ok && selector.Sel.Name == "Reader" {
+            if ident, ok := selector.X.(*ast.Ident); ok {
+                pkgName, ok := analyzer.object(ident).(*types.PkgName)
+                sensitive = ok && pkgName.Imported().Path() == "crypto/rand"
+            }
+        }
+    }
+    if sensitive {
+        name := callee[strings.LastIndex(callee, "/")+1:]
+        analyzer.report(call, goUncheckedError, fmt.Sprintf("The error from %s is %s.", name, how))
+    }
+}
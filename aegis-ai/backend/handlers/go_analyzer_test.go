@@ -0,0 +1,213 @@
+package handlers
+
+import (
+    "fmt"
+    "sort"
+    "testing"
+)
+
+var goAnalyzerSources = map[string]string{
+    "store/users.go": `package store
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+)
+
+type Store struct {
+    db *sql.DB
+}
+
+func (s *Store) FindUser(name string) (*sql.Rows, error) {
+    return s.db.Query("SELECT * FROM users WHERE name = '" + name + "'")
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id string) error {
+    query := fmt.Sprintf("DELETE FROM users WHERE id = %s", id)
+    _, err := s.db.ExecContext(ctx, query)
+    return err
+}
+
+func (s *Store) CountUsers(table string) *sql.Row {
+    query := "SELECT count(*) FROM users"
+    query += " WHERE team = " + table
+    return s.db.QueryRow(query)
+}
+
+func (s *Store) SafeUser(name string) (*sql.Rows, error) {
+    const base = "SELECT * FROM users"
+    return s.db.Query(base+" WHERE name = ?", name)
+}
+
+type Cache struct{}
+
+func (Cache) Query(key string) string { return key }
+
+func (s *Store) Cached(c Cache, name string) string {
+    return c.Query("user:" + name)
+}
+`,
+    "store/orm.go": `package store
+
+import "example.com/orm"
+
+func Legacy(conn *orm.Conn, id string) {
+    conn.Exec("UPDATE accounts SET active = 0 WHERE id = " + id)
+}
+`,
+    "cmd/run.go": `package main
+
+import (
+    "context"
+    "os"
+    "os/exec"
+)
+
+func Run(ctx context.Context, script string, repo string) {
+    exec.Command("sh", "-c", script).Run()
+    exec.CommandContext(ctx, "git", "clone", repo).Run()
+    exec.Command("git", "status").Run()
+    tool := os.Args[1]
+    exec.Command(tool).Run()
+}
+
+func main() {}
+`,
+    "net/client.go": `package net
+
+import (
+    "crypto/tls"
+    "net/http"
+)
+
+func Client(insecure bool) *http.Client {
+    config := &tls.Config{InsecureSkipVerify: true}
+    other := &tls.Config{}
+    other.InsecureSkipVerify = true
+    checked := &tls.Config{InsecureSkipVerify: insecure}
+    _, _ = other, checked
+    return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+`,
+    "auth/tokens.go": `package auth
+
+import (
+    "crypto/rand"
+    "io"
+    mrand "math/rand"
+
+    "golang.org/x/crypto/bcrypt"
+    jwt "github.com/golang-jwt/jwt/v5"
+)
+
+func GenerateToken() string {
+    letters := "abcdef0123456789"
+    out := make([]byte, 32)
+    for i := range out {
+        out[i] = letters[mrand.Intn(len(letters))]
+        out[i] ^= byte(mrand.Intn(2))
+    }
+    return string(out)
+}
+
+func Shuffle(items []string) {
+    mrand.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+    resetToken := mrand.Int63()
+    _ = resetToken
+}
+
+func Key() []byte {
+    key := make([]byte, 32)
+    rand.Read(key)
+    io.ReadFull(rand.Reader, key)
+    return key
+}
+
+func Check(hash, password []byte) bool {
+    bcrypt.CompareHashAndPassword(hash, password)
+    token, _ := jwt.Parse(string(password), nil)
+    if err := bcrypt.CompareHashAndPassword(hash, password); err != nil {
+        return false
+    }
+    return token != nil
+}
+`,
+    "auth/tokens_test.go": `package auth
+
+import "crypto/tls"
+
+var testConfig = tls.Config{InsecureSkipVerify: true}
+`,
+}
+
+func TestAnalyzeGoCode(t *testing.T) {
+    findings := analyzeGoCode(goAnalyzerSources, true)
+
+    var got []string
+    for _, finding := range findings {
+        risk := finding.Risk
+        if risk.Source != RiskSourceRule || risk.CodeSnippet == "" {
+            t.Errorf("Expected a rule finding with a snippet, got %+v", risk)
+        }
+        got = append(got, fmt.Sprintf("%s:%d %s", risk.FilePath, risk.LineNumber, risk.RuleID))
+    }
+    sort.Strings(got)
+
+    expected := []string{
+        "auth/tokens.go:16 go-math-rand-secret",
+        "auth/tokens.go:24 go-math-rand-secret",
+        "auth/tokens.go:30 go-unchecked-security-error",
+        "auth/tokens.go:31 go-unchecked-security-error",
+        "auth/tokens.go:36 go-unchecked-security-error",
+        "auth/tokens.go:37 go-unchecked-security-error",
+        "cmd/run.go:10 go-exec-shell-injection",
+        "cmd/run.go:11 go-exec-tainted-arguments",
+        "cmd/run.go:14 go-exec-shell-injection",
+        "net/client.go:9 go-tls-insecure-skip-verify",
+        "net/client.go:11 go-tls-insecure-skip-verify",
+        "store/orm.go:6 go-sql-string-concat",
+        "store/users.go:14 go-sql-string-concat",
+        "store/users.go:19 go-sql-string-concat",
+        "store/users.go:26 go-sql-string-concat",
+    }
+    sort.Strings(expected)
+    if fmt.Sprint(got) != fmt.Sprint(expected) {
+        t.Errorf("Unexpected Go analyzer findings:\n got      %v\n expected %v", got, expected)
+    }
+}
+
+func TestIsSecretName(t *testing.T) {
+    cases := map[string]bool{
+        "GenerateToken": true,
+        "resetToken":    true,
+        "apiKey":        true,
+        "API_KEY":       true,
+        "sessionID":     true,
+        "CSRFToken":     true,
+        "footprint":     false,
+        "keyboard":      false,
+        "Shuffle":       false,
+        "items[i]":      false,
+    }
+    for name, expected := range cases {
+        if isSecretName(name) != expected {
+            t.Errorf("isSecretName(%q) = %v, expected %v", name, !expected, expected)
+        }
+    }
+}
+
+func TestGuessPackageName(t *testing.T) {
+    cases := map[string]string{
+        "github.com/golang-jwt/jwt/v5": "jwt",
+        "gopkg.in/yaml.v3":             "yaml",
+        "github.com/go-redis/redis":    "redis",
+        "github.com/gin-gonic/gin":     "gin",
+        "example.com/my-lib":           "mylib",
+    }
+    for importPath, expected := range cases {
+        if name := guessPackageName(importPath); name != expected {
+            t.Errorf("guessPackageName(%q) = %q, expected %q", importPath, name, expected)
+        }
+    }
+}
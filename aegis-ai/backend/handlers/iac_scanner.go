@@ -0,0 +1,266 @@
+package handlers
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// IaC rule IDs
+const (
+    iacRunAsRoot     = "iac-run-as-root"
+    iacLatestTag     = "iac-latest-tag"
+    iacPrivileged    = "iac-privileged-container"
+    iacHostPath      = "iac-host-path-mount"
+    iacNoLimits      = "iac-missing-resource-limits"
+    iacPublicBucket  = "iac-public-bucket"
+    iacOpenAdminPort = "iac-open-admin-port"
+    iacOpenIngress   = "iac-open-security-group"
+)
+
+var iacRules = map[string]staticRule{
+    iacRunAsRoot: {
+        Title:       "Container Runs as Root",
+        Description: "The container runs as the root user.",
+        Impact:      "Any code execution bug in the application gives root inside the container, which makes escaping to the host far easier. Run as a dedicated non-root user.",
+        Tier:        TierMedium,
+        CVSS:        6.3,
+        Compliance:  []string{"CIS Docker Benchmark 4.1", "CIS Kubernetes Benchmark 5.2.6"},
+    },
+    iacLatestTag: {
+        Title:       "Unpinned Container Image Tag",
+        Description: "The image uses the mutable \"latest\" tag (or no tag).",
+        Impact:      "Builds and deployments silently pick up whatever the tag points to, including compromised or breaking images. Pin a version tag or digest.",
+        Tier:        TierMedium,
+        CVSS:        4.3,
+        Compliance:  []string{"CIS Docker Benchmark 5.27", "SOC2 CC8.1"},
+    },
+    iacPrivileged: {
+        Title:       "Privileged Container",
+        Description: "The container runs in privileged mode with all capabilities and device access.",
+        Impact:      "A privileged container is effectively root on the host - a compromise of the container compromises the node.",
+        Tier:        TierHigh,
+        CVSS:        8.8,
+        Compliance:  []string{"CIS Docker Benchmark 5.4", "CIS Kubernetes Benchmark 5.2.1"},
+    },
+    iacHostPath: {
+        Title:       "Host Filesystem Mounted into Container",
+        Description: "A host path is mounted into the container.",
+        Impact:      "Host mounts expose node files (and with the Docker socket, full control of the host) to anything running in the container.",
+        Tier:        TierHigh,
+        CVSS:        8.1,
+        Compliance:  []string{"CIS Docker Benchmark 5.5", "CIS Kubernetes Benchmark 5.2.12"},
+    },
+    iacNoLimits: {
+        Title:       "Missing Container Resource Limits",
+        Description: "The container has no memory/CPU limits.",
+        Impact:      "A runaway or attacked container can exhaust the node's memory and CPU and take down neighbouring workloads.",
+        Tier:        TierMedium,
+        CVSS:        5.3,
+        Compliance:  []string{"CIS Docker Benchmark 5.10", "SOC2 A1.1"},
+    },
+    iacPublicBucket: {
+        Title:       "Publicly Accessible Storage Bucket",
+        Description: "The storage bucket is readable (or writable) by anyone on the internet.",
+        Impact:      "Public buckets are a leading cause of data breaches - every object in them can be listed and downloaded without credentials.",
+        Tier:        TierCritical,
+        CVSS:        9.1,
+        Compliance:  []string{"CIS AWS Foundations 2.1.5", "PCI-DSS Requirement 1.3", "GDPR Article 32"},
+    },
+    iacOpenAdminPort: {
+        Title:       "Administrative Port Open to the Internet",
+        Description: "A security group allows inbound traffic from anywhere (0.0.0.0/0) to an administrative or database port.",
+        Impact:      "SSH, RDP and database ports reachable from the internet are scanned and brute-forced within minutes of exposure.",
+        Tier:        TierCritical,
+        CVSS:        9.0,
+        Compliance:  []string{"CIS AWS Foundations 5.2", "PCI-DSS Requirement 1.2"},
+    },
+    iacOpenIngress: {
+        Title:       "Security Group Open to the Internet",
+        Description: "A security group allows inbound traffic from anywhere (0.0.0.0/0).",
+        Impact:      "Services reachable from the whole internet widen the attack surface. Restrict the source range to known networks or a load balancer.",
+        Tier:        TierMedium,
+        CVSS:        5.8,
+        Compliance:  []string{"CIS AWS Foundations 5.2", "PCI-DSS Requirement 1.2"},
+    },
+}
+
+// Infrastructure file kinds
+const (
+    iacDockerfile = "dockerfile"
+    iacCompose    = "compose"
+    iacKubernetes = "kubernetes"
+    iacTerraform  = "terraform"
+)
+
+// iacFileKind tells which IaC parser handles a file, or "" for none
+func iacFileKind(path string, content string) string {
+    base := strings.ToLower(filepath.Base(path))
+    switch {
+    case base == "dockerfile" || strings.HasPrefix(base, "dockerfile.") || strings.HasSuffix(base, ".dockerfile"):
+        return iacDockerfile
+    case strings.HasSuffix(base, ".tf"):
+        return iacTerraform
+    case !strings.HasSuffix(base, ".yml") && !strings.HasSuffix(base, ".yaml"):
+        return ""
+    case strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose."):
+        return iacCompose
+    case strings.Contains(content, "apiVersion:") && strings.Contains(content, "kind:"):
+        return iacKubernetes
+    }
+    return ""
+}
+
+// scanInfrastructure runs the IaC rules over every Dockerfile, Compose file,
+// Kubernetes manifest and Terraform file in the repository. Files are read
+// from disk because extraction skips variants such as Dockerfile.prod; symlinks
+// leaving the repository are not followed.
+func scanInfrastructure(ctx context.Context, repoPath string) []ruleFinding {
+    var findings []ruleFinding
+    skip := func(relPath string, isDir bool) bool {
+        if isDir {
+            return dependencySkipDirs[filepath.Base(relPath)]
+        }
+        name := strings.ToLower(filepath.Base(relPath))
+        // Any YAML file may be a Kubernetes manifest, so those are read too
+        return iacFileKind(name, "") == "" && !strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml")
+    }
+    // A failed walk still reports the files scanned before it stopped
+    walkRepository(ctx, repoPath, skip, func(entry repoEntry) {
+        content, err := os.ReadFile(entry.RealPath)
+        if err != nil {
+            return
+        }
+        relativePath := entry.Path
+
+        switch text := string(content); iacFileKind(relativePath, text) {
+        case iacDockerfile:
+            findings = append(findings, scanDockerfile(relativePath, text)...)
+        case iacCompose:
+            findings = append(findings, scanCompose(relativePath, text)...)
+        case iacKubernetes:
+            findings = append(findings, scanKubernetes(relativePath, text)...)
+        case iacTerraform:
+            findings = append(findings, scanTerraform(relativePath, text)...)
+        }
+    })
+    return findings
+}
+
+// newIaCFinding builds a located finding; detail names the offending resource
+func newIaCFinding(path string, content string, line int, ruleID string, detail string) ruleFinding {
+    return newStaticFinding(path, content, line, ruleID, iacRules[ruleID], detail)
+}
+
+// imageUnpinned reports whether an image reference floats: no tag, or "latest".
+// Digests and variable references are treated as pinned.
+func imageUnpinned(image string) bool {
+    if image == "" || strings.Contains(image, "@") || strings.Contains(image, "$") {
+        return false
+    }
+    name := image[strings.LastIndex(image, "/")+1:]
+    tag := ""
+    if index := strings.LastIndex(name, ":"); index >= 0 {
+        tag = name[index+1:]
+    }
+    return tag == "" || tag == "latest"
+}
+
+// isRootUser reports whether a USER / user: value is root
+func isRootUser(user string) bool {
+    name := strings.SplitN(strings.TrimSpace(user), ":", 2)[0]
+    return name == "root" || name == "0"
+}
+
+// dockerInstruction is one Dockerfile instruction with continuation lines joined
+type dockerInstruction struct {
+    Command string
+    Args    string
+    Line    int
+}
+
+func parseDockerfile(content string) []dockerInstruction {
+    var instructions []dockerInstruction
+    var current *dockerInstruction
+    for index, line := range strings.Split(content, "\n") {
+        trimmed := strings.TrimSpace(line)
+        if current == nil {
+            if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+                continue
+            }
+            fields := strings.SplitN(trimmed, " ", 2)
+            current = &dockerInstruction{Command: strings.ToUpper(fields[0]), Line: index + 1}
+            if len(fields) > 1 {
+                trimmed = fields[1]
+            } else {
+                trimmed = ""
+            }
+        } else if strings.HasPrefix(trimmed, "#") {
+            continue
+        }
+
+        continued := strings.HasSuffix(trimmed, "\\")
+        current.Args = strings.TrimSpace(current.Args + " " + strings.TrimSuffix(trimmed, "\\"))
+        if !continued {
+            instructions = append(instructions, *current)
+            current = nil
+        }
+    }
+    if current != nil {
+        instructions = append(instructions, *current)
+    }
+    return instructions
+}
+
+// scanDockerfile flags floating base images and a final stage that runs as root
+func scanDockerfile(path string, content string) []ruleFinding {
+    var findings []ruleFinding
+    stageUsers := make(map[string]string)
+    user, userLine, fromLine, stage, base := "", 0, 0, "", ""
+
+    for _, instruction := range parseDockerfile(content) {
+        switch instruction.Command {
+        case "FROM":
+            var args []string
+            for _, field := range strings.Fields(instruction.Args) {
+                if !strings.HasPrefix(field, "--") {
+                    args = append(args, field)
+                }
+            }
+            if len(args) == 0 {
+                continue
+            }
+            if stage != "" {
+                stageUsers[stage] = user
+            }
+            image := args[0]
+            stage, base = "", image
+            if len(args) >= 3 && strings.EqualFold(args[1], "as") {
+                stage = strings.ToLower(args[2])
+            }
+            fromLine, userLine = instruction.Line, 0
+
+            // A later stage built on an earlier one inherits its user
+            inherited, isStage := stageUsers[strings.ToLower(image)]
+            user = inherited
+            if !isStage && image != "scratch" && imageUnpinned(image) {
+                findings = append(findings, newIaCFinding(path, content, instruction.Line, iacLatestTag, "Base image: "+image+"."))
+            }
+        case "USER":
+            user, userLine = instruction.Args, instruction.Line
+        }
+    }
+
+    switch {
+    case fromLine == 0:
+    case user == "":
+        findings = append(findings, newIaCFinding(path, content, fromLine, iacRunAsRoot, "The final stage has no USER instruction."))
+    case isRootUser(user) && userLine == 0:
+        // Inherited from the stage it builds on - point at the final FROM
+        findings = append(findings, newIaCFinding(path, content, fromLine, iacRunAsRoot, "The final stage inherits USER "+user+" from stage "+base+"."))
+    case isRootUser(user):
+        findings = append(findings, newIaCFinding(path, content, userLine, iacRunAsRoot, "The final stage sets USER "+user+"."))
+    }
+    return findings
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "testing"
+)
+
+var infrastructureFiles = map[string]string{
+    "Dockerfile": `# build stage
+FROM golang:1.22 AS build
+RUN go build \
+    -o /app .
+
+FROM build AS test
+RUN go test ./...
+
+FROM --platform=linux/amd64 alpine
+COPY --from=build /app /app
+ENTRYPOINT ["/app"]
+`,
+    "deploy/Dockerfile.prod": `FROM node:latest AS base
+USER node
+
+FROM base
+USER root
+`,
+    "docker/Dockerfile.safe": `FROM gcr.io/distroless/static@sha256:abc123
+USER 65532:65532
+`,
+    "docker-compose.yml": `services:
+  web:
+    image: nginx
+    privileged: true
+    user: "0:0"
+    volumes:
+      - ./site:/usr/share/nginx/html
+      - /var/run/docker.sock:/var/run/docker.sock
+  db:
+    image: postgres:16
+    mem_limit: 512m
+    volumes:
+      - type: bind
+        source: /etc
+        target: /host-etc
+`,
+    "k8s/app.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: api
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+      initContainers:
+        - name: migrate
+          image: api:1.4.0
+      containers:
+        - name: api
+          image: registry.local:5000/api
+          resources:
+            limits:
+              memory: 256Mi
+        - name: agent
+          image: agent:2.0
+          securityContext:
+            privileged: true
+            runAsUser: 0
+      volumes:
+        - name: logs
+          hostPath:
+            path: /var/log
+`,
+    "k8s/cron.yml": `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: report
+              image: report:1.0
+`,
+    "config/settings.yaml": "debug: true\n",
+    "infra/main.tf": `# storage
+resource "aws_s3_bucket" "assets" {
+  bucket = "assets"
+  acl    = "public-read" # served directly
+}
+
+resource "aws_s3_bucket_public_access_block" "assets" {
+  bucket              = aws_s3_bucket.assets.id
+  block_public_acls   = true
+  block_public_policy = false
+}
+
+resource "google_storage_bucket_iam_member" "viewer" {
+  bucket = "reports"
+  member = "allUsers"
+}
+
+/* resource "aws_s3_bucket" "old" {
+  acl = "public-read"
+} */
+
+resource "aws_security_group" "web" {
+  name = "web"
+
+  ingress {
+    from_port   = 443
+    to_port     = 443
+    protocol    = "tcp"
+    cidr_blocks = ["0.0.0.0/0"]
+  }
+
+  ingress {
+    from_port = 22
+    to_port   = 22
+    protocol  = "tcp"
+    cidr_blocks = [
+      "10.0.0.0/8",
+      "0.0.0.0/0",
+    ]
+  }
+
+  ingress {
+    from_port   = 8080
+    to_port     = 8081
+    protocol    = "tcp"
+    cidr_blocks = ["10.0.0.0/8"]
+  }
+
+  tags = {
+    Name = "web"
+  }
+}
+
+resource "aws_security_group_rule" "all" {
+  type             = "ingress"
+  from_port        = 0
+  to_port          = 0
+  protocol         = "-1"
+  ipv6_cidr_blocks = ["::/0"]
+}
+
+resource "aws_vpc_security_group_ingress_rule" "app" {
+  cidr_ipv4   = "0.0.0.0/0"
+  from_port   = 8000
+  to_port     = 8100
+  ip_protocol = "tcp"
+}
+`,
+    "node_modules/pkg/Dockerfile": "FROM node\n",
+}
+
+func TestScanInfrastructure(t *testing.T) {
+    repoPath := writeRepo(t, infrastructureFiles)
+    // Host files behind a symlink must not end up in finding snippets
+    host := writeRepo(t, map[string]string{"Dockerfile": "FROM host:latest\n", "etc/kube.yaml": "apiVersion: v1\nkind: Pod\n"})
+    os.Symlink(filepath.Join(host, "Dockerfile"), filepath.Join(repoPath, "Dockerfile.host"))
+    os.Symlink(filepath.Join(host, "etc"), filepath.Join(repoPath, "hostetc"))
+
+    findings := scanInfrastructure(context.Background(), repoPath)
+
+    var got []string
+    for _, finding := range findings {
+        risk := finding.Risk
+        if risk.Source != RiskSourceRule || !risk.Verified || risk.CodeSnippet == "" {
+            t.Errorf("Expected a verified rule finding with a snippet, got %+v", risk)
+        }
+        got = append(got, fmt.Sprintf("%s:%d %s", risk.FilePath, risk.LineNumber, risk.RuleID))
+    }
+    sort.Strings(got)
+
+    expected := []string{
+        "Dockerfile:9 iac-latest-tag",
+        "Dockerfile:9 iac-run-as-root",
+        "deploy/Dockerfile.prod:1 iac-latest-tag",
+        "deploy/Dockerfile.prod:5 iac-run-as-root",
+        "docker-compose.yml:8 iac-host-path-mount",
+        "docker-compose.yml:14 iac-host-path-mount",
+        "docker-compose.yml:2 iac-missing-resource-limits",
+        "docker-compose.yml:3 iac-latest-tag",
+        "docker-compose.yml:4 iac-privileged-container",
+        "docker-compose.yml:5 iac-run-as-root",
+        "infra/main.tf:10 iac-public-bucket",
+        "infra/main.tf:15 iac-public-bucket",
+        "infra/main.tf:36 iac-open-admin-port",
+        "infra/main.tf:4 iac-public-bucket",
+        "infra/main.tf:59 iac-open-admin-port",
+        "infra/main.tf:63 iac-open-security-group",
+        "k8s/app.yaml:20 iac-latest-tag",
+        "k8s/app.yaml:24 iac-missing-resource-limits",
+        "k8s/app.yaml:27 iac-privileged-container",
+        "k8s/app.yaml:28 iac-run-as-root",
+        "k8s/app.yaml:31 iac-host-path-mount",
+        "k8s/cron.yml:11 iac-missing-resource-limits",
+        "k8s/cron.yml:11 iac-run-as-root",
+    }
+    sort.Strings(expected)
+    if fmt.Sprint(got) != fmt.Sprint(expected) {
+        t.Errorf("Unexpected IaC findings:\n got      %v\n expected %v", got, expected)
+    }
+}
+
+func TestScanDockerfileInheritedRoot(t *testing.T) {
+    findings := scanDockerfile("Dockerfile", `FROM alpine:3.20 AS base
+USER root
+
+FROM base
+COPY app /app
+`)
+    if len(findings) != 1 || findings[0].Risk.RuleID != iacRunAsRoot {
+        t.Fatalf("Expected one run-as-root finding, got %+v", findings)
+    }
+    risk := findings[0].Risk
+    if risk.LineNumber != 4 || risk.CodeSnippet != "FROM base" || !strings.Contains(risk.Description, "inherits USER root from stage base") {
+        t.Errorf("Expected the inherited root user reported at the final FROM, got %+v", risk)
+    }
+}
+
+func TestParseHCL(t *testing.T) {
+    root := parseHCL(infrastructureFiles["infra/main.tf"])
+    resources := root.resources()
+    if len(resources) != 6 {
+        t.Fatalf("Expected 6 resources (commented-out one skipped), got %d", len(resources))
+    }
+
+    group := resources[3]
+    if group.Labels[0] != "aws_security_group" || len(group.Blocks) != 3 || group.Line != 22 {
+        t.Fatalf("Unexpected security group block %+v", group)
+    }
+    cidrs := group.Blocks[1].Attributes["cidr_blocks"]
+    if values := hclStrings(cidrs.Value); cidrs.Line != 36 || len(values) != 2 || values[1] != "0.0.0.0/0" {
+        t.Errorf("Expected the multi-line list from line 36, got %+v", cidrs)
+    }
+    if tags := group.Attributes["tags"]; tags.Line != 49 || hclDepth(tags.Value) != 0 {
+        t.Errorf("Expected the tags object to be kept as one attribute, got %+v", tags)
+    }
+    if acl := resources[0].Attributes["acl"]; hclString(acl.Value) != "public-read" {
+        t.Errorf("Expected the trailing comment stripped, got %q", acl.Value)
+    }
+}
+
+func TestImageUnpinned(t *testing.T) {
+    cases := map[string]bool{
+        "nginx":                       true,
+        "nginx:latest":                true,
+        "registry.local:5000/api":     true,
+        "registry.local:5000/api:1.2": false,
+        "nginx@sha256:abc":            false,
+        "${BASE_IMAGE}":               false,
+        "ghcr.io/org/tool:v1.0.0":     false,
+    }
+    for image, expected := range cases {
+        if imageUnpinned(image) != expected {
+            t.Errorf("imageUnpinned(%q) = %v, expected %v", image, !expected, expected)
+        }
+    }
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+    "regexp"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+// hclBlock is a parsed HCL block such as resource "aws_s3_bucket" "logs" { ... }
+type hclBlock struct {
+    Type       string
+    Labels     []string
+    Line       int
+    Attributes map[string]hclAttribute
+    Blocks     []*hclBlock
+}
+
+// hclAttribute keeps the raw expression of "name = value" and where it starts
+type hclAttribute struct {
+    Value string
+    Line  int
+}
+
+var (
+    hclBlockPattern     = regexp.MustCompile(`^([A-Za-z_][\w-]*)((?:\s+(?:"[^"]*"|[A-Za-z_][\w-]*))*)\s*\{\s*(\})?$`)
+    hclLabelPattern     = regexp.MustCompile(`"([^"]*)"|([A-Za-z_][\w-]*)`)
+    hclAttributePattern = regexp.MustCompile(`^([A-Za-z_][\w-]*)\s*=\s*(.*)$`)
+    hclHeredocPattern   = regexp.MustCompile(`^<<-?([A-Za-z_]\w*)$`)
+    hclStringPattern    = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+)
+
+// parseHCL parses the subset of HCL that Terraform configurations use:
+// nested blocks with labels, and attributes whose lists, objects and
+// heredocs may span several lines. Expressions are kept as raw text.
+func parseHCL(content string) *hclBlock {
+    root := &hclBlock{Attributes: make(map[string]hclAttribute)}
+    stack := []*hclBlock{root}
+    lines := strings.Split(content, "\n")
+    inComment := false
+
+    for index := 0; index < len(lines); index++ {
+        line := stripHCLComment(lines[index], &inComment)
+        current := stack[len(stack)-1]
+
+        if line == "}" {
+            if len(stack) > 1 {
+                stack = stack[:len(stack)-1]
+            }
+            continue
+        }
+        if match := hclBlockPattern.FindStringSubmatch(line); match != nil {
+            block := &hclBlock{Type: match[1], Line: index + 1, Attributes: make(map[string]hclAttribute)}
+            for _, label := range hclLabelPattern.FindAllStringSubmatch(match[2], -1) {
+                block.Labels = append(block.Labels, label[1]+label[2])
+            }
+            current.Blocks = append(current.Blocks, block)
+            if match[3] == "" {
+                stack = append(stack, block)
+            }
+            continue
+        }
+
+        match := hclAttributePattern.FindStringSubmatch(line)
+        if match == nil {
+            continue
+        }
+        value, start := match[2], index+1
+        if heredoc := hclHeredocPattern.FindStringSubmatch(value); heredoc != nil {
+            for index+1 < len(lines) {
+                index++
+                if strings.TrimSpace(lines[index]) == heredoc[1] {
+                    break
+                }
+                value += "\n" + lines[index]
+            }
+        } else {
+            for hclDepth(value) > 0 && index+1 < len(lines) {
+                index++
+                value += "\n" + stripHCLComment(lines[index], &inComment)
+            }
+        }
+        current.Attributes[match[1]] = hclAttribute{Value: strings.TrimSpace(value), Line: start}
+    }
+    return root
+}
+
+// stripHCLComment removes #, // and /* */ comments outside string literals
+func stripHCLComment(line string, inComment *bool) string {
+    var result strings.Builder
+    inString := false
+    for index := 0; index < len(line); index++ {
+        char := line[index]
+        switch {
+        case *inComment:
+            if char == '*' && index+1 < len(line) && line[index+1] == '/' {
+                *inComment = false
+                index++
+            }
+        case inString:
+            result.WriteByte(char)
+            if char == '\\' && index+1 < len(line) {
+                index++
+                result.WriteByte(line[index])
+            } else if char == '"' {
+                inString = false
+            }
+        case char == '"':
+            inString = true
+            result.WriteByte(char)
+        case char == '#' || (char == '/' && index+1 < len(line) && line[index+1] == '/'):
+            return strings.TrimSpace(result.String())
+        case char == '/' && index+1 < len(line) && line[index+1] == '*':
+            *inComment = true
+            index++
+        default:
+            result.WriteByte(char)
+        }
+    }
+    return strings.TrimSpace(result.String())
+}
+
+// hclDepth counts unclosed brackets outside string literals
+func hclDepth(expression string) int {
+    depth, inString := 0, false
+    for index := 0; index < len(expression); index++ {
+        switch char := expression[index]; {
+        case inString && char == '\\':
+            index++
+        case char == '"':
+            inString = !inString
+        case inString:
+        case char == '[' || char == '{' || char == '(':
+            depth++
+        case char == ']' || char == '}' || char == ')':
+            depth--
+        }
+    }
+    return depth
+}
+
+// hclStrings returns the string literals of an expression, e.g. a cidr_blocks list
+func hclStrings(expression string) []string {
+    var values []string
+    for _, match := range hclStringPattern.FindAllStringSubmatch(expression, -1) {
+        values = append(values, match[1])
+    }
+    return values
+}
+
+// hclString returns the value of a plain string literal or the raw expression
+func hclString(expression string) string {
+    if values := hclStrings(expression); len(values) == 1 && expression == `"`+values[0]+`"` {
+        return values[0]
+    }
+    return expression
+}
+
+// resources walks every resource block with its type and name
+func (block *hclBlock) resources() []*hclBlock {
+    var resources []*hclBlock
+    for _, child := range block.Blocks {
+        if child.Type == "resource" && len(child.Labels) == 2 {
+            resources = append(resources, child)
+        }
+    }
+    return resources
+}
+
+var (
+    publicACLs          = map[string]bool{"public-read": true, "public-read-write": true, "authenticated-read": true}
+    publicMembers       = []string{"allUsers", "allAuthenticatedUsers"}
+    publicAccessToggles = []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"}
+    adminPorts          = []int{22, 3389, 1433, 3306, 5432, 6379, 9200, 27017}
+    webPorts            = map[int]bool{80: true, 443: true}
+)
+
+// scanTerraform flags public buckets and security groups open to the internet
+func scanTerraform(path string, content string) []ruleFinding {
+    var findings []ruleFinding
+    for _, resource := range parseHCL(content).resources() {
+        address := resource.Labels[0] + "." + resource.Labels[1]
+        switch resource.Labels[0] {
+        case "aws_s3_bucket", "aws_s3_bucket_acl":
+            if acl, ok := resource.Attributes["acl"]; ok && publicACLs[hclString(acl.Value)] {
+                findings = append(findings, newIaCFinding(path, content, acl.Line, iacPublicBucket, "Resource "+address+" uses ACL "+hclString(acl.Value)+"."))
+            }
+        case "aws_s3_bucket_public_access_block":
+            for _, toggle := range publicAccessToggles {
+                if attribute, ok := resource.Attributes[toggle]; ok && attribute.Value == "false" {
+                    findings = append(findings, newIaCFinding(path, content, attribute.Line, iacPublicBucket, "Resource "+address+" disables "+toggle+"."))
+                    break
+                }
+            }
+        case "google_storage_bucket_iam_member", "google_storage_bucket_iam_binding":
+            attribute, ok := resource.Attributes["member"]
+            if !ok {
+                attribute = resource.Attributes["members"]
+            }
+            for _, member := range hclStrings(attribute.Value) {
+                if slices.Contains(publicMembers, member) {
+                    findings = append(findings, newIaCFinding(path, content, attribute.Line, iacPublicBucket, "Resource "+address+" grants access to "+member+"."))
+                    break
+                }
+            }
+        case "aws_security_group":
+            for _, rule := range resource.Blocks {
+                if rule.Type == "ingress" {
+                    findings = append(findings, openIngressFindings(path, content, address, rule.Line, rule.Attributes)...)
+                }
+            }
+        case "aws_security_group_rule":
+            if hclString(resource.Attributes["type"].Value) == "ingress" {
+                findings = append(findings, openIngressFindings(path, content, address, resource.Line, resource.Attributes)...)
+            }
+        case "aws_vpc_security_group_ingress_rule":
+            findings = append(findings, openIngressFindings(path, content, address, resource.Line, resource.Attributes)...)
+        }
+    }
+    return findings
+}
+
+// openIngressFindings checks one ingress rule; both the inline and the
+// standalone rule resources share the attribute names this looks at
+func openIngressFindings(path string, content string, address string, line int, attributes map[string]hclAttribute) []ruleFinding {
+    var source hclAttribute
+    for _, name := range []string{"cidr_blocks", "ipv6_cidr_blocks", "cidr_ipv4", "cidr_ipv6"} {
+        attribute, ok := attributes[name]
+        if !ok {
+            continue
+        }
+        values := hclStrings(attribute.Value)
+        if slices.Contains(values, "0.0.0.0/0") || slices.Contains(values, "::/0") {
+            source = attribute
+            break
+        }
+    }
+    if source.Line == 0 {
+        return nil
+    }
+
+    protocol := hclString(attributes["protocol"].Value)
+    if protocol == "" {
+        protocol = hclString(attributes["ip_protocol"].Value)
+    }
+    from, fromErr := strconv.Atoi(hclString(attributes["from_port"].Value))
+    to, toErr := strconv.Atoi(hclString(attributes["to_port"].Value))
+    allPorts := protocol == "-1" || protocol == "all" || fromErr != nil || toErr != nil || (from <= 0 && to >= 65535)
+    if allPorts {
+        return []ruleFinding{newIaCFinding(path, content, source.Line, iacOpenAdminPort, "Resource "+address+" allows all ports from the internet.")}
+    }
+    for _, port := range adminPorts {
+        if from <= port && port <= to {
+            return []ruleFinding{newIaCFinding(path, content, source.Line, iacOpenAdminPort, "Resource "+address+" exposes port "+strconv.Itoa(port)+".")}
+        }
+    }
+    // Public web listeners are usually intentional
+    if from == to && webPorts[from] {
+        return nil
+    }
+    return []ruleFinding{newIaCFinding(path, content, source.Line, iacOpenIngress, "Resource "+address+" exposes ports "+strconv.Itoa(from)+"-"+strconv.Itoa(to)+".")}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+    "fmt"
+    "io"
+    "strconv"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// sensitiveHostPaths are host mounts that hand the container control of the node
+var sensitiveHostPaths = []string{"/", "/etc", "/proc", "/sys", "/root", "/var/run/docker.sock", "/run/docker.sock", "/var/lib/docker", "/var/lib/kubelet"}
+
+func sensitiveHostPath(path string) bool {
+    path = strings.TrimSuffix(path, "/")
+    if path == "" {
+        path = "/"
+    }
+    for _, sensitive := range sensitiveHostPaths {
+        if path == sensitive {
+            return true
+        }
+    }
+    return false
+}
+
+// yamlDocuments decodes every document of a (possibly multi-document) YAML file
+func yamlDocuments(content string) []*yaml.Node {
+    var documents []*yaml.Node
+    decoder := yaml.NewDecoder(strings.NewReader(content))
+    for {
+        var document yaml.Node
+        if err := decoder.Decode(&document); err != nil {
+            if err != io.EOF {
+                fmt.Printf("⚠️ Skipping malformed YAML document: %v\n", err)
+            }
+            break
+        }
+        if len(document.Content) > 0 {
+            documents = append(documents, document.Content[0])
+        }
+    }
+    return documents
+}
+
+// yamlField returns the key and value nodes of a mapping entry, or nils
+func yamlField(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+    if node == nil || node.Kind != yaml.MappingNode {
+        return nil, nil
+    }
+    for index := 0; index+1 < len(node.Content); index += 2 {
+        if node.Content[index].Value == key {
+            return node.Content[index], node.Content[index+1]
+        }
+    }
+    return nil, nil
+}
+
+// yamlPath follows nested mapping keys
+func yamlPath(node *yaml.Node, keys ...string) *yaml.Node {
+    for _, key := range keys {
+        _, node = yamlField(node, key)
+    }
+    return node
+}
+
+func yamlScalar(node *yaml.Node) string {
+    if node == nil || node.Kind != yaml.ScalarNode {
+        return ""
+    }
+    return node.Value
+}
+
+func yamlTrue(node *yaml.Node) bool {
+    value, err := strconv.ParseBool(yamlScalar(node))
+    return err == nil && value
+}
+
+func yamlItems(node *yaml.Node) []*yaml.Node {
+    if node == nil || node.Kind != yaml.SequenceNode {
+        return nil
+    }
+    return node.Content
+}
+
+// scanCompose checks each docker-compose service
+func scanCompose(path string, content string) []ruleFinding {
+    var findings []ruleFinding
+    for _, document := range yamlDocuments(content) {
+        services := yamlPath(document, "services")
+        if services == nil || services.Kind != yaml.MappingNode {
+            continue
+        }
+        for index := 0; index+1 < len(services.Content); index += 2 {
+            nameNode, service := services.Content[index], services.Content[index+1]
+            detail := "Service: " + nameNode.Value + "."
+
+            if key, value := yamlField(service, "privileged"); yamlTrue(value) {
+                findings = append(findings, newIaCFinding(path, content, key.Line, iacPrivileged, detail))
+            }
+            if key, value := yamlField(service, "user"); value != nil && isRootUser(yamlScalar(value)) {
+                findings = append(findings, newIaCFinding(path, content, key.Line, iacRunAsRoot, detail))
+            }
+            if key, value := yamlField(service, "image"); imageUnpinned(yamlScalar(value)) {
+                findings = append(findings, newIaCFinding(path, content, key.Line, iacLatestTag, detail+" Image: "+value.Value+"."))
+            }
+            for _, volume := range yamlItems(yamlPath(service, "volumes")) {
+                // Short syntax is "host:container[:mode]", long syntax has a source key
+                sourceKey, sourceNode := yamlField(volume, "source")
+                source, line := yamlScalar(sourceNode), volume.Line
+                if sourceKey != nil {
+                    line = sourceKey.Line
+                }
+                if volume.Kind == yaml.ScalarNode {
+                    source = strings.SplitN(volume.Value, ":", 2)[0]
+                }
+                if strings.HasPrefix(source, "/") && sensitiveHostPath(source) {
+                    findings = append(findings, newIaCFinding(path, content, line, iacHostPath, detail+" Host path: "+source+"."))
+                }
+            }
+
+            limited := yamlPath(service, "deploy", "resources", "limits") != nil
+            for _, key := range []string{"mem_limit", "cpus", "cpu_quota"} {
+                if _, value := yamlField(service, key); value != nil {
+                    limited = true
+                }
+            }
+            if !limited {
+                findings = append(findings, newIaCFinding(path, content, nameNode.Line, iacNoLimits, detail))
+            }
+        }
+    }
+    return findings
+}
+
+// podSpecPaths locate the pod spec inside each workload kind
+var podSpecPaths = map[string][]string{
+    "Pod":                   {"spec"},
+    "Deployment":            {"spec", "template", "spec"},
+    "StatefulSet":           {"spec", "template", "spec"},
+    "DaemonSet":             {"spec", "template", "spec"},
+    "ReplicaSet":            {"spec", "template", "spec"},
+    "ReplicationController": {"spec", "template", "spec"},
+    "Job":                   {"spec", "template", "spec"},
+    "CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// scanKubernetes checks the pod spec of every workload in a manifest
+func scanKubernetes(path string, content string) []ruleFinding {
+    var findings []ruleFinding
+    for _, document := range yamlDocuments(content) {
+        kind := yamlScalar(yamlPath(document, "kind"))
+        specPath, ok := podSpecPaths[kind]
+        if !ok {
+            continue
+        }
+        spec := yamlPath(document, specPath...)
+        if spec == nil {
+            continue
+        }
+        workload := kind + " " + yamlScalar(yamlPath(document, "metadata", "name"))
+        podSecurity := yamlPath(spec, "securityContext")
+
+        containers := yamlItems(yamlPath(spec, "containers"))
+        initContainers := yamlItems(yamlPath(spec, "initContainers"))
+        for index, container := range append(append([]*yaml.Node{}, containers...), initContainers...) {
+            nameKey, nameNode := yamlField(container, "name")
+            detail := workload + ", container " + yamlScalar(nameNode) + "."
+            line := container.Line
+            if nameKey != nil {
+                line = nameKey.Line
+            }
+            security := yamlPath(container, "securityContext")
+
+            if key, value := yamlField(security, "privileged"); yamlTrue(value) {
+                findings = append(findings, newIaCFinding(path, content, key.Line, iacPrivileged, detail))
+            }
+            findings = append(findings, kubernetesRootFindings(path, content, line, detail, podSecurity, security)...)
+            if key, value := yamlField(container, "image"); imageUnpinned(yamlScalar(value)) {
+                findings = append(findings, newIaCFinding(path, content, key.Line, iacLatestTag, detail+" Image: "+value.Value+"."))
+            }
+            // Init containers run to completion, so only long-running containers need limits
+            if index < len(containers) && yamlPath(container, "resources", "limits", "memory") == nil {
+                findings = append(findings, newIaCFinding(path, content, line, iacNoLimits, detail))
+            }
+        }
+
+        for _, volume := range yamlItems(yamlPath(spec, "volumes")) {
+            key, hostPath := yamlField(volume, "hostPath")
+            if hostPath == nil {
+                continue
+            }
+            source := yamlScalar(yamlPath(hostPath, "path"))
+            detail := workload + ", volume " + yamlScalar(yamlPath(volume, "name")) + " (host path " + source + ")."
+            findings = append(findings, newIaCFinding(path, content, key.Line, iacHostPath, detail))
+        }
+    }
+    return findings
+}
+
+// kubernetesRootFindings flags a container that runs as UID 0 or never opts
+// into runAsNonRoot; container settings override the pod's
+func kubernetesRootFindings(path string, content string, line int, detail string, podSecurity *yaml.Node, security *yaml.Node) []ruleFinding {
+    userKey, user := yamlField(security, "runAsUser")
+    if user == nil {
+        userKey, user = yamlField(podSecurity, "runAsUser")
+    }
+    _, nonRoot := yamlField(security, "runAsNonRoot")
+    if nonRoot == nil {
+        _, nonRoot = yamlField(podSecurity, "runAsNonRoot")
+    }
+
+    if user != nil && yamlScalar(user) == "0" {
+        return []ruleFinding{newIaCFinding(path, content, userKey.Line, iacRunAsRoot, detail+" runAsUser is 0.")}
+    }
+    if uid, err := strconv.Atoi(yamlScalar(user)); (err == nil && uid > 0) || yamlTrue(nonRoot) {
+        return nil
+    }
+    return []ruleFinding{newIaCFinding(path, content, line, iacRunAsRoot, detail+" Neither runAsNonRoot nor a non-zero runAsUser is set.")}
+}
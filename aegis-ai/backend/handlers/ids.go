@@ -0,0 +1,84 @@
+package handlers
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "sync"
+    "time"
+)
+
+// Crockford base32 alphabet used by ULIDs
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var (
+    ulidMu       sync.Mutex
+    ulidLastTime uint64
+    ulidLastRand [10]byte
+)
+
+// newULID returns a 26-char ULID: 48-bit millisecond timestamp followed by
+// 80 random bits. IDs from the same millisecond increment the random part
+// so they stay strictly sortable.
+func newULID() string {
+    ulidMu.Lock()
+    now := uint64(time.Now().UnixMilli())
+    var entropy [10]byte
+    if now <= ulidLastTime {
+        // Same (or earlier) millisecond - keep ordering by incrementing
+        now = ulidLastTime
+        entropy = ulidLastRand
+        for i := len(entropy) - 1; i >= 0; i-- {
+            entropy[i]++
+            if entropy[i] != 0 {
+                break
+            }
+        }
+    } else if _, err := rand.Read(entropy[:]); err != nil {
+        ulidMu.Unlock()
+        panic("crypto/rand unavailable: " + err.Error())
+    }
+    ulidLastTime = now
+    ulidLastRand = entropy
+    ulidMu.Unlock()
+
+    var raw [16]byte
+    for i := 0; i < 6; i++ {
+        raw[i] = byte(now >> (40 - 8*i))
+    }
+    copy(raw[6:], entropy[:])
+    return encodeCrockford(raw)
+}
+
+// encodeCrockford encodes 128 bits as 26 base32 characters (first char holds 3 bits)
+func encodeCrockford(raw [16]byte) string {
+    out := make([]byte, 26)
+    var bits uint
+    var acc uint32
+    pos := 25
+    for i := len(raw) - 1; i >= 0; i-- {
+        acc |= uint32(raw[i]) << bits
+        bits += 8
+        for bits >= 5 {
+            out[pos] = ulidAlphabet[acc&31]
+            pos--
+            acc >>= 5
+            bits -= 5
+        }
+    }
+    out[0] = ulidAlphabet[acc&31]
+    return string(out)
+}
+
+// newAnalysisID returns a collision-free, time-sortable analysis ID
+func newAnalysisID() string {
+    return "analysis_" + newULID()
+}
+
+// newSessionToken returns an unguessable 256-bit session token
+func newSessionToken() string {
+    var token [32]byte
+    if _, err := rand.Read(token[:]); err != nil {
+        panic("crypto/rand unavailable: " + err.Error())
+    }
+    return hex.EncodeToString(token[:])
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+    "strings"
+    "sync"
+    "testing"
+)
+
+func TestAnalysisIDsUniqueUnderConcurrency(t *testing.T) {
+    const goroutines = 16
+    const perGoroutine = 500
+
+    var mu sync.Mutex
+    seen := make(map[string]bool)
+    var wg sync.WaitGroup
+    for g := 0; g < goroutines; g++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            ids := make([]string, 0, perGoroutine)
+            for i := 0; i < perGoroutine; i++ {
+                ids = append(ids, newAnalysisID())
+            }
+            mu.Lock()
+            defer mu.Unlock()
+            for _, id := range ids {
+                if seen[id] {
+                    t.Errorf("Duplicate analysis ID: %s", id)
+                }
+                seen[id] = true
+            }
+        }()
+    }
+    wg.Wait()
+
+    if len(seen) != goroutines*perGoroutine {
+        t.Errorf("Expected %d IDs, got %d", goroutines*perGoroutine, len(seen))
+    }
+}
+
+func TestAnalysisIDsAreSortable(t *testing.T) {
+    previous := newAnalysisID()
+    for i := 0; i < 1000; i++ {
+        id := newAnalysisID()
+        if id <= previous {
+            t.Fatalf("IDs not increasing: %s then %s", previous, id)
+        }
+        if len(strings.TrimPrefix(id, "analysis_")) != 26 {
+            t.Fatalf("Unexpected ULID length: %s", id)
+        }
+        previous = id
+    }
+}
+
+func TestSessionTokensUniqueUnderConcurrency(t *testing.T) {
+    sessions := NewSessionStore()
+
+    var wg sync.WaitGroup
+    ids := make(chan string, 200)
+    for i := 0; i < 200; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            ids <- sessions.Create(&GitHubUser{Login: "octocat"}, "token")
+        }()
+    }
+    wg.Wait()
+    close(ids)
+
+    seen := make(map[string]bool)
+    for id := range ids {
+        if len(id) != 64 {
+            t.Errorf("Session token too short to be unguessable: %q", id)
+        }
+        if seen[id] {
+            t.Errorf("Duplicate session token: %s", id)
+        }
+        seen[id] = true
+    }
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "sync"
+)
+
+// MockLLMProvider returns canned responses so the scan pipeline can run
+// offline. Fixtures are keyed by prompt hash (see PromptHash); Default
+// answers anything else.
+type MockLLMProvider struct {
+    Fixtures map[string]string
+    Default  string
+
+    mu    sync.Mutex
+    calls []string
+}
+
+func NewMockLLMProvider(fixtures map[string]string) *MockLLMProvider {
+    return &MockLLMProvider{Fixtures: fixtures}
+}
+
+// NewMockLLMProviderFromDir loads every *.json file in dir as a fixture keyed
+// by its file name without extension. The fixture named after repo (e.g.
+// vulnerable-app.json) becomes the Default response, falling back to default.json.
+func NewMockLLMProviderFromDir(dir string, repo string) (*MockLLMProvider, error) {
+    files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+    if err != nil {
+        return nil, err
+    }
+    if len(files) == 0 {
+        return nil, fmt.Errorf("no fixtures found in %s", dir)
+    }
+
+    provider := NewMockLLMProvider(make(map[string]string))
+    for _, file := range files {
+        content, err := os.ReadFile(file)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read fixture %s: %v", file, err)
+        }
+        key := strings.TrimSuffix(filepath.Base(file), ".json")
+        if key == "default" {
+            provider.Default = string(content)
+            continue
+        }
+        provider.Fixtures[key] = string(content)
+    }
+    if repo != "" {
+        fixture, exists := provider.Fixtures[path.Base(repo)]
+        if !exists {
+            return nil, fmt.Errorf("no fixture for repo %q in %s", repo, dir)
+        }
+        provider.Default = fixture
+    }
+    return provider, nil
+}
+
+// PromptHash is the fixture key for an exact prompt
+func PromptHash(prompt string) string {
+    sum := sha256.Sum256([]byte(prompt))
+    return hex.EncodeToString(sum[:])
+}
+
+func (p *MockLLMProvider) Name() string {
+    return "mock"
+}
+
+func (p *MockLLMProvider) ModelNames() []string {
+    return []string{"mock"}
+}
+
+func (p *MockLLMProvider) Complete(ctx context.Context, request CompletionRequest) (string, error) {
+    if err := ctx.Err(); err != nil {
+        return "", err
+    }
+
+    hash := PromptHash(request.UserPrompt)
+    p.mu.Lock()
+    p.calls = append(p.calls, hash)
+    p.mu.Unlock()
+
+    if response, exists := p.Fixtures[hash]; exists {
+        fmt.Printf("🧪 [mock] Serving fixture: %s\n", hash)
+        return response, nil
+    }
+    if p.Default != "" {
+        return p.Default, nil
+    }
+    return "", fmt.Errorf("no fixture for prompt hash %s", hash)
+}
+
+// Calls returns the prompt hashes the provider has been asked to complete
+func (p *MockLLMProvider) Calls() []string {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return append([]string(nil), p.calls...)
+}
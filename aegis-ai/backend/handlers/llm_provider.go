@@ -0,0 +1,264 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Default provider order when AEGIS_LLM_PROVIDERS is not set
+var defaultLLMProviderOrder = []string{"groq", "openrouter", "local"}
+
+// LLMProvider is a chat-completion backend used for the security audit.
+// The analyzer fails over between each provider's models, then between
+// providers in the configured order, sizing the prompt for every model.
+type LLMProvider interface {
+    Name() string
+    ModelNames() []string
+    Complete(ctx context.Context, request CompletionRequest) (string, error)
+}
+
+// CompletionRequest is one chat completion against a specific model
+type CompletionRequest struct {
+    Model        string
+    SystemPrompt string
+    UserPrompt   string
+    MaxTokens    int
+}
+
+// promptBuilder renders the user prompt to fit a model and returns the file
+// segments that made it in, or an error if nothing useful fits
+type promptBuilder func(spec ModelSpec) (string, []fileSegment, error)
+
+// staticPrompt sends the same prompt to every model
+func staticPrompt(prompt string) promptBuilder {
+    return func(spec ModelSpec) (string, []fileSegment, error) {
+        return prompt, nil, nil
+    }
+}
+
+// Providers set by main (or tests) - nil means load from the environment
+var llmProviders []LLMProvider
+
+// SetLLMProviders overrides the providers used for analysis.
+// It must be called before the server starts handling requests.
+func SetLLMProviders(providers []LLMProvider) {
+    llmProviders = providers
+}
+
+func activeLLMProviders() []LLMProvider {
+    if llmProviders != nil {
+        return llmProviders
+    }
+    return LoadLLMProvidersFromEnv()
+}
+
+// LoadLLMProvidersFromEnv builds the configured providers in AEGIS_LLM_PROVIDERS
+// order (e.g. "local,groq"), skipping any provider that has no credentials or URL
+func LoadLLMProvidersFromEnv() []LLMProvider {
+    order := defaultLLMProviderOrder
+    if configured := os.Getenv("AEGIS_LLM_PROVIDERS"); configured != "" {
+        order = splitList(configured)
+    }
+
+    var providers []LLMProvider
+    for _, name := range order {
+        switch strings.ToLower(name) {
+        case "groq":
+            if apiKey := os.Getenv("GROQ_API_KEY"); apiKey != "" {
+                providers = append(providers, withRetryPolicy(NewGroqProvider(apiKey, splitList(os.Getenv("GROQ_MODELS"))), "GROQ"))
+            }
+        case "openrouter":
+            if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey != "" {
+                providers = append(providers, withRetryPolicy(NewOpenRouterProvider(apiKey, splitList(os.Getenv("OPENROUTER_MODELS"))), "OPENROUTER"))
+            }
+        case "local":
+            if baseURL := os.Getenv("LOCAL_LLM_URL"); baseURL != "" {
+                providers = append(providers, withRetryPolicy(NewLocalProvider(baseURL, splitList(os.Getenv("LOCAL_LLM_MODELS"))), "LOCAL_LLM"))
+            }
+        case "mock":
+            // Offline fixtures for demos and CI - never in the default order.
+            // AEGIS_MOCK_LLM_REPO picks the fixture every scan is answered with.
+            if dir := os.Getenv("AEGIS_MOCK_LLM_FIXTURES"); dir != "" {
+                provider, err := NewMockLLMProviderFromDir(dir, os.Getenv("AEGIS_MOCK_LLM_REPO"))
+                if err != nil {
+                    fmt.Printf("⚠️ Mock LLM provider disabled: %v\n", err)
+                    continue
+                }
+                providers = append(providers, provider)
+            }
+        default:
+            fmt.Printf("⚠️ Unknown LLM provider in AEGIS_LLM_PROVIDERS: %s\n", name)
+        }
+    }
+    return providers
+}
+
+// withRetryPolicy wraps a remote provider with the retry policy and circuit
+// breaker configured under its env prefix (see RetryPolicyFromEnv)
+func withRetryPolicy(provider LLMProvider, prefix string) LLMProvider {
+    return NewResilientProvider(provider, RetryPolicyFromEnv(prefix), CircuitBreakerFromEnv(prefix))
+}
+
+// callAIProviders tries each provider's models in order, building the prompt for
+// the model's context window, and returns the first answer that passes schema
+// validation (see completeWithRepair). It also returns the segments that were
+// actually sent so coverage reflects smaller fallbacks.
+func callAIProviders(ctx context.Context, providers []LLMProvider, build promptBuilder, knownFiles map[string]bool) (*AIAnalysisResponse, []fileSegment, error) {
+    if len(providers) == 0 {
+        return nil, nil, fmt.Errorf("all AI services unavailable. Please set GROQ_API_KEY, OPENROUTER_API_KEY or LOCAL_LLM_URL")
+    }
+
+    var lastError error
+    var parseError *AIParseError
+    for _, provider := range providers {
+        fmt.Printf("🚀 Using %s provider (Comprehensive Security Analysis)...\n", provider.Name())
+
+        for _, model := range provider.ModelNames() {
+            // Stop trying models once the scan is cancelled or past its deadline
+            if err := ctx.Err(); err != nil {
+                return nil, nil, err
+            }
+
+            spec := LookupModel(model)
+            prompt, sent, err := build(spec)
+            if err != nil {
+                fmt.Printf("⚠️ [%s] Skipping model %s: %v\n", provider.Name(), model, err)
+                lastError = fmt.Errorf("%s model %s: %v", provider.Name(), model, err)
+                continue
+            }
+
+            systemPrompt := activePrompts().System()
+            promptTokens := estimateTokens(systemPrompt) + estimateTokens(prompt)
+            fmt.Printf("🤖 [%s] Trying model: %s (~%d/%d prompt tokens, est. $%.4f)\n", provider.Name(), model,
+                promptTokens, spec.PromptBudget(), spec.EstimateCost(promptTokens, spec.MaxOutputTokens))
+
+            response, err := completeWithRepair(ctx, provider, CompletionRequest{
+                Model:        model,
+                SystemPrompt: systemPrompt,
+                UserPrompt:   prompt,
+                MaxTokens:    spec.MaxOutputTokens,
+            }, knownFiles)
+            if errors.Is(err, ErrCircuitOpen) {
+                // The provider is down - don't walk through the rest of its models
+                fmt.Printf("🔌 [%s] %v\n", provider.Name(), err)
+                lastError = err
+                break
+            }
+            if err != nil {
+                fmt.Printf("❌ [%s] Model %s failed: %v\n", provider.Name(), model, err)
+                lastError = fmt.Errorf("%s model %s: %v", provider.Name(), model, err)
+                if invalid, ok := err.(*AIParseError); ok {
+                    parseError = invalid
+                }
+                continue
+            }
+
+            fmt.Printf("✅ [%s] Success with model: %s\n", provider.Name(), model)
+            return response, sent, nil
+        }
+    }
+
+    if err := ctx.Err(); err != nil {
+        return nil, nil, err
+    }
+    // A model answered but never produced valid JSON - report that rather than an outage
+    if parseError != nil {
+        return nil, nil, parseError
+    }
+    return nil, nil, fmt.Errorf("all AI providers failed: %v", lastError)
+}
+
+// HTTPStatusError is a non-200 answer from a chat completion endpoint
+type HTTPStatusError struct {
+    StatusCode int
+    Status     string
+    RetryAfter time.Duration // from the Retry-After header, 0 if absent
+}
+
+func (e *HTTPStatusError) Error() string {
+    return fmt.Sprintf("failed with status: %s", e.Status)
+}
+
+// Transient reports whether the same request may succeed later: rate limits,
+// timeouts and server errors. Other 4xx answers are permanent.
+func (e *HTTPStatusError) Transient() bool {
+    return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+    value = strings.TrimSpace(value)
+    if value == "" {
+        return 0
+    }
+    if seconds, err := strconv.Atoi(value); err == nil {
+        if seconds < 0 {
+            return 0
+        }
+        return time.Duration(seconds) * time.Second
+    }
+    if date, err := http.ParseTime(value); err == nil && date.After(now) {
+        return date.Sub(now)
+    }
+    return 0
+}
+
+// postChatCompletion sends an OpenAI-style chat request and decodes the reply into out
+func postChatCompletion(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}, out interface{}) error {
+    jsonData, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("failed to marshal request: %v", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+    if err != nil {
+        return fmt.Errorf("failed to create request: %v", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for key, value := range headers {
+        req.Header.Set(key, value)
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    body, _ := io.ReadAll(resp.Body)
+    if resp.StatusCode != http.StatusOK {
+        return &HTTPStatusError{
+            StatusCode: resp.StatusCode,
+            Status:     resp.Status,
+            RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+        }
+    }
+
+    if err := json.Unmarshal(body, out); err != nil {
+        return fmt.Errorf("failed to parse response: %v", err)
+    }
+    return nil
+}
+
+func splitList(value string) []string {
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+func newLLMHTTPClient() *http.Client {
+    return &http.Client{Timeout: 120 * time.Second} // Increased timeout for larger models
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestProviderFailover(t *testing.T) {
+    down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "overloaded", http.StatusServiceUnavailable)
+    }))
+    defer down.Close()
+
+    var gotModel string
+    up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        var req OpenRouterRequest
+        json.NewDecoder(r.Body).Decode(&req)
+        gotModel = req.Model
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "choices": []map[string]interface{}{
+                {"message": map[string]string{"content": `{"high_risks": [{"file": "app.js", "line": 3, "title": "SQL Injection", "description": "Query built from user input", "confidence": 0.9}]}`}},
+            },
+        })
+    }))
+    defer up.Close()
+
+    providers := []LLMProvider{
+        NewLocalProvider(down.URL, []string{"llama3.1"}),
+        NewLocalProvider(up.URL+"/", []string{"qwen2.5-coder"}),
+    }
+
+    response, _, err := callAIProviders(context.Background(), providers, staticPrompt("audit this"), nil)
+    if err != nil {
+        t.Fatalf("Expected failover to second provider, got %v", err)
+    }
+    if gotModel != "qwen2.5-coder" {
+        t.Errorf("Expected second provider's model, got %q", gotModel)
+    }
+    if len(response.HighRisks) != 1 || response.HighRisks[0].FilePath != "app.js" {
+        t.Errorf("Unexpected parsed response: %+v", response.HighRisks)
+    }
+
+    if _, _, err := callAIProviders(context.Background(), providers[:1], staticPrompt("audit this"), nil); err == nil {
+        t.Error("Expected error when every provider fails")
+    }
+}
+
+func TestLoadLLMProvidersFromEnvOrder(t *testing.T) {
+    t.Setenv("AEGIS_LLM_PROVIDERS", "local, groq, openrouter")
+    t.Setenv("GROQ_API_KEY", "gsk_test_key")
+    t.Setenv("OPENROUTER_API_KEY", "")
+    t.Setenv("LOCAL_LLM_URL", "http://localhost:11434/v1")
+
+    providers := LoadLLMProvidersFromEnv()
+    if len(providers) != 2 || providers[0].Name() != "local" || providers[1].Name() != "groq" {
+        names := []string{}
+        for _, provider := range providers {
+            names = append(names, provider.Name())
+        }
+        t.Errorf("Unexpected providers: %v", names)
+    }
+}
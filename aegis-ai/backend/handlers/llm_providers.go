@@ -0,0 +1,159 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "os"
+    "strings"
+)
+
+// GROQ PROVIDER - hosted Llama/Mixtral models
+type GroqProvider struct {
+    APIKey  string
+    BaseURL string
+    Models  []string
+    Client  *http.Client
+}
+
+func NewGroqProvider(apiKey string, models []string) *GroqProvider {
+    if len(models) == 0 {
+        // Enhanced models for better analysis
+        models = []string{
+            "llama-3.1-70b-versatile",    // Most capable for comprehensive analysis
+            "mixtral-8x7b-32768",         // Large context window
+            "llama-3.1-8b-instant",       // Fast and reliable
+        }
+    }
+    baseURL := os.Getenv("GROQ_API_URL")
+    if baseURL == "" {
+        baseURL = "https://api.groq.com/openai/v1"
+    }
+    return &GroqProvider{
+        APIKey:  apiKey,
+        BaseURL: baseURL,
+        Models:  models,
+        Client:  newLLMHTTPClient(),
+    }
+}
+
+func (p *GroqProvider) Name() string {
+    return "groq"
+}
+
+func (p *GroqProvider) ModelNames() []string {
+    return p.Models
+}
+
+func (p *GroqProvider) Complete(ctx context.Context, request CompletionRequest) (string, error) {
+    fmt.Printf("🔑 Using Groq API key: %s\n", maskString(p.APIKey))
+
+    groqRequest := GroqRequest{
+        Messages: []GroqMessage{
+            {Role: "system", Content: request.SystemPrompt},
+            {Role: "user", Content: request.UserPrompt},
+        },
+        Model:       request.Model,
+        Temperature: 0.1, // Lower for more consistent security analysis
+        MaxTokens:   request.MaxTokens,
+        TopP:        0.9,
+    }
+
+    var groqResp GroqResponse
+    headers := map[string]string{"Authorization": "Bearer " + p.APIKey}
+    if err := postChatCompletion(ctx, p.Client, p.BaseURL+"/chat/completions", headers, groqRequest, &groqResp); err != nil {
+        return "", err
+    }
+    if groqResp.Error.Message != "" {
+        return "", fmt.Errorf("returned error: %s", groqResp.Error.Message)
+    }
+    if len(groqResp.Choices) == 0 || groqResp.Choices[0].Message.Content == "" {
+        return "", fmt.Errorf("returned empty response")
+    }
+    return groqResp.Choices[0].Message.Content, nil
+}
+
+// OPENAI-COMPATIBLE PROVIDER - OpenRouter and local servers (Ollama, llama.cpp)
+type OpenAICompatibleProvider struct {
+    ProviderName string
+    APIKey       string
+    BaseURL      string
+    Models       []string
+    Headers      map[string]string
+    Client       *http.Client
+}
+
+func NewOpenRouterProvider(apiKey string, models []string) *OpenAICompatibleProvider {
+    if len(models) == 0 {
+        models = []string{
+            "meta-llama/llama-3.1-70b-instruct",
+            "mistralai/mixtral-8x7b-instruct",
+        }
+    }
+    return &OpenAICompatibleProvider{
+        ProviderName: "openrouter",
+        APIKey:       apiKey,
+        BaseURL:      "https://openrouter.ai/api/v1",
+        Models:       models,
+        Headers: map[string]string{
+            "HTTP-Referer": "https://github.com/aegis-ai",
+            "X-Title":      "Aegis AI",
+        },
+        Client: newLLMHTTPClient(),
+    }
+}
+
+// NewLocalProvider talks to a local OpenAI-compatible server,
+// e.g. http://localhost:11434/v1 for Ollama
+func NewLocalProvider(baseURL string, models []string) *OpenAICompatibleProvider {
+    if len(models) == 0 {
+        models = []string{"llama3.1"}
+    }
+    return &OpenAICompatibleProvider{
+        ProviderName: "local",
+        APIKey:       os.Getenv("LOCAL_LLM_API_KEY"),
+        BaseURL:      strings.TrimSuffix(baseURL, "/"),
+        Models:       models,
+        Client:       newLLMHTTPClient(),
+    }
+}
+
+func (p *OpenAICompatibleProvider) Name() string {
+    return p.ProviderName
+}
+
+func (p *OpenAICompatibleProvider) ModelNames() []string {
+    return p.Models
+}
+
+func (p *OpenAICompatibleProvider) Complete(ctx context.Context, request CompletionRequest) (string, error) {
+    headers := make(map[string]string)
+    for key, value := range p.Headers {
+        headers[key] = value
+    }
+    if p.APIKey != "" {
+        headers["Authorization"] = "Bearer " + p.APIKey
+    }
+
+    chatRequest := OpenRouterRequest{
+        Model: request.Model,
+        Messages: []OpenRouterMessage{
+            {Role: "system", Content: request.SystemPrompt},
+            {Role: "user", Content: request.UserPrompt},
+        },
+        Temperature: 0.1,
+        MaxTokens:   request.MaxTokens,
+    }
+
+    var response OpenRouterResponse
+    if err := postChatCompletion(ctx, p.Client, p.BaseURL+"/chat/completions", headers, chatRequest, &response); err != nil {
+        return "", err
+    }
+    if response.Error.Message != "" {
+        return "", fmt.Errorf("returned error: %s", response.Error.Message)
+    }
+    if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+        return "", fmt.Errorf("returned empty response")
+    }
+    return response.Choices[0].Message.Content, nil
+}
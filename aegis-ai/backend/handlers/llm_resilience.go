@@ -0,0 +1,238 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "net/url"
+    "os"
+    "sync"
+    "time"
+)
+
+const (
+    defaultRetryAttempts    = 3
+    defaultRetryBaseDelay   = time.Second
+    defaultRetryMaxDelay    = 30 * time.Second
+    defaultBreakerThreshold = 3
+    defaultBreakerCooldown  = time.Minute
+)
+
+// ErrCircuitOpen is returned without calling the provider while its breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// RetryPolicy controls how often one model call is retried on transient errors
+type RetryPolicy struct {
+    MaxAttempts int           // total tries per call, 1 disables retries
+    BaseDelay   time.Duration // first backoff, doubled on every retry
+    MaxDelay    time.Duration // backoff cap and the longest Retry-After we wait for
+}
+
+// RetryPolicyFromEnv reads <prefix>_RETRY_ATTEMPTS, <prefix>_RETRY_BASE_DELAY and
+// <prefix>_RETRY_MAX_DELAY (e.g. GROQ_RETRY_ATTEMPTS), falling back to the
+// AEGIS_RETRY_* values and then the defaults
+func RetryPolicyFromEnv(prefix string) RetryPolicy {
+    return RetryPolicy{
+        MaxAttempts: envInt(prefix+"_RETRY_ATTEMPTS", envInt("AEGIS_RETRY_ATTEMPTS", defaultRetryAttempts)),
+        BaseDelay:   envDuration(prefix+"_RETRY_BASE_DELAY", envDuration("AEGIS_RETRY_BASE_DELAY", defaultRetryBaseDelay)),
+        MaxDelay:    envDuration(prefix+"_RETRY_MAX_DELAY", envDuration("AEGIS_RETRY_MAX_DELAY", defaultRetryMaxDelay)),
+    }
+}
+
+// envDuration reads a duration like "500ms" or "2s" from the environment
+func envDuration(name string, fallback time.Duration) time.Duration {
+    value, err := time.ParseDuration(os.Getenv(name))
+    if err != nil || value <= 0 {
+        return fallback
+    }
+    return value
+}
+
+// backoff is the delay before retry number attempt (1-based): exponential
+// with jitter between half and the full delay so parallel scans spread out
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+    delay := p.BaseDelay
+    for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+        delay *= 2
+    }
+    if delay > p.MaxDelay {
+        delay = p.MaxDelay
+    }
+    return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isTransientError reports whether retrying a failed call could help:
+// rate limits, server errors and network failures
+func isTransientError(err error) bool {
+    var statusErr *HTTPStatusError
+    if errors.As(err, &statusErr) {
+        return statusErr.Transient()
+    }
+    var urlErr *url.Error
+    return errors.As(err, &urlErr)
+}
+
+// Circuit breaker states
+const (
+    BreakerClosed   = "closed"
+    BreakerOpen     = "open"
+    BreakerHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops calling a provider after Threshold consecutive failed
+// calls. Once Cooldown has passed a single probe call is let through; its
+// result closes the breaker again or restarts the cool-off.
+type CircuitBreaker struct {
+    Threshold int
+    Cooldown  time.Duration
+
+    mu       sync.Mutex
+    state    string
+    failures int
+    openedAt time.Time
+    now      func() time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+    return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown, state: BreakerClosed, now: time.Now}
+}
+
+// CircuitBreakerFromEnv reads <prefix>_BREAKER_THRESHOLD and <prefix>_BREAKER_COOLDOWN,
+// falling back to the AEGIS_BREAKER_* values and then the defaults
+func CircuitBreakerFromEnv(prefix string) *CircuitBreaker {
+    return NewCircuitBreaker(
+        envInt(prefix+"_BREAKER_THRESHOLD", envInt("AEGIS_BREAKER_THRESHOLD", defaultBreakerThreshold)),
+        envDuration(prefix+"_BREAKER_COOLDOWN", envDuration("AEGIS_BREAKER_COOLDOWN", defaultBreakerCooldown)),
+    )
+}
+
+// Allow reports whether a call may go through, and moves an open breaker
+// to half-open once the cool-off has passed
+func (b *CircuitBreaker) Allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    switch b.state {
+    case BreakerOpen:
+        if b.now().Sub(b.openedAt) < b.Cooldown {
+            return false
+        }
+        b.state = BreakerHalfOpen
+        b.openedAt = b.now()
+        return true
+    case BreakerHalfOpen:
+        // Only the probe call goes through until it reports back, unless it
+        // never did (e.g. its scan was cancelled) within another cool-off
+        if b.now().Sub(b.openedAt) < b.Cooldown {
+            return false
+        }
+        b.openedAt = b.now()
+        return true
+    }
+    return true
+}
+
+// Success closes the breaker and resets the failure count
+func (b *CircuitBreaker) Success() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.state = BreakerClosed
+    b.failures = 0
+}
+
+// Failure counts a failed call and opens the breaker at the threshold
+// or when the half-open probe fails
+func (b *CircuitBreaker) Failure() {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    b.failures++
+    if b.state == BreakerHalfOpen || (b.Threshold > 0 && b.failures >= b.Threshold) {
+        b.state = BreakerOpen
+        b.openedAt = b.now()
+    }
+}
+
+func (b *CircuitBreaker) State() string {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.state
+}
+
+// retryUntil is when an open breaker lets the next probe through
+func (b *CircuitBreaker) retryUntil() time.Time {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    return b.openedAt.Add(b.Cooldown)
+}
+
+// ResilientProvider wraps a provider with its retry policy and circuit breaker
+type ResilientProvider struct {
+    LLMProvider
+    Retry   RetryPolicy
+    Breaker *CircuitBreaker
+
+    sleep func(ctx context.Context, delay time.Duration) error
+}
+
+func NewResilientProvider(provider LLMProvider, retry RetryPolicy, breaker *CircuitBreaker) *ResilientProvider {
+    if retry.MaxAttempts < 1 {
+        retry.MaxAttempts = 1
+    }
+    return &ResilientProvider{LLMProvider: provider, Retry: retry, Breaker: breaker, sleep: sleepContext}
+}
+
+func (p *ResilientProvider) Complete(ctx context.Context, request CompletionRequest) (string, error) {
+    if !p.Breaker.Allow() {
+        return "", fmt.Errorf("%w: %s skipped until %s", ErrCircuitOpen, p.Name(), p.Breaker.retryUntil().Format(time.RFC3339))
+    }
+
+    for attempt := 1; ; attempt++ {
+        content, err := p.LLMProvider.Complete(ctx, request)
+        if err == nil {
+            p.Breaker.Success()
+            return content, nil
+        }
+        if ctx.Err() != nil {
+            return "", err
+        }
+        if !isTransientError(err) {
+            // The provider answered - the request itself was rejected
+            p.Breaker.Success()
+            return "", err
+        }
+        if attempt >= p.Retry.MaxAttempts {
+            p.Breaker.Failure()
+            return "", fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+        }
+
+        delay := p.Retry.backoff(attempt)
+        var statusErr *HTTPStatusError
+        if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+            if statusErr.RetryAfter > p.Retry.MaxDelay {
+                p.Breaker.Failure()
+                return "", fmt.Errorf("%w (Retry-After %s exceeds the %s limit)", err, statusErr.RetryAfter, p.Retry.MaxDelay)
+            }
+            delay = statusErr.RetryAfter
+        }
+
+        fmt.Printf("⏳ [%s] Model %s: %v - retrying in %s (attempt %d/%d)\n",
+            p.Name(), request.Model, err, delay.Round(time.Millisecond), attempt+1, p.Retry.MaxAttempts)
+        if err := p.sleep(ctx, delay); err != nil {
+            return "", err
+        }
+    }
+}
+
+// sleepContext waits for delay or until ctx is done
+func sleepContext(ctx context.Context, delay time.Duration) error {
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-ctx.Done():
+        return ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
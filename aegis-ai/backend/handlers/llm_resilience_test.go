@@ -0,0 +1,139 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// scriptedServer answers chat completions with the given statuses in order,
+// then with a valid completion
+func scriptedServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+    t.Helper()
+    var requests int32
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        call := int(atomic.AddInt32(&requests, 1))
+        if call <= len(statuses) {
+            if statuses[call-1] == http.StatusTooManyRequests {
+                w.Header().Set("Retry-After", "2")
+            }
+            http.Error(w, "scripted failure", statuses[call-1])
+            return
+        }
+        w.Write([]byte(`{"choices": [{"message": {"content": "ok"}}]}`))
+    }))
+    t.Cleanup(server.Close)
+    return server, &requests
+}
+
+// recordSleeps replaces the provider's sleep so tests don't wait
+func recordSleeps(provider *ResilientProvider) *[]time.Duration {
+    var delays []time.Duration
+    provider.sleep = func(ctx context.Context, delay time.Duration) error {
+        delays = append(delays, delay)
+        return ctx.Err()
+    }
+    return &delays
+}
+
+func TestParseRetryAfter(t *testing.T) {
+    now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+    cases := map[string]time.Duration{
+        "":                              0,
+        "7":                             7 * time.Second,
+        "-1":                            0,
+        "soon":                          0,
+        "Sat, 17 Oct 2026 12:00:30 GMT": 30 * time.Second,
+        "Sat, 17 Oct 2026 11:00:00 GMT": 0,
+    }
+    for value, expected := range cases {
+        if got := parseRetryAfter(value, now); got != expected {
+            t.Errorf("parseRetryAfter(%q) = %s, expected %s", value, got, expected)
+        }
+    }
+}
+
+func TestResilientProviderRetries(t *testing.T) {
+    policy := RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+    request := CompletionRequest{Model: "llama3.1", UserPrompt: "audit"}
+
+    server, requests := scriptedServer(t, http.StatusTooManyRequests, http.StatusBadGateway)
+    provider := NewResilientProvider(NewLocalProvider(server.URL, nil), policy, NewCircuitBreaker(3, time.Minute))
+    delays := recordSleeps(provider)
+    content, err := provider.Complete(context.Background(), request)
+    if err != nil || content != "ok" {
+        t.Fatalf("Expected success after transient errors, got %q (%v)", content, err)
+    }
+    if *requests != 3 || len(*delays) != 2 {
+        t.Fatalf("Expected 3 requests and 2 waits, got %d requests and %v", *requests, *delays)
+    }
+    if (*delays)[0] != 2*time.Second {
+        t.Errorf("Expected Retry-After to be honoured, waited %s", (*delays)[0])
+    }
+    if backoff := (*delays)[1]; backoff < 100*time.Millisecond || backoff > 200*time.Millisecond {
+        t.Errorf("Expected jittered exponential backoff for the second retry, waited %s", backoff)
+    }
+
+    server, requests = scriptedServer(t, http.StatusBadRequest)
+    provider = NewResilientProvider(NewLocalProvider(server.URL, nil), policy, NewCircuitBreaker(1, time.Minute))
+    recordSleeps(provider)
+    var statusErr *HTTPStatusError
+    if _, err := provider.Complete(context.Background(), request); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+        t.Errorf("Expected the 400 to be returned, got %v", err)
+    }
+    if *requests != 1 || provider.Breaker.State() != BreakerClosed {
+        t.Errorf("Expected no retry and a closed breaker for a permanent error, got %d requests (%s)", *requests, provider.Breaker.State())
+    }
+
+    server, requests = scriptedServer(t, http.StatusTooManyRequests)
+    provider = NewResilientProvider(NewLocalProvider(server.URL, nil), RetryPolicy{MaxAttempts: 3, MaxDelay: time.Second}, NewCircuitBreaker(3, time.Minute))
+    recordSleeps(provider)
+    if _, err := provider.Complete(context.Background(), request); err == nil || *requests != 1 {
+        t.Errorf("Expected a Retry-After above the limit to fail fast, got %d requests (%v)", *requests, err)
+    }
+}
+
+func TestCircuitBreakerSkipsProvider(t *testing.T) {
+    now := time.Now()
+    breaker := NewCircuitBreaker(2, time.Minute)
+    breaker.now = func() time.Time { return now }
+
+    down, downRequests := scriptedServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+    flaky := NewResilientProvider(NewLocalProvider(down.URL, []string{"model-a", "model-b", "model-c"}), RetryPolicy{MaxAttempts: 1}, breaker)
+    up, _ := scriptedServer(t)
+    providers := []LLMProvider{flaky, NewLocalProvider(up.URL, []string{"backup"})}
+
+    // Two failed models open the breaker, so the third is never called
+    if _, _, err := callAIProviders(context.Background(), providers, staticPrompt("audit"), nil); err == nil {
+        t.Fatal("Expected the non-JSON backup answer to fail validation")
+    }
+    if *downRequests != 2 || breaker.State() != BreakerOpen {
+        t.Fatalf("Expected the breaker to open after 2 failures, got %d requests (%s)", *downRequests, breaker.State())
+    }
+
+    if _, err := flaky.Complete(context.Background(), CompletionRequest{Model: "model-a"}); !errors.Is(err, ErrCircuitOpen) || *downRequests != 2 {
+        t.Errorf("Expected calls to be skipped while open, got %v after %d requests", err, *downRequests)
+    }
+
+    // After the cool-off a single probe goes through; it fails and reopens the breaker
+    now = now.Add(2 * time.Minute)
+    if _, err := flaky.Complete(context.Background(), CompletionRequest{Model: "model-a"}); errors.Is(err, ErrCircuitOpen) || *downRequests != 3 {
+        t.Fatalf("Expected a probe call after the cool-off, got %v", err)
+    }
+    if breaker.State() != BreakerOpen {
+        t.Errorf("Expected a failed probe to reopen the breaker, got %s", breaker.State())
+    }
+
+    // The server has recovered by the next probe
+    now = now.Add(2 * time.Minute)
+    if content, err := flaky.Complete(context.Background(), CompletionRequest{Model: "model-a"}); err != nil || content != "ok" {
+        t.Fatalf("Expected the probe to succeed, got %q (%v)", content, err)
+    }
+    if breaker.State() != BreakerClosed {
+        t.Errorf("Expected a successful probe to close the breaker, got %s", breaker.State())
+    }
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+    "context"
+    "errors"
     "fmt"
     "net/http"
     "os/exec"
@@ -9,7 +11,8 @@ import (
 )
 
 type ManualAnalysisRequest struct {
-    RepoURL string `json:"repo_url" binding:"required"`
+    RepoURL string              `json:"repo_url" binding:"required"`
+    History *HistoryScanOptions `json:"history,omitempty"` // also scan past commits for secrets
 }
 
 type ManualAnalysisResponse struct {
@@ -24,103 +27,124 @@ func HandleManualAnalysis(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
         return
     }
+    history := historyScan
+    if req.History != nil {
+        if err := req.History.Validate(); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+            return
+        }
+        history = req.History
+    }
 
-    analysisID := generateAnalysisID()
-    analysisStatus[analysisID] = "processing"
-
-    // Store in analysisStorage for fix handling
-    analysisStorage[analysisID] = &Analysis{
-        ID:       analysisID,
-        RepoURL:  req.RepoURL,
-        RepoName: extractRepoName(req.RepoURL),
+    // Persist the pending scan so status and fix handling can find it
+    record, err := analysisService.Create(TriggerManual, req.RepoURL, nil)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store analysis: " + err.Error()})
+        return
     }
+    analysisID := record.ID
 
-    // Start analysis in background
-    go processManualAnalysis(analysisID, req.RepoURL)
+    // Queue the analysis - a newer request for the same repo supersedes a queued one
+    repoURL := req.RepoURL
+    analysisService.Enqueue(&ScanJob{
+        AnalysisID: analysisID,
+        Key:        repoURL,
+        Process: func(ctx context.Context, analysisID string) error {
+            return processManualAnalysis(ctx, analysisID, repoURL, history)
+        },
+    })
 
     c.JSON(http.StatusAccepted, ManualAnalysisResponse{
         AnalysisID: analysisID,
-        Status:     "processing",
-        Message:    "Analysis started in background",
+        Status:     StatusQueued,
+        Message:    fmt.Sprintf("Analysis queued (position %d)", analysisService.QueuePosition(analysisID)),
     })
 }
 
-func processManualAnalysis(analysisID string, repoURL string) {
+func processManualAnalysis(ctx context.Context, analysisID string, repoURL string, history *HistoryScanOptions) error {
     fmt.Printf("🔍 [MANUAL] Starting analysis for repo: %s\n", repoURL)
     
     repoPath := "/tmp/repo_manual_scan_" + analysisID
     exec.Command("rm", "-rf", repoPath).Run()
+    defer exec.Command("rm", "-rf", repoPath).Run()
 
     // Clone repository
-    cloneCmd := exec.Command("git", "clone", "--depth", "1", repoURL, repoPath)
+    analysisService.SetStatus(analysisID, StatusCloning)
+    cloneArgs := append([]string{"clone"}, history.cloneArgs()...)
+    cloneCmd := exec.CommandContext(ctx, "git", append(cloneArgs, repoURL, repoPath)...)
     if err := cloneCmd.Run(); err != nil {
         fmt.Printf("❌ Clone failed: %v\n", err)
-        analysisStatus[analysisID] = "failed"
-        return
+        return fmt.Errorf("clone failed: %v", err)
     }
 
     fmt.Printf("📁 [ANALYSIS %s] Repository cloned, starting AI analysis...\n", analysisID)
+    analysisService.SetStatus(analysisID, StatusAnalyzing)
     
     // Analyze with AI
-    analysis, err := AnalyzeEntireCodebase(repoPath)
+    analysis, err := AnalyzeEntireCodebase(ctx, repoPath, history)
     if err != nil {
         fmt.Printf("❌ [ANALYSIS %s] AI Analysis failed: %v\n", analysisID, err)
-        analysisStatus[analysisID] = "failed"
-        return
+        return err
+    }
+    if err := ctx.Err(); err != nil {
+        return err
     }
 
-    // Store analysis in both systems
-    analyses[analysisID] = analysis
-    
-    // Update analysisStorage with the full analysis data
-    if storedAnalysis, exists := analysisStorage[analysisID]; exists {
-        storedAnalysis.Risks = append(analysis.CriticalRisks, analysis.HighRisks...)
-        storedAnalysis.AutoFixes = analysis.AutoFixes
-        storedAnalysis.Summary = analysis.Summary
+    if err := analysisService.Complete(analysisID, analysis); err != nil {
+        fmt.Printf("❌ [ANALYSIS %s] Failed to store analysis: %v\n", analysisID, err)
+        return nil
     }
     
-    analysisStatus[analysisID] = "completed"
-    
     fmt.Printf("✅ [ANALYSIS %s] Analysis complete: %d critical risks found\n", analysisID, len(analysis.CriticalRisks))
-    
-    // Cleanup
-    exec.Command("rm", "-rf", repoPath).Run()
+    return nil
 }
 
 func GetAnalysis(c *gin.Context) {
     analysisID := c.Param("id")
     
-    analysis, exists := analyses[analysisID]
-    if !exists {
+    analysis, err := analysisService.Get(analysisID)
+    if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
         return
     }
 
-    if analysisStatus[analysisID] != "completed" {
+    if analysis.Status != StatusCompleted {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Analysis not completed"})
         return
     }
 
+    // Records stored before scoring was kept come back in model order
+    sortRisks(&analysis.AIAnalysisResponse)
     c.JSON(http.StatusOK, analysis)
 }
 
 func GetAnalysisStatus(c *gin.Context) {
     analysisID := c.Param("id")
     
-    status, exists := analysisStatus[analysisID]
-    if !exists {
+    analysis, err := analysisService.Get(analysisID)
+    if err != nil {
         c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
         return
     }
 
-    c.JSON(http.StatusOK, gin.H{"status": status})
+    c.JSON(http.StatusOK, gin.H{
+        "status":         analysis.Status,
+        "queue_position": analysisService.QueuePosition(analysisID),
+        "error":          analysis.Error,
+    })
 }
 
 func GetAllAnalyses(c *gin.Context) {
+    stored, err := analysisService.List()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list analyses: " + err.Error()})
+        return
+    }
+
     // Return all completed analyses for the dashboard
-    var completedAnalyses []*AIAnalysisResponse
-    for id, analysis := range analyses {
-        if analysisStatus[id] == "completed" {
+    completedAnalyses := []*ScanRecord{}
+    for _, analysis := range stored {
+        if analysis.Status == StatusCompleted {
             completedAnalyses = append(completedAnalyses, analysis)
         }
     }
@@ -168,6 +192,46 @@ func GetAllAnalyses(c *gin.Context) {
     })
 }
 
-func generateAnalysisID() string {
-    return fmt.Sprintf("analysis_%d", len(analyses)+1)
+// CancelAnalysis stops a queued or running scan (DELETE /api/analysis/:id)
+func CancelAnalysis(c *gin.Context) {
+    analysisID := c.Param("id")
+
+    err := analysisService.Cancel(analysisID, "cancelled by user")
+    switch {
+    case errors.Is(err, ErrAnalysisNotFound):
+        c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+        return
+    case errors.Is(err, ErrScanFinished):
+        c.JSON(http.StatusConflict, gin.H{"error": "Analysis already finished"})
+        return
+    case err != nil:
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel analysis: " + err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{"status": StatusCancelled})
+}
+
+// GetQueueStats reports scan queue depth and worker usage
+func GetQueueStats(c *gin.Context) {
+    c.JSON(http.StatusOK, analysisService.QueueStats())
+}
+
+// GetCacheStats reports finding cache size and hit/miss counts
+func GetCacheStats(c *gin.Context) {
+    c.JSON(http.StatusOK, findingCache.Stats())
+}
+
+// InvalidateCache drops cached findings from older prompt versions, or
+// everything with ?all=true
+func InvalidateCache(c *gin.Context) {
+    all, _ := strconv.ParseBool(c.Query("all"))
+    removed, err := findingCache.Invalidate(all)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate cache: " + err.Error()})
+        return
+    }
+
+    fmt.Printf("🧹 Invalidated %d cached findings (all=%v)\n", removed, all)
+    c.JSON(http.StatusOK, gin.H{"removed": removed, "stats": findingCache.Stats()})
 }
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// ModelSpec describes a model's limits and price so prompts can be sized for it
+type ModelSpec struct {
+    Name            string  `json:"name"`
+    ContextWindow   int     `json:"context_window"`    // prompt + completion tokens
+    MaxOutputTokens int     `json:"max_output_tokens"` // requested completion size
+    InputCostPerM   float64 `json:"input_cost_per_m"`  // USD per million prompt tokens
+    OutputCostPerM  float64 `json:"output_cost_per_m"` // USD per million completion tokens
+}
+
+// Conservative limits for models we know nothing about
+var defaultModelSpec = ModelSpec{ContextWindow: 8192, MaxOutputTokens: 2048}
+
+var (
+    modelRegistryMu sync.RWMutex
+    modelRegistry   = map[string]ModelSpec{
+        // Groq
+        "llama-3.1-70b-versatile": {ContextWindow: 131072, MaxOutputTokens: 8000, InputCostPerM: 0.59, OutputCostPerM: 0.79},
+        "llama-3.1-8b-instant":    {ContextWindow: 131072, MaxOutputTokens: 8000, InputCostPerM: 0.05, OutputCostPerM: 0.08},
+        "mixtral-8x7b-32768":      {ContextWindow: 32768, MaxOutputTokens: 8000, InputCostPerM: 0.24, OutputCostPerM: 0.24},
+
+        // OpenRouter
+        "meta-llama/llama-3.1-70b-instruct": {ContextWindow: 131072, MaxOutputTokens: 8000, InputCostPerM: 0.52, OutputCostPerM: 0.75},
+        "mistralai/mixtral-8x7b-instruct":   {ContextWindow: 32768, MaxOutputTokens: 8000, InputCostPerM: 0.54, OutputCostPerM: 0.54},
+
+        // Local defaults (Ollama) - override with AEGIS_MODEL_SPECS if num_ctx is raised
+        "llama3.1": {ContextWindow: 8192, MaxOutputTokens: 2048},
+
+        // Offline fixtures
+        "mock": {ContextWindow: 32768, MaxOutputTokens: 4096},
+    }
+)
+
+// LookupModel returns the registered spec for a model, or conservative defaults
+func LookupModel(name string) ModelSpec {
+    modelRegistryMu.RLock()
+    spec, exists := modelRegistry[name]
+    modelRegistryMu.RUnlock()
+
+    if !exists {
+        spec = defaultModelSpec
+    }
+    spec.Name = name
+    return spec
+}
+
+// RegisterModel adds or replaces a model's spec
+func RegisterModel(spec ModelSpec) {
+    modelRegistryMu.Lock()
+    defer modelRegistryMu.Unlock()
+    modelRegistry[spec.Name] = spec
+}
+
+// LoadModelSpecsFromEnv registers specs from AEGIS_MODEL_SPECS, formatted as
+// "model=context_window:max_output,other-model=32768:4096"
+func LoadModelSpecsFromEnv() error {
+    for _, entry := range splitList(os.Getenv("AEGIS_MODEL_SPECS")) {
+        name, limits, found := strings.Cut(entry, "=")
+        contextWindow, maxOutput, valid := strings.Cut(limits, ":")
+        if !found || !valid {
+            return fmt.Errorf("invalid model spec %q, expected model=context_window:max_output", entry)
+        }
+
+        spec := LookupModel(strings.TrimSpace(name))
+        var err error
+        if spec.ContextWindow, err = strconv.Atoi(contextWindow); err != nil {
+            return fmt.Errorf("invalid context window in %q: %v", entry, err)
+        }
+        if spec.MaxOutputTokens, err = strconv.Atoi(maxOutput); err != nil {
+            return fmt.Errorf("invalid max output in %q: %v", entry, err)
+        }
+        if spec.MaxOutputTokens >= spec.ContextWindow {
+            return fmt.Errorf("max output must be smaller than the context window in %q", entry)
+        }
+        RegisterModel(spec)
+    }
+    return nil
+}
+
+// PromptBudget is how many prompt tokens fit next to the completion,
+// keeping a 10% margin because token counts are estimated
+func (m ModelSpec) PromptBudget() int {
+    budget := m.ContextWindow - m.MaxOutputTokens
+    return budget - budget/10
+}
+
+// EstimateCost returns the USD cost of a call with the given token counts
+func (m ModelSpec) EstimateCost(promptTokens int, outputTokens int) float64 {
+    return (float64(promptTokens)*m.InputCostPerM + float64(outputTokens)*m.OutputCostPerM) / 1e6
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+    "context"
+    "fmt"
+    "testing"
+)
+
+func TestLookupModel(t *testing.T) {
+    if spec := LookupModel("mixtral-8x7b-32768"); spec.ContextWindow != 32768 || spec.Name != "mixtral-8x7b-32768" {
+        t.Errorf("Unexpected registered spec: %+v", spec)
+    }
+    if spec := LookupModel("some-new-model"); spec.ContextWindow != defaultModelSpec.ContextWindow {
+        t.Errorf("Expected conservative defaults for unknown models, got %+v", spec)
+    }
+    if LookupModel("mixtral-8x7b-32768").PromptBudget() >= LookupModel("llama-3.1-70b-versatile").PromptBudget() {
+        t.Error("Expected the 32k model to have a smaller prompt budget")
+    }
+}
+
+func TestLoadModelSpecsFromEnv(t *testing.T) {
+    t.Setenv("AEGIS_MODEL_SPECS", "qwen2.5-coder=32768:4096")
+    if err := LoadModelSpecsFromEnv(); err != nil {
+        t.Fatalf("LoadModelSpecsFromEnv failed: %v", err)
+    }
+    if spec := LookupModel("qwen2.5-coder"); spec.ContextWindow != 32768 || spec.MaxOutputTokens != 4096 {
+        t.Errorf("Spec not registered: %+v", spec)
+    }
+
+    t.Setenv("AEGIS_MODEL_SPECS", "broken=4096")
+    if err := LoadModelSpecsFromEnv(); err == nil {
+        t.Error("Expected an error for a malformed spec")
+    }
+}
+
+// recordingProvider fails the models listed in failing and records each prompt it receives
+type recordingProvider struct {
+    models  []string
+    failing map[string]bool
+    prompts map[string]string
+}
+
+func (p *recordingProvider) Name() string         { return "recording" }
+func (p *recordingProvider) ModelNames() []string { return p.models }
+
+func (p *recordingProvider) Complete(ctx context.Context, request CompletionRequest) (string, error) {
+    p.prompts[request.Model] = request.UserPrompt
+    if p.failing[request.Model] {
+        return "", fmt.Errorf("model overloaded")
+    }
+    return `{"high_risks": []}`, nil
+}
+
+func TestPromptDegradesForSmallerFallbackModel(t *testing.T) {
+    RegisterModel(ModelSpec{Name: "test-large", ContextWindow: 32768, MaxOutputTokens: 4096})
+    RegisterModel(ModelSpec{Name: "test-small", ContextWindow: 6144, MaxOutputTokens: 2048})
+
+    codebase := map[string]string{
+        "auth/login.go": numberedLines(300),
+        "src/report.go": numberedLines(300),
+        "src/util.go":   numberedLines(300),
+    }
+    provider := &recordingProvider{
+        models:  []string{"test-large", "test-small"},
+        failing: map[string]bool{"test-large": true},
+        prompts: make(map[string]string),
+    }
+    analysisContext := AnalysisContext{BusinessType: "technology"}
+
+    budget := batchTokenBudget(primaryModelSpec([]LLMProvider{provider}), analysisContext)
+    batches := planAnalysisBatches(codebase, budget, 10)
+    if len(batches) != 1 {
+        t.Fatalf("Expected the large model to take the codebase in one batch, got %d", len(batches))
+    }
+    useFindingCache(t)
+
+    _, outcomes, err := analyzeBatches(context.Background(), []LLMProvider{provider}, batches, analysisContext, codebaseFiles(codebase))
+    if err != nil {
+        t.Fatalf("analyzeBatches failed: %v", err)
+    }
+    if estimateTokens(provider.prompts["test-small"]) > LookupModel("test-small").PromptBudget() {
+        t.Errorf("Fallback prompt (~%d tokens) exceeds the small model's budget", estimateTokens(provider.prompts["test-small"]))
+    }
+    if len(provider.prompts["test-small"]) >= len(provider.prompts["test-large"]) {
+        t.Error("Expected a smaller prompt for the fallback model")
+    }
+
+    coverage := buildCoverageReport(codebase, nil, batches, outcomes)
+    statuses := make(map[string]FileCoverage)
+    for _, file := range coverage.Files {
+        statuses[file.Path] = file
+    }
+    if statuses["auth/login.go"].Status != CoverageFull {
+        t.Errorf("Expected the priority file to be kept whole, got %+v", statuses["auth/login.go"])
+    }
+    if coverage.FullyScanned == coverage.TotalFiles {
+        t.Errorf("Expected the small model to degrade coverage, got %+v", coverage)
+    }
+    for _, file := range coverage.Files {
+        if file.Status != CoverageFull && file.Reason != "model context too small" {
+            t.Errorf("Unexpected reason for %s: %q", file.Path, file.Reason)
+        }
+    }
+}
@@ -0,0 +1,588 @@
+package handlers
+
+import (
+    "archive/zip"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/fs"
+    "math"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// OSVAdvisory is the subset of the OSV schema (https://ossf.github.io/osv-schema/) we match on
+type OSVAdvisory struct {
+    ID        string        `json:"id"`
+    Aliases   []string      `json:"aliases"`
+    Summary   string        `json:"summary"`
+    Details   string        `json:"details"`
+    Withdrawn string        `json:"withdrawn"`
+    Affected  []OSVAffected `json:"affected"`
+    Severity  []struct {
+        Type  string `json:"type"`
+        Score string `json:"score"`
+    } `json:"severity"`
+    DatabaseSpecific struct {
+        Severity string `json:"severity"`
+    } `json:"database_specific"`
+}
+
+type OSVAffected struct {
+    Package struct {
+        Ecosystem string `json:"ecosystem"`
+        Name      string `json:"name"`
+    } `json:"package"`
+    Ranges   []OSVRange `json:"ranges"`
+    Versions []string   `json:"versions"`
+}
+
+type OSVRange struct {
+    Type   string `json:"type"`
+    Events []struct {
+        Introduced   string `json:"introduced"`
+        Fixed        string `json:"fixed"`
+        LastAffected string `json:"last_affected"`
+    } `json:"events"`
+}
+
+// DependencyAdvisory is attached to dependency findings so reports can show
+// the advisory, its CVEs and the versions that fix it
+type DependencyAdvisory struct {
+    ID         string   `json:"id"`
+    CVEs       []string `json:"cves,omitempty"`
+    Ecosystem  string   `json:"ecosystem"`
+    Package    string   `json:"package"`
+    Version    string   `json:"version"`
+    Affected   string   `json:"affected"`
+    Fixed      []string `json:"fixed,omitempty"`
+    CVSSVector string   `json:"cvss_vector,omitempty"` // empty when the score came from a severity label
+
+    source *OSVAdvisory
+}
+
+// AdvisoryDatabase is an offline OSV advisory set indexed by package
+type AdvisoryDatabase struct {
+    Source     string
+    Advisories int
+
+    byPackage map[string][]*OSVAdvisory
+}
+
+func NewAdvisoryDatabase() *AdvisoryDatabase {
+    return &AdvisoryDatabase{byPackage: make(map[string][]*OSVAdvisory)}
+}
+
+var advisoryDatabase *AdvisoryDatabase
+
+// SetAdvisoryDatabase sets the database dependencies are checked against;
+// nil only inventories dependencies. It must be called before the server starts.
+func SetAdvisoryDatabase(database *AdvisoryDatabase) {
+    advisoryDatabase = database
+}
+
+// LoadAdvisoryDatabaseFromEnv loads AEGIS_OSV_DB. Returns nil when it's unset.
+func LoadAdvisoryDatabaseFromEnv() (*AdvisoryDatabase, error) {
+    path := os.Getenv("AEGIS_OSV_DB")
+    if path == "" {
+        return nil, nil
+    }
+    return LoadAdvisoryDatabase(path)
+}
+
+// LoadAdvisoryDatabase imports OSV advisories from a directory of .json files
+// and OSV export archives (e.g. the per-ecosystem all.zip downloads), or from
+// a single such file
+func LoadAdvisoryDatabase(path string) (*AdvisoryDatabase, error) {
+    database := NewAdvisoryDatabase()
+    database.Source = path
+
+    err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+        if err != nil {
+            return err
+        }
+        switch {
+        case entry.IsDir():
+            return nil
+        case strings.HasSuffix(file, ".zip"):
+            return database.importArchive(file)
+        case strings.HasSuffix(file, ".json"):
+            data, err := os.ReadFile(file)
+            if err != nil {
+                return err
+            }
+            return database.importJSON(file, data)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return database, nil
+}
+
+func (db *AdvisoryDatabase) importArchive(file string) error {
+    archive, err := zip.OpenReader(file)
+    if err != nil {
+        return fmt.Errorf("failed to open %s: %v", file, err)
+    }
+    defer archive.Close()
+
+    for _, entry := range archive.File {
+        if !strings.HasSuffix(entry.Name, ".json") {
+            continue
+        }
+        reader, err := entry.Open()
+        if err != nil {
+            return fmt.Errorf("failed to read %s in %s: %v", entry.Name, file, err)
+        }
+        data, err := io.ReadAll(reader)
+        reader.Close()
+        if err != nil {
+            return fmt.Errorf("failed to read %s in %s: %v", entry.Name, file, err)
+        }
+        if err := db.importJSON(file+":"+entry.Name, data); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// importJSON accepts a single advisory or an array of them
+func (db *AdvisoryDatabase) importJSON(name string, data []byte) error {
+    var advisories []OSVAdvisory
+    if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+        if err := json.Unmarshal(data, &advisories); err != nil {
+            return fmt.Errorf("invalid advisories in %s: %v", name, err)
+        }
+    } else {
+        var advisory OSVAdvisory
+        if err := json.Unmarshal(data, &advisory); err != nil {
+            return fmt.Errorf("invalid advisory in %s: %v", name, err)
+        }
+        advisories = append(advisories, advisory)
+    }
+    for i := range advisories {
+        db.Add(&advisories[i])
+    }
+    return nil
+}
+
+// Add indexes an advisory under every package it affects. Withdrawn advisories are ignored.
+func (db *AdvisoryDatabase) Add(advisory *OSVAdvisory) {
+    if advisory.ID == "" || advisory.Withdrawn != "" {
+        return
+    }
+    db.Advisories++
+    seen := make(map[string]bool)
+    for _, affected := range advisory.Affected {
+        key := packageKey(affected.Package.Ecosystem, affected.Package.Name)
+        if !seen[key] {
+            seen[key] = true
+            db.byPackage[key] = append(db.byPackage[key], advisory)
+        }
+    }
+}
+
+var pypiNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// packageKey normalizes names the way each registry compares them
+func packageKey(ecosystem string, name string) string {
+    switch ecosystem {
+    case EcosystemPyPI:
+        name = pypiNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+    case EcosystemNPM, EcosystemRubyGems:
+        name = strings.ToLower(name)
+    }
+    return ecosystem + "\x00" + name
+}
+
+// Lookup returns the advisories affecting one dependency version
+func (db *AdvisoryDatabase) Lookup(dependency Dependency) []DependencyAdvisory {
+    if db == nil {
+        return nil
+    }
+    key := packageKey(dependency.Ecosystem, dependency.Name)
+
+    var matches []DependencyAdvisory
+    for _, advisory := range db.byPackage[key] {
+        for _, affected := range advisory.Affected {
+            if packageKey(affected.Package.Ecosystem, affected.Package.Name) != key || !affected.contains(dependency.Version) {
+                continue
+            }
+            matches = append(matches, DependencyAdvisory{
+                ID:         advisory.ID,
+                CVEs:       advisory.cves(),
+                Ecosystem:  dependency.Ecosystem,
+                Package:    dependency.Name,
+                Version:    dependency.Version,
+                Affected:   affected.describe(),
+                Fixed:      affected.fixedVersions(),
+                CVSSVector: advisory.cvssVector(),
+                source:     advisory,
+            })
+            break
+        }
+    }
+    return matches
+}
+
+func (advisory *OSVAdvisory) cves() []string {
+    var cves []string
+    for _, id := range append([]string{advisory.ID}, advisory.Aliases...) {
+        if strings.HasPrefix(id, "CVE-") {
+            cves = append(cves, id)
+        }
+    }
+    return cves
+}
+
+// contains reports whether version is listed or inside one of the ranges
+func (affected OSVAffected) contains(version string) bool {
+    for _, listed := range affected.Versions {
+        if compareVersions(listed, version) == 0 {
+            return true
+        }
+    }
+    for _, r := range affected.Ranges {
+        if r.Type != "GIT" && r.contains(version) {
+            return true
+        }
+    }
+    return false
+}
+
+// contains walks the range events in order: introduced opens an affected
+// span, fixed (exclusive) and last_affected (inclusive) close it
+func (r OSVRange) contains(version string) bool {
+    affected := false
+    for _, event := range r.Events {
+        switch {
+        case event.Introduced != "":
+            if event.Introduced == "0" || compareVersions(version, event.Introduced) >= 0 {
+                affected = true
+            }
+        case event.Fixed != "":
+            if compareVersions(version, event.Fixed) >= 0 {
+                affected = false
+            }
+        case event.LastAffected != "":
+            if compareVersions(version, event.LastAffected) > 0 {
+                affected = false
+            }
+        }
+    }
+    return affected
+}
+
+// describe renders the affected ranges, e.g. ">=1.0.0, <1.2.5 || >=2.0.0, <2.1.1"
+func (affected OSVAffected) describe() string {
+    var spans []string
+    for _, r := range affected.Ranges {
+        if r.Type == "GIT" {
+            continue
+        }
+        span := ""
+        for _, event := range r.Events {
+            switch {
+            case event.Introduced != "":
+                if span != "" {
+                    spans = append(spans, span)
+                }
+                span = ">=" + event.Introduced
+            case event.Fixed != "":
+                span += ", <" + event.Fixed
+                spans, span = append(spans, span), ""
+            case event.LastAffected != "":
+                span += ", <=" + event.LastAffected
+                spans, span = append(spans, span), ""
+            }
+        }
+        if span != "" {
+            spans = append(spans, span)
+        }
+    }
+    if len(spans) == 0 && len(affected.Versions) > 0 {
+        return strings.Join(affected.Versions, ", ")
+    }
+    return strings.ReplaceAll(strings.Join(spans, " || "), ">=0, ", "")
+}
+
+func (affected OSVAffected) fixedVersions() []string {
+    var fixed []string
+    for _, r := range affected.Ranges {
+        for _, event := range r.Events {
+            if event.Fixed != "" && r.Type != "GIT" {
+                fixed = append(fixed, event.Fixed)
+            }
+        }
+    }
+    sort.Slice(fixed, func(i, j int) bool { return compareVersions(fixed[i], fixed[j]) < 0 })
+    return fixed
+}
+
+// CVSSScore is the highest CVSS v3 base score in the advisory, falling back
+// to the database's severity label
+func (advisory *OSVAdvisory) CVSSScore() float64 {
+    if best, vector := advisory.highestCVSS(); vector != "" {
+        return best
+    }
+    switch strings.ToUpper(advisory.DatabaseSpecific.Severity) {
+    case "CRITICAL":
+        return 9.0
+    case "HIGH":
+        return 7.5
+    case "LOW":
+        return 3.0
+    }
+    return 5.5
+}
+
+// cvssVector is the vector behind CVSSScore, or "" when the score is a fallback
+func (advisory *OSVAdvisory) cvssVector() string {
+    _, vector := advisory.highestCVSS()
+    return vector
+}
+
+func (advisory *OSVAdvisory) highestCVSS() (float64, string) {
+    best, bestVector := 0.0, ""
+    for _, severity := range advisory.Severity {
+        if score, ok := cvss3BaseScore(severity.Score); ok && score > best {
+            best, bestVector = score, severity.Score
+        }
+    }
+    return best, bestVector
+}
+
+// cvss3BaseScore computes the base score of a CVSS:3.x vector string
+func cvss3BaseScore(vector string) (float64, bool) {
+    if !strings.HasPrefix(vector, "CVSS:3.") {
+        return 0, false
+    }
+    metrics := make(map[string]string)
+    for _, part := range strings.Split(vector, "/")[1:] {
+        if key, value, found := strings.Cut(part, ":"); found {
+            metrics[key] = value
+        }
+    }
+
+    weights := map[string]map[string]float64{
+        "AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+        "AC": {"L": 0.77, "H": 0.44},
+        "UI": {"N": 0.85, "R": 0.62},
+        "C":  {"H": 0.56, "L": 0.22, "N": 0},
+        "I":  {"H": 0.56, "L": 0.22, "N": 0},
+        "A":  {"H": 0.56, "L": 0.22, "N": 0},
+    }
+    values := make(map[string]float64)
+    for metric, options := range weights {
+        value, ok := options[metrics[metric]]
+        if !ok {
+            return 0, false
+        }
+        values[metric] = value
+    }
+    scopeChanged := metrics["S"] == "C"
+    privileges := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+    if scopeChanged {
+        privileges["L"], privileges["H"] = 0.68, 0.5
+    }
+    pr, ok := privileges[metrics["PR"]]
+    if !ok || (metrics["S"] != "U" && !scopeChanged) {
+        return 0, false
+    }
+
+    iss := 1 - (1-values["C"])*(1-values["I"])*(1-values["A"])
+    impact := 6.42 * iss
+    if scopeChanged {
+        impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+    }
+    if impact <= 0 {
+        return 0, true
+    }
+    exploitability := 8.22 * values["AV"] * values["AC"] * pr * values["UI"]
+    if scopeChanged {
+        return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), true
+    }
+    return cvssRoundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// cvssRoundUp is the CVSS 3.1 Roundup: the smallest one-decimal number >= value
+func cvssRoundUp(value float64) float64 {
+    scaled := int64(math.Round(value * 100000))
+    if scaled%10000 == 0 {
+        return float64(scaled) / 100000
+    }
+    return float64(scaled/10000+1) / 10
+}
+
+var versionTokenPattern = regexp.MustCompile(`[0-9]+|[A-Za-z]+`)
+
+// Qualifiers that mark a release (ignored) or a post-release (sorts after the release)
+var (
+    releaseQualifiers = map[string]bool{"final": true, "ga": true, "release": true, "r": true}
+    postQualifiers    = map[string]bool{"post": true, "sp": true, "pl": true, "patch": true, "p": true}
+)
+
+// compareVersions orders versions across ecosystems: numeric parts compare as
+// numbers, pre-release tags (alpha, beta, rc, SNAPSHOT...) sort before the
+// release and post-release tags after it. Build metadata and a leading "v"
+// are ignored, and missing trailing parts count as zero (1.2 == 1.2.0).
+func compareVersions(a string, b string) int {
+    tokensA, tokensB := versionTokens(a), versionTokens(b)
+    for i := 0; i < len(tokensA) || i < len(tokensB); i++ {
+        var tokenA, tokenB string
+        if i < len(tokensA) {
+            tokenA = tokensA[i]
+        }
+        if i < len(tokensB) {
+            tokenB = tokensB[i]
+        }
+        if result := compareVersionTokens(tokenA, tokenB); result != 0 {
+            return result
+        }
+    }
+    return 0
+}
+
+func versionTokens(version string) []string {
+    version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+    version = strings.SplitN(version, "+", 2)[0]
+    var tokens []string
+    for _, token := range versionTokenPattern.FindAllString(strings.ToLower(version), -1) {
+        if !releaseQualifiers[token] {
+            tokens = append(tokens, token)
+        }
+    }
+    return tokens
+}
+
+// versionTokenRank places a token: pre-release tags, then missing/zero, then
+// numbers and post-release tags
+func versionTokenRank(token string) int {
+    switch {
+    case token == "" || strings.Trim(token, "0") == "" && token[0] == '0':
+        return 1
+    case token[0] >= '0' && token[0] <= '9':
+        return 2
+    case postQualifiers[token]:
+        return 2
+    }
+    return 0
+}
+
+func compareVersionTokens(a string, b string) int {
+    rankA, rankB := versionTokenRank(a), versionTokenRank(b)
+    if rankA != rankB {
+        return rankA - rankB
+    }
+    numericA := a != "" && a[0] >= '0' && a[0] <= '9'
+    numericB := b != "" && b[0] >= '0' && b[0] <= '9'
+    switch {
+    case rankA == 1:
+        return 0
+    case numericA && numericB:
+        a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+        if len(a) != len(b) {
+            return len(a) - len(b)
+        }
+    case numericA != numericB:
+        // A number outranks a post-release tag in the same position (1.0.1 > 1.0.post1)
+        if numericA {
+            return 1
+        }
+        return -1
+    }
+    return strings.Compare(a, b)
+}
+
+// scanDependencies checks each dependency against the advisory database and
+// returns one rule finding per affected dependency and advisory
+func scanDependencies(database *AdvisoryDatabase, repoPath string, dependencies []Dependency) []ruleFinding {
+    manifests := make(map[string]string)
+    var findings []ruleFinding
+    for _, dependency := range dependencies {
+        for _, advisory := range database.Lookup(dependency) {
+            manifest, loaded := manifests[dependency.Manifest]
+            if !loaded {
+                content, _ := os.ReadFile(filepath.Join(repoPath, dependency.Manifest))
+                manifest = string(content)
+                manifests[dependency.Manifest] = manifest
+            }
+            findings = append(findings, newDependencyFinding(dependency, advisory, manifest))
+        }
+    }
+    return findings
+}
+
+// upgradeVersion is the lowest fixed version above the current one - the
+// smallest upgrade that leaves the affected range
+func upgradeVersion(current string, fixed []string) string {
+    for _, version := range fixed {
+        if compareVersions(version, current) > 0 {
+            return version
+        }
+    }
+    if len(fixed) > 0 {
+        return fixed[len(fixed)-1]
+    }
+    return ""
+}
+
+func newDependencyFinding(dependency Dependency, advisory DependencyAdvisory, manifest string) ruleFinding {
+    source := advisory.source
+    cvss := source.CVSSScore()
+
+    tier, priority := TierMedium, "Medium"
+    switch {
+    case cvss >= 9:
+        tier, priority = TierCritical, "Immediate"
+    case cvss >= 7:
+        tier, priority = TierHigh, "High"
+    }
+
+    reference := advisory.ID
+    if len(advisory.CVEs) > 0 {
+        reference = advisory.CVEs[0]
+    }
+    description := source.Summary
+    if description == "" {
+        description = strings.SplitN(strings.TrimSpace(source.Details), "\n", 2)[0]
+    }
+    impact := fmt.Sprintf("%s %s is affected (%s). No fixed version is published yet; consider replacing the package.",
+        dependency.Name, dependency.Version, advisory.Affected)
+    if upgrade := upgradeVersion(dependency.Version, advisory.Fixed); upgrade != "" {
+        impact = fmt.Sprintf("%s %s is affected (%s). Upgrade to %s or later.", dependency.Name, dependency.Version, advisory.Affected, upgrade)
+    }
+
+    snippet := ""
+    if lines := strings.Split(manifest, "\n"); dependency.Line >= 1 && dependency.Line <= len(lines) {
+        snippet = strings.TrimSpace(lines[dependency.Line-1])
+    }
+
+    return ruleFinding{
+        Tier:  tier,
+        Match: dependency.Name + "@" + dependency.Version,
+        Risk: Risk{
+            File:                   dependency.Manifest,
+            FilePath:               dependency.Manifest,
+            Line:                   dependency.Line,
+            LineNumber:             dependency.Line,
+            Title:                  fmt.Sprintf("Vulnerable dependency %s %s (%s)", dependency.Name, dependency.Version, reference),
+            Description:            description,
+            Impact:                 impact,
+            Confidence:             0.9,
+            CodeSnippet:            snippet,
+            CVSSScore:              cvss,
+            ExploitationComplexity: "Medium",
+            RemediationPriority:    priority,
+            Source:                 RiskSourceRule,
+            RuleID:                 advisory.ID,
+            Verified:               true,
+            Advisory:               &advisory,
+        },
+    }
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+    "context"
+    "slices"
+    "strings"
+    "testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+    ordered := [][2]string{
+        {"1.2.3", "1.10.0"},
+        {"v0.9.0", "0.17.0"},
+        {"1.0.0-beta.2", "1.0.0-beta.10"},
+        {"1.0.0-alpha", "1.0.0"},
+        {"2.0-beta9", "2.0"},
+        {"1.0-SNAPSHOT", "1.0"},
+        {"2.2.3", "2.2.3.1"},
+        {"1.0", "1.0.post1"},
+        {"1.0.post1", "1.0.1"},
+    }
+    for _, pair := range ordered {
+        if compareVersions(pair[0], pair[1]) >= 0 || compareVersions(pair[1], pair[0]) <= 0 {
+            t.Errorf("Expected %s < %s", pair[0], pair[1])
+        }
+    }
+    for _, pair := range [][2]string{{"1.2", "1.2.0"}, {"v1.9.1", "1.9.1"}, {"2.15.0.Final", "2.15.0"}, {"1.0.0+build.5", "1.0.0"}} {
+        if compareVersions(pair[0], pair[1]) != 0 {
+            t.Errorf("Expected %s == %s", pair[0], pair[1])
+        }
+    }
+}
+
+func TestCVSS3BaseScore(t *testing.T) {
+    cases := map[string]float64{
+        "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H": 10.0,
+        "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H": 7.5,
+        "CVSS:3.1/AV:N/AC:L/PR:H/UI:N/S:U/C:H/I:H/A:H": 7.2,
+        "CVSS:3.0/AV:L/AC:H/PR:L/UI:R/S:U/C:L/I:N/A:N": 2.2,
+        "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N": 0,
+    }
+    for vector, expected := range cases {
+        if score, ok := cvss3BaseScore(vector); !ok || score != expected {
+            t.Errorf("cvss3BaseScore(%s) = %.1f, expected %.1f", vector, score, expected)
+        }
+    }
+    if _, ok := cvss3BaseScore("CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N"); ok {
+        t.Error("Expected CVSS v4 vectors to be left to the severity label")
+    }
+}
+
+func TestScanDependenciesAgainstOSV(t *testing.T) {
+    database, err := LoadAdvisoryDatabase("testdata/osv")
+    if err != nil {
+        t.Fatalf("Failed to load advisories: %v", err)
+    }
+    if database.Advisories != 5 {
+        t.Errorf("Expected 5 advisories (withdrawn one skipped), got %d", database.Advisories)
+    }
+
+    repo := writeRepo(t, dependencyManifests)
+    findings := scanDependencies(database, repo, collectDependencies(context.Background(), repo))
+
+    byID := make(map[string]ruleFinding)
+    for _, finding := range findings {
+        byID[finding.Risk.RuleID] = finding
+    }
+    expected := map[string]struct {
+        manifest string
+        line     int
+        tier     int
+        fixed    string
+    }{
+        "GHSA-35jh-r3h4-6jhm": {"web/package-lock.json", 8, TierHigh, "4.17.21"},
+        "GO-2023-2102":        {"go.mod", 8, TierHigh, "0.17.0"},
+        "PYSEC-2023-74":       {"requirements.txt", 2, TierMedium, "2.31.0"},
+        "GHSA-jfh8-c2jp-5v3q": {"pom.xml", 9, TierCritical, "2.15.0"},
+        "GHSA-2xjw-9ph6-q6f5": {"Gemfile.lock", 6, TierCritical, "2.2.3.1"},
+    }
+    if len(findings) != len(expected) {
+        t.Errorf("Expected %d vulnerable dependencies, got %d", len(expected), len(findings))
+    }
+    for id, want := range expected {
+        finding, ok := byID[id]
+        if !ok {
+            t.Errorf("Expected a finding for %s", id)
+            continue
+        }
+        risk := finding.Risk
+        if risk.FilePath != want.manifest || risk.LineNumber != want.line || finding.Tier != want.tier {
+            t.Errorf("%s: expected %s:%d (%s), got %s:%d (%s)", id, want.manifest, want.line, tierNames[want.tier],
+                risk.FilePath, risk.LineNumber, tierNames[finding.Tier])
+        }
+        if risk.Advisory == nil || len(risk.Advisory.CVEs) == 0 || !strings.Contains(risk.Title, risk.Advisory.CVEs[0]) {
+            t.Errorf("%s: expected the CVE in the advisory and title, got %q %+v", id, risk.Title, risk.Advisory)
+        }
+        if !strings.Contains(risk.Impact, "Upgrade to "+want.fixed) || risk.Source != RiskSourceRule || risk.CodeSnippet == "" {
+            t.Errorf("%s: expected an upgrade to %s from the manifest line, got %q (%q)", id, want.fixed, risk.Impact, risk.CodeSnippet)
+        }
+    }
+
+    // log4j 2.14.1 sits in the last of three affected ranges
+    if log4j := byID["GHSA-jfh8-c2jp-5v3q"].Risk.Advisory; log4j.Affected != ">=2.0-beta9, <2.3.1 || >=2.4, <2.12.2 || >=2.13.0, <2.15.0" {
+        t.Errorf("Unexpected affected ranges %q", log4j.Affected)
+    }
+    if snippet := byID["GHSA-jfh8-c2jp-5v3q"].Risk.CodeSnippet; snippet != "<version>${log4j.version}</version>" {
+        t.Errorf("Expected the manifest's version line, got %q", snippet)
+    }
+    if vector := byID["GHSA-jfh8-c2jp-5v3q"].Risk.Advisory.CVSSVector; vector != "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H" {
+        t.Errorf("Expected the scored CVSS vector on the advisory, got %q", vector)
+    }
+    if vector := byID["PYSEC-2023-74"].Risk.Advisory.CVSSVector; vector != "" {
+        t.Errorf("Expected no vector for a score taken from the severity label, got %q", vector)
+    }
+
+    // An AI finding on the manifest line keeps the advisory, so the SBOM still lists the CVE
+    response := &AIAnalysisResponse{MediumRisks: []Risk{{FilePath: "web/package-lock.json", LineNumber: 8, Title: "Outdated lodash", CVSSScore: 4, Source: RiskSourceAI}}}
+    mergeRuleFindings(response, findings)
+    var merged Risk
+    for _, risk := range response.HighRisks {
+        if risk.Source == RiskSourceRule && risk.FilePath == "web/package-lock.json" {
+            merged = risk
+        }
+    }
+    if merged.Title != "Outdated lodash" || merged.Advisory == nil || merged.Advisory.ID != "GHSA-35jh-r3h4-6jhm" || merged.CVSSScore == 4 {
+        t.Errorf("Expected the AI finding to carry the lodash advisory and its score, got %+v", merged)
+    }
+    var listed []string
+    for _, vulnerability := range buildCycloneDX(&ScanRecord{AIAnalysisResponse: *response}).Vulnerabilities {
+        listed = append(listed, vulnerability.ID)
+    }
+    if !slices.Contains(listed, "GHSA-35jh-r3h4-6jhm") || len(listed) != len(expected) {
+        t.Errorf("Expected every advisory in the SBOM, got %v", listed)
+    }
+
+    if findings := scanDependencies(nil, repo, collectDependencies(context.Background(), repo)); len(findings) != 0 {
+        t.Errorf("Expected no findings without a database, got %d", len(findings))
+    }
+}
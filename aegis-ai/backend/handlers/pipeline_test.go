@@ -0,0 +1,263 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// newTestGitRepo copies a testdata repo into a fresh git repository
+func newTestGitRepo(t *testing.T, name string) string {
+    t.Helper()
+    if _, err := exec.LookPath("git"); err != nil {
+        t.Skip("git not installed")
+    }
+
+    src := filepath.Join("testdata", "repos", name)
+    dst := filepath.Join(t.TempDir(), name)
+    err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+        if err != nil {
+            return err
+        }
+        rel, _ := filepath.Rel(src, path)
+        if info.IsDir() {
+            return os.MkdirAll(filepath.Join(dst, rel), 0755)
+        }
+        content, err := os.ReadFile(path)
+        if err != nil {
+            return err
+        }
+        return os.WriteFile(filepath.Join(dst, rel), content, 0644)
+    })
+    if err != nil {
+        t.Fatalf("Failed to copy test repo: %v", err)
+    }
+
+    for _, args := range [][]string{
+        {"init", "-q"},
+        {"add", "."},
+        {"-c", "user.name=Aegis Test", "-c", "user.email=test@aegis.local", "commit", "-q", "-m", "initial"},
+    } {
+        cmd := exec.Command("git", args...)
+        cmd.Dir = dst
+        if output, err := cmd.CombinedOutput(); err != nil {
+            t.Fatalf("git %v failed: %v\n%s", args, err, output)
+        }
+    }
+    return dst
+}
+
+// useTestServices swaps in a fresh service and providers for the duration of a test
+func useTestServices(t *testing.T, providers []LLMProvider) *AnalysisService {
+    t.Helper()
+    previousService, previousProviders := analysisService, llmProviders
+    service := NewAnalysisService(NewMemoryAnalysisStore(), 1, time.Minute)
+    SetAnalysisService(service)
+    SetLLMProviders(providers)
+    useFindingCache(t)
+    t.Cleanup(func() {
+        SetAnalysisService(previousService)
+        SetLLMProviders(previousProviders)
+    })
+    return service
+}
+
+func loadFixture(t *testing.T, name string) string {
+    t.Helper()
+    content, err := os.ReadFile(filepath.Join("testdata", "llm_fixtures", name))
+    if err != nil {
+        t.Fatalf("Failed to read fixture: %v", err)
+    }
+    return string(content)
+}
+
+func waitForFinalStatus(t *testing.T, service *AnalysisService, id string) *ScanRecord {
+    t.Helper()
+    deadline := time.Now().Add(30 * time.Second)
+    for {
+        record, err := service.Get(id)
+        if err != nil {
+            t.Fatalf("Get failed: %v", err)
+        }
+        if isFinalStatus(record.Status) {
+            return record
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("Scan %s stuck in %s", id, record.Status)
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestManualScanPipelineWithMockProvider(t *testing.T) {
+    repoPath := newTestGitRepo(t, "vulnerable-app")
+    mock, err := NewMockLLMProviderFromDir(filepath.Join("testdata", "llm_fixtures"), "vulnerable-app")
+    if err != nil {
+        t.Fatalf("Failed to load fixtures: %v", err)
+    }
+    service := useTestServices(t, []LLMProvider{mock})
+    router := newTestRouter()
+
+    body, _ := json.Marshal(ManualAnalysisRequest{RepoURL: repoPath})
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest("POST", "/api/analyze", bytes.NewBuffer(body)))
+    if w.Code != http.StatusAccepted {
+        t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body.String())
+    }
+    var started ManualAnalysisResponse
+    json.Unmarshal(w.Body.Bytes(), &started)
+
+    record := waitForFinalStatus(t, service, started.AnalysisID)
+    if record.Status != StatusCompleted {
+        t.Fatalf("Expected completed scan, got %s: %s", record.Status, record.Error)
+    }
+    if len(mock.Calls()) != 1 {
+        t.Errorf("Expected one provider call, got %d", len(mock.Calls()))
+    }
+
+    w = httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest("GET", "/api/analysis/"+started.AnalysisID, nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+    }
+    var analysis ScanRecord
+    if err := json.Unmarshal(w.Body.Bytes(), &analysis); err != nil {
+        t.Fatalf("Invalid analysis JSON: %v", err)
+    }
+
+    // The AI's SQL injection is confirmed by the taint engine and raised to critical
+    if len(analysis.CriticalRisks) != 2 || analysis.CriticalRisks[0].FilePath != "config.js" ||
+        analysis.CriticalRisks[1].FilePath != "app.js" || analysis.CriticalRisks[1].RuleID != taintSQL || len(analysis.CriticalRisks[1].TaintPath) == 0 {
+        t.Errorf("Unexpected critical risks: %+v", analysis.CriticalRisks)
+    }
+    if critical := analysis.CriticalRisks; len(critical) == 2 && (critical[0].CVSSScore != 9.8 ||
+        critical[0].ExploitationComplexity != "Low" || len(critical[0].ComplianceViolations) != 2) {
+        t.Errorf("Scoring fields not retained: %+v", critical[0])
+    }
+    if len(analysis.HighRisks) != 0 || len(analysis.MediumRisks) != 1 {
+        t.Errorf("Expected all risk tiers kept, got %d high / %d medium", len(analysis.HighRisks), len(analysis.MediumRisks))
+    }
+    if len(analysis.AutoFixes) == 0 {
+        t.Error("Expected auto-fixes for fixture risks")
+    }
+    for _, risk := range append(append(analysis.CriticalRisks, analysis.HighRisks...), analysis.MediumRisks...) {
+        if !risk.Verified || risk.Correction != "" {
+            t.Errorf("Expected fixture risk %q to verify at its reported line, got %+v", risk.Title, risk)
+        }
+    }
+    if analysis.Coverage == nil || analysis.Coverage.FullyScanned != analysis.Coverage.TotalFiles || analysis.Coverage.TotalFiles < 3 {
+        t.Errorf("Expected every fixture file fully scanned, got %+v", analysis.Coverage)
+    }
+    if !activePrompts().IsCurrent(analysis.PromptVersion) {
+        t.Errorf("Expected the analysis to be stamped with the prompt version, got %q", analysis.PromptVersion)
+    }
+    if analysis.Trigger != TriggerManual || analysis.CompletedAt == "" {
+        t.Errorf("Missing scan metadata: trigger=%q completed_at=%q", analysis.Trigger, analysis.CompletedAt)
+    }
+}
+
+func TestWebhookPipelineWithFakeGroqServer(t *testing.T) {
+    repoPath := newTestGitRepo(t, "vulnerable-app")
+    fixture := loadFixture(t, "vulnerable-app.json")
+
+    // The first model is "overloaded" so the provider must fail over to the next one
+    var mu sync.Mutex
+    var models []string
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer gsk_test_key" {
+            http.Error(w, "unexpected request", http.StatusBadRequest)
+            return
+        }
+        var req GroqRequest
+        json.NewDecoder(r.Body).Decode(&req)
+        mu.Lock()
+        models = append(models, req.Model)
+        first := len(models) == 1
+        mu.Unlock()
+
+        if first {
+            http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+            return
+        }
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "choices": []map[string]interface{}{
+                {"message": map[string]string{"content": fixture}},
+            },
+        })
+    }))
+    defer server.Close()
+
+    t.Setenv("GROQ_API_URL", server.URL)
+    service := useTestServices(t, []LLMProvider{NewGroqProvider("gsk_test_key", nil)})
+    router := newTestRouter()
+
+    event := PullRequestEvent{Action: "synchronize", Number: 42}
+    event.PullRequest.HTMLURL = "https://github.com/acme/vulnerable-app/pull/42"
+    event.PullRequest.Head.Ref = "feature/login"
+    event.PullRequest.Head.SHA = "0123456789abcdef"
+    event.Repository.CloneURL = repoPath
+    event.Repository.Name = "vulnerable-app"
+    body, _ := json.Marshal(event)
+
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest("POST", "/webhook", bytes.NewBuffer(body)))
+    if w.Code != http.StatusAccepted {
+        t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body.String())
+    }
+    var accepted struct {
+        AnalysisID string `json:"analysis_id"`
+    }
+    json.Unmarshal(w.Body.Bytes(), &accepted)
+
+    record := waitForFinalStatus(t, service, accepted.AnalysisID)
+    if record.Status != StatusCompleted {
+        t.Fatalf("Expected completed scan, got %s: %s", record.Status, record.Error)
+    }
+    if record.PRNumber != 42 || record.CommitSHA != "0123456789abcdef" || record.Trigger != TriggerWebhook {
+        t.Errorf("PR metadata not recorded: %+v", record)
+    }
+    if len(record.CriticalRisks) != 2 || len(record.HighRisks) != 0 {
+        t.Errorf("Unexpected risks: %d critical, %d high", len(record.CriticalRisks), len(record.HighRisks))
+    }
+    if len(models) != 2 || models[0] == models[1] {
+        t.Errorf("Expected failover across two models, got %v", models)
+    }
+}
+
+func TestUnparseableAIResponseFailsParse(t *testing.T) {
+    repoPath := newTestGitRepo(t, "vulnerable-app")
+    mock := NewMockLLMProvider(nil)
+    mock.Default = "I'm sorry, I can only describe the issues in prose."
+    service := useTestServices(t, []LLMProvider{mock})
+
+    record, err := service.Create(TriggerManual, repoPath, nil)
+    if err != nil {
+        t.Fatalf("Create failed: %v", err)
+    }
+    service.Enqueue(&ScanJob{
+        AnalysisID: record.ID,
+        Process: func(ctx context.Context, analysisID string) error {
+            return processManualAnalysis(ctx, analysisID, repoPath, nil)
+        },
+    })
+
+    record = waitForFinalStatus(t, service, record.ID)
+    if record.Status != StatusFailedParse {
+        t.Fatalf("Expected failed_parse, got %s: %s", record.Status, record.Error)
+    }
+    if len(record.CriticalRisks) != 0 || !strings.Contains(record.Error, "no valid JSON object") {
+        t.Errorf("Expected no fabricated findings and a parse reason, got %d critical, error %q", len(record.CriticalRisks), record.Error)
+    }
+    if len(mock.Calls()) != 1+defaultRepairAttempts {
+        t.Errorf("Expected %d attempts, got %d", 1+defaultRepairAttempts, len(mock.Calls()))
+    }
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+    "crypto/sha256"
+    "embed"
+    "encoding/hex"
+    "fmt"
+    "io/fs"
+    "os"
+    "path"
+    "sort"
+    "strings"
+    "sync"
+    "text/template"
+)
+
+// Prompt templates ship in the binary under prompts/<version>/ and can be
+// overridden with AEGIS_PROMPT_DIR (same layout) and AEGIS_PROMPT_VERSION
+//
+//go:embed prompts
+var embeddedPrompts embed.FS
+
+const defaultPromptVersion = "v1"
+
+// PromptSet is one version of the system persona and audit templates
+type PromptSet struct {
+    Version string // directory name, e.g. "v1"
+    Digest  string // hash of every template source, changes on any edit
+    Source  string // "embedded" or the override directory
+
+    system    string
+    templates *template.Template
+    forced    string // AEGIS_PROMPT_TEMPLATE - always use this audit template
+}
+
+// auditPromptData is what the audit templates render
+type auditPromptData struct {
+    BusinessType  string
+    Requirements  []string
+    Languages     []string
+    Batch         int
+    TotalBatches  int
+    PriorityFiles []promptFile
+    ConfigFiles   []promptFile
+    SourceFiles   []promptFile
+}
+
+type promptFile struct {
+    Path    string
+    Content string
+}
+
+var promptFuncs = template.FuncMap{
+    "join": strings.Join,
+}
+
+// LoadPromptSet parses every *.tmpl file in fsys under version/. It needs a
+// system.tmpl and an audit.tmpl; audit.<name>.tmpl files are optional variants.
+func LoadPromptSet(fsys fs.FS, version string) (*PromptSet, error) {
+    files, err := fs.Glob(fsys, path.Join(version, "*.tmpl"))
+    if err != nil {
+        return nil, err
+    }
+    if len(files) == 0 {
+        return nil, fmt.Errorf("no prompt templates found for version %q", version)
+    }
+    sort.Strings(files)
+
+    digest := sha256.New()
+    templates := template.New(version).Funcs(promptFuncs).Option("missingkey=error")
+    for _, file := range files {
+        content, err := fs.ReadFile(fsys, file)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read prompt template %s: %v", file, err)
+        }
+        digest.Write([]byte(path.Base(file) + "\x00"))
+        digest.Write(content)
+
+        if _, err := templates.New(path.Base(file)).Parse(string(content)); err != nil {
+            return nil, fmt.Errorf("invalid prompt template %s: %v", file, err)
+        }
+    }
+
+    for _, required := range []string{"system.tmpl", "audit.tmpl"} {
+        if templates.Lookup(required) == nil {
+            return nil, fmt.Errorf("prompt version %q is missing %s", version, required)
+        }
+    }
+
+    var system strings.Builder
+    if err := templates.ExecuteTemplate(&system, "system.tmpl", nil); err != nil {
+        return nil, fmt.Errorf("failed to render system prompt: %v", err)
+    }
+
+    return &PromptSet{
+        Version:   version,
+        Digest:    hex.EncodeToString(digest.Sum(nil))[:8],
+        Source:    "embedded",
+        system:    strings.TrimSpace(system.String()),
+        templates: templates,
+    }, nil
+}
+
+// LoadPromptSetFromEnv loads AEGIS_PROMPT_VERSION from AEGIS_PROMPT_DIR, or
+// from the embedded templates when no directory is set
+func LoadPromptSetFromEnv() (*PromptSet, error) {
+    version := strings.TrimSpace(os.Getenv("AEGIS_PROMPT_VERSION"))
+    if version == "" {
+        version = defaultPromptVersion
+    }
+
+    var prompts *PromptSet
+    var err error
+    if dir := strings.TrimSpace(os.Getenv("AEGIS_PROMPT_DIR")); dir != "" {
+        if prompts, err = LoadPromptSet(os.DirFS(dir), version); err != nil {
+            return nil, fmt.Errorf("%s: %v", dir, err)
+        }
+        prompts.Source = dir
+    } else if prompts, err = loadEmbeddedPrompts(version); err != nil {
+        return nil, err
+    }
+
+    if forced := strings.TrimSpace(os.Getenv("AEGIS_PROMPT_TEMPLATE")); forced != "" {
+        if prompts.templates.Lookup(forced) == nil {
+            return nil, fmt.Errorf("AEGIS_PROMPT_TEMPLATE %q not found in prompt version %s", forced, version)
+        }
+        prompts.forced = forced
+    }
+    return prompts, nil
+}
+
+func loadEmbeddedPrompts(version string) (*PromptSet, error) {
+    prompts, err := fs.Sub(embeddedPrompts, "prompts")
+    if err != nil {
+        return nil, err
+    }
+    return LoadPromptSet(prompts, version)
+}
+
+var (
+    promptSetMu     sync.RWMutex
+    activePromptSet *PromptSet
+)
+
+// SetPromptSet overrides the templates used for analysis
+func SetPromptSet(prompts *PromptSet) {
+    promptSetMu.Lock()
+    defer promptSetMu.Unlock()
+    activePromptSet = prompts
+}
+
+// activePrompts returns the configured prompt set, loading the embedded
+// default on first use
+func activePrompts() *PromptSet {
+    promptSetMu.RLock()
+    prompts := activePromptSet
+    promptSetMu.RUnlock()
+    if prompts != nil {
+        return prompts
+    }
+
+    prompts, err := loadEmbeddedPrompts(defaultPromptVersion)
+    if err != nil {
+        // The embedded templates are part of the build - this is a programming error
+        panic(fmt.Sprintf("embedded prompt templates are invalid: %v", err))
+    }
+    SetPromptSet(prompts)
+    return prompts
+}
+
+// ID identifies the template set and its exact content, e.g. "v1-3fa2b1c9"
+func (p *PromptSet) ID() string {
+    return p.Version + "-" + p.Digest
+}
+
+// System is the persona sent as the system prompt
+func (p *PromptSet) System() string {
+    return p.system
+}
+
+// TemplateFor picks the audit template for a scan: AEGIS_PROMPT_TEMPLATE if
+// set, then audit.<business type>.tmpl, then audit.<requirement>.tmpl for each
+// compliance requirement in order, then audit.tmpl
+func (p *PromptSet) TemplateFor(analysisContext AnalysisContext) string {
+    if p.forced != "" {
+        return p.forced
+    }
+
+    candidates := []string{analysisContext.BusinessType}
+    candidates = append(candidates, analysisContext.Requirements...)
+    for _, candidate := range candidates {
+        name := "audit." + strings.ToLower(strings.TrimSpace(candidate)) + ".tmpl"
+        if candidate != "" && p.templates.Lookup(name) != nil {
+            return name
+        }
+    }
+    return "audit.tmpl"
+}
+
+// PromptVersion is stamped on analyses and cache entries: the set ID plus the
+// template chosen for the context, e.g. "v1-3fa2b1c9/audit.fintech.tmpl"
+func (p *PromptSet) PromptVersion(analysisContext AnalysisContext) string {
+    return p.ID() + "/" + p.TemplateFor(analysisContext)
+}
+
+// IsCurrent reports whether a stamped prompt version came from this set
+func (p *PromptSet) IsCurrent(promptVersion string) bool {
+    return strings.HasPrefix(promptVersion, p.ID()+"/")
+}
+
+// RenderAudit renders the audit prompt for a request and its file segments
+func (p *PromptSet) RenderAudit(request AIAnalysisRequest, segments []fileSegment) (string, error) {
+    data := auditPromptData{
+        BusinessType: request.Context.BusinessType,
+        Requirements: request.Context.Requirements,
+        Languages:    request.Context.Languages,
+        Batch:        request.Batch,
+        TotalBatches: request.TotalBatches,
+    }
+
+    // Smart file prioritization - segments arrive already sized for the model
+    for _, segment := range segments {
+        file := promptFile{Path: segment.Path, Content: segmentContent(segment)}
+        if isSecurityCriticalFile(segment.Path) {
+            data.PriorityFiles = append(data.PriorityFiles, file)
+        } else if isConfigFile(segment.Path) {
+            data.ConfigFiles = append(data.ConfigFiles, file)
+        } else {
+            data.SourceFiles = append(data.SourceFiles, file)
+        }
+    }
+
+    name := p.TemplateFor(request.Context)
+    var prompt strings.Builder
+    if err := p.templates.ExecuteTemplate(&prompt, name, data); err != nil {
+        return "", fmt.Errorf("failed to render prompt template %s: %v", name, err)
+    }
+    return prompt.String(), nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestEmbeddedPromptTemplates(t *testing.T) {
+    prompts, err := loadEmbeddedPrompts(defaultPromptVersion)
+    if err != nil {
+        t.Fatalf("Embedded templates failed to load: %v", err)
+    }
+    if !strings.HasPrefix(prompts.System(), "You are a senior security engineer") {
+        t.Errorf("Unexpected system prompt: %q", prompts.System())
+    }
+
+    selections := map[string]AnalysisContext{
+        "audit.fintech.tmpl":    {BusinessType: "fintech", Requirements: []string{"GDPR"}},
+        "audit.healthcare.tmpl": {BusinessType: "healthcare"},
+        "audit.gdpr.tmpl":       {BusinessType: "ecommerce", Requirements: []string{"PCI-DSS", "GDPR"}},
+        "audit.tmpl":            {BusinessType: "technology"},
+    }
+    for expected, analysisContext := range selections {
+        if name := prompts.TemplateFor(analysisContext); name != expected {
+            t.Errorf("Expected %s for %+v, got %s", expected, analysisContext, name)
+        }
+    }
+
+    request := AIAnalysisRequest{
+        Context:      AnalysisContext{BusinessType: "fintech", Requirements: []string{"PCI-DSS"}, Languages: []string{"javascript"}},
+        Batch:        2,
+        TotalBatches: 3,
+    }
+    segments := []fileSegment{
+        {Path: "config.js", StartLine: 1, EndLine: 1, TotalLines: 1, Content: `password = "hunter2"`},
+        {Path: "src/app.js", StartLine: 10, EndLine: 12, TotalLines: 40, Content: "app.get('/')"},
+    }
+    prompt, err := prompts.RenderAudit(request, segments)
+    if err != nil {
+        t.Fatalf("RenderAudit failed: %v", err)
+    }
+    for _, expected := range []string{
+        "FINTECH PRIORITIES",
+        "COMPLIANCE REQUIREMENTS: PCI-DSS",
+        "CODEBASE PART 2 OF 3",
+        "FILE: config.js\npassword = \"hunter2\"",
+        "[lines 10-12 of 40",
+        `"critical_risks": [`,
+    } {
+        if !strings.Contains(prompt, expected) {
+            t.Errorf("Rendered prompt is missing %q", expected)
+        }
+    }
+}
+
+func TestPromptTemplatesFromDirectory(t *testing.T) {
+    dir := t.TempDir()
+    write := func(name, content string) {
+        if err := os.MkdirAll(filepath.Join(dir, "v2"), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(filepath.Join(dir, "v2", name), []byte(content), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    write("system.tmpl", "Be terse.\n")
+    write("audit.tmpl", "Audit {{.BusinessType}}:{{range .SourceFiles}} {{.Path}}{{end}}")
+    write("audit.strict.tmpl", "Strict audit")
+
+    t.Setenv("AEGIS_PROMPT_DIR", dir)
+    t.Setenv("AEGIS_PROMPT_VERSION", "v2")
+    prompts, err := LoadPromptSetFromEnv()
+    if err != nil {
+        t.Fatalf("LoadPromptSetFromEnv failed: %v", err)
+    }
+    if prompts.System() != "Be terse." || prompts.Source != dir || !strings.HasPrefix(prompts.ID(), "v2-") {
+        t.Errorf("Unexpected prompt set: %+v", prompts)
+    }
+    prompt, err := prompts.RenderAudit(AIAnalysisRequest{Context: AnalysisContext{BusinessType: "retail"}},
+        []fileSegment{{Path: "main.go", StartLine: 1, EndLine: 1, TotalLines: 1}})
+    if err != nil || prompt != "Audit retail: main.go" {
+        t.Errorf("Unexpected override render %q (%v)", prompt, err)
+    }
+
+    // Any template edit changes the version, so cached findings from the old text stop matching
+    write("audit.tmpl", "Audit v2 edited")
+    edited, err := LoadPromptSetFromEnv()
+    if err != nil {
+        t.Fatalf("Reload failed: %v", err)
+    }
+    if edited.ID() == prompts.ID() || edited.IsCurrent(prompts.PromptVersion(AnalysisContext{})) {
+        t.Errorf("Expected an edited template to change the prompt version, both are %s", edited.ID())
+    }
+
+    t.Setenv("AEGIS_PROMPT_TEMPLATE", "audit.strict.tmpl")
+    forced, err := LoadPromptSetFromEnv()
+    if err != nil || forced.TemplateFor(AnalysisContext{BusinessType: "fintech"}) != "audit.strict.tmpl" {
+        t.Errorf("Expected AEGIS_PROMPT_TEMPLATE to pick the template (%v)", err)
+    }
+    t.Setenv("AEGIS_PROMPT_TEMPLATE", "audit.missing.tmpl")
+    if _, err := LoadPromptSetFromEnv(); err == nil {
+        t.Error("Expected an unknown AEGIS_PROMPT_TEMPLATE to fail")
+    }
+
+    if err := os.Remove(filepath.Join(dir, "v2", "audit.tmpl")); err != nil {
+        t.Fatal(err)
+    }
+    t.Setenv("AEGIS_PROMPT_TEMPLATE", "")
+    if _, err := LoadPromptSetFromEnv(); err == nil || !strings.Contains(err.Error(), "audit.tmpl") {
+        t.Errorf("Expected a missing audit.tmpl to fail, got %v", err)
+    }
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+    "bytes"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "regexp"
+    "slices"
+    "strings"
+
+    "gopkg.in/yaml.v3"
+)
+
+// Per-repository configuration, read from the root of the clone
+const repoConfigFile = ".aegis.yml"
+
+// Analyzers a repository can switch off under `analyzers:`
+const (
+    AnalyzerAI           = "ai"
+    AnalyzerSecrets      = "secrets"
+    AnalyzerDependencies = "dependencies"
+    AnalyzerIaC          = "iac"
+    AnalyzerGo           = "go"    // type-checked Go AST rules
+    AnalyzerTaint        = "taint" // request input followed to sinks in Go and Express handlers
+)
+
+var repoAnalyzers = []string{AnalyzerAI, AnalyzerSecrets, AnalyzerDependencies, AnalyzerIaC, AnalyzerGo, AnalyzerTaint}
+
+// Business types detectBusinessType can return (and audit templates exist for)
+var businessTypes = []string{"healthcare", "fintech", "ecommerce", "education", "government", "technology"}
+
+// Severity names used by fail_on, in tier order
+var severityNames = []string{"critical", "high", "medium"}
+
+// RepoConfig is a repository's .aegis.yml:
+//
+//	version: 1
+//	paths:
+//	  include: ["src/**"]
+//	  exclude: ["src/generated/"]
+//	analyzers:
+//	  ai: true
+//	  dependencies: false
+//	compliance: [PCI-DSS, GDPR]
+//	business_type: fintech
+//	fail_on:
+//	  critical: 1
+//	  high: 5
+//	suppress: [go-math-rand-secret]
+//
+// Analyzers default to enabled; compliance and business_type replace what the
+// scan would detect from the code.
+type RepoConfig struct {
+    Version      int             `yaml:"version"`
+    Paths        FileFilter      `yaml:"paths"`
+    Analyzers    map[string]bool `yaml:"analyzers"`
+    Compliance   []string        `yaml:"compliance"`
+    BusinessType string          `yaml:"business_type"`
+    FailOn       map[string]int  `yaml:"fail_on"`  // fail once this many risks are at or above the severity
+    Suppress     []string        `yaml:"suppress"` // rule IDs never reported for this repository
+
+    suppressed int
+}
+
+// RepoConfigReport records how a repository's .aegis.yml shaped the analysis
+type RepoConfigReport struct {
+    Path               string   `json:"path"`
+    Errors             []string `json:"errors,omitempty"` // the offending settings are ignored
+    DisabledAnalyzers  []string `json:"disabled_analyzers,omitempty"`
+    SuppressedFindings int      `json:"suppressed_findings"`
+}
+
+// PolicyGate is the verdict against the repository's fail_on thresholds
+type PolicyGate struct {
+    Failed  bool     `json:"failed"`
+    Reasons []string `json:"reasons,omitempty"`
+}
+
+// loadRepoConfig reads .aegis.yml from the repository root. Both results are
+// nil when there is no file; the config is nil when the file can't be used.
+// Only a regular file is read - a symlink could make the report echo a host
+// file back through its validation errors.
+func loadRepoConfig(repoPath string) (*RepoConfig, *RepoConfigReport) {
+    file := filepath.Join(repoPath, repoConfigFile)
+    info, err := os.Lstat(file)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    report := &RepoConfigReport{Path: repoConfigFile}
+    if err == nil && !info.Mode().IsRegular() {
+        report.Errors = []string{"not a regular file (symlinks are not followed), ignored"}
+        return nil, report
+    }
+    var content []byte
+    if err == nil {
+        content, err = os.ReadFile(file)
+    }
+    if err != nil {
+        report.Errors = []string{fmt.Sprintf("cannot read file, ignored: %v", err)}
+        return nil, report
+    }
+
+    config, problems := parseRepoConfig(content)
+    report.Errors = problems
+    if config != nil {
+        report.DisabledAnalyzers = config.disabledAnalyzers()
+    }
+    return config, report
+}
+
+var unknownFieldPattern = regexp.MustCompile(`^(line \d+): field (\S+) not found in type \S+$`)
+
+// parseRepoConfig decodes and validates a config. Invalid settings are
+// reported and dropped; only malformed YAML discards the whole file.
+func parseRepoConfig(content []byte) (*RepoConfig, []string) {
+    config := &RepoConfig{}
+    decoder := yaml.NewDecoder(bytes.NewReader(content))
+    decoder.KnownFields(true)
+
+    var problems []string
+    if err := decoder.Decode(config); err != nil && err != io.EOF {
+        var typeErr *yaml.TypeError
+        if !errors.As(err, &typeErr) {
+            return nil, []string{fmt.Sprintf("invalid YAML, file ignored: %v", strings.TrimPrefix(err.Error(), "yaml: "))}
+        }
+        // Type errors leave the rest of the document decoded
+        for _, message := range typeErr.Errors {
+            problems = append(problems, unknownFieldPattern.ReplaceAllString(message, `$1: unknown field "$2"`))
+        }
+    }
+    return config, append(problems, config.validate()...)
+}
+
+// validate drops invalid settings and describes each one
+func (c *RepoConfig) validate() []string {
+    var problems []string
+    if c.Version != 0 && c.Version != 1 {
+        problems = append(problems, fmt.Sprintf("version: unsupported version %d (expected 1)", c.Version))
+    }
+
+    validGlobs := func(field string, patterns []string) []string {
+        var valid []string
+        for _, pattern := range patterns {
+            if err := validateGlob(pattern); err != nil {
+                problems = append(problems, fmt.Sprintf("%s: %v", field, err))
+                continue
+            }
+            valid = append(valid, pattern)
+        }
+        return valid
+    }
+    c.Paths.Include = validGlobs("paths.include", c.Paths.Include)
+    c.Paths.Exclude = validGlobs("paths.exclude", c.Paths.Exclude)
+
+    for name := range c.Analyzers {
+        if !slices.Contains(repoAnalyzers, name) {
+            problems = append(problems, fmt.Sprintf("analyzers: unknown analyzer %q (expected one of %s)", name, strings.Join(repoAnalyzers, ", ")))
+            delete(c.Analyzers, name)
+        }
+    }
+
+    c.Compliance = splitList(strings.Join(c.Compliance, ","))
+    if c.BusinessType != "" {
+        c.BusinessType = strings.ToLower(strings.TrimSpace(c.BusinessType))
+        if !slices.Contains(businessTypes, c.BusinessType) {
+            problems = append(problems, fmt.Sprintf("business_type: unknown business type %q (expected one of %s)", c.BusinessType, strings.Join(businessTypes, ", ")))
+            c.BusinessType = ""
+        }
+    }
+
+    for severity, count := range c.FailOn {
+        switch {
+        case !slices.Contains(severityNames, severity):
+            problems = append(problems, fmt.Sprintf("fail_on: unknown severity %q (expected one of %s)", severity, strings.Join(severityNames, ", ")))
+            delete(c.FailOn, severity)
+        case count < 1:
+            problems = append(problems, fmt.Sprintf("fail_on.%s: must be at least 1, got %d", severity, count))
+            delete(c.FailOn, severity)
+        }
+    }
+
+    c.Suppress = splitList(strings.Join(c.Suppress, ","))
+    // Map iteration order is random - keep the report stable
+    slices.Sort(problems)
+    return problems
+}
+
+// enabled reports whether an analyzer should run; everything runs without a config
+func (c *RepoConfig) enabled(analyzer string) bool {
+    if c == nil {
+        return true
+    }
+    enabled, set := c.Analyzers[analyzer]
+    return !set || enabled
+}
+
+func (c *RepoConfig) disabledAnalyzers() []string {
+    var disabled []string
+    for _, analyzer := range repoAnalyzers {
+        if !c.enabled(analyzer) {
+            disabled = append(disabled, analyzer)
+        }
+    }
+    return disabled
+}
+
+// overrideContext replaces the detected business type and compliance frameworks
+func (c *RepoConfig) overrideContext(analysisContext *AnalysisContext) {
+    if c == nil {
+        return
+    }
+    if c.BusinessType != "" {
+        analysisContext.BusinessType = c.BusinessType
+    }
+    if len(c.Compliance) > 0 {
+        analysisContext.Requirements = c.Compliance
+    }
+}
+
+// overrideSummary keeps the AI's own guess from replacing the configured values
+func (c *RepoConfig) overrideSummary(summary *AnalysisSummary) {
+    if c == nil {
+        return
+    }
+    if c.BusinessType != "" {
+        summary.BusinessType = c.BusinessType
+    }
+    if len(c.Compliance) > 0 {
+        summary.Compliance = c.Compliance
+    }
+}
+
+// filter drops rule findings the repository suppressed or left out of paths.
+// The dependency and IaC scanners read every manifest in the clone, so their
+// findings are only scoped to paths.include and paths.exclude here.
+func (c *RepoConfig) filter(findings []ruleFinding) []ruleFinding {
+    if c == nil {
+        return findings
+    }
+    var kept []ruleFinding
+    for _, finding := range findings {
+        if c.keeps(finding.Risk) {
+            kept = append(kept, finding)
+        }
+    }
+    return kept
+}
+
+// filterHistory applies the same suppressions and paths to secrets found in past commits
+func (c *RepoConfig) filterHistory(secrets []HistorySecret) []HistorySecret {
+    if c == nil {
+        return secrets
+    }
+    var kept []HistorySecret
+    for _, secret := range secrets {
+        if c.keeps(secret.Risk) {
+            kept = append(kept, secret)
+        }
+    }
+    return kept
+}
+
+// keeps reports whether a rule risk survives suppress and paths, counting suppressions
+func (c *RepoConfig) keeps(risk Risk) bool {
+    file := normalizeRiskPath(risk.FilePath)
+    switch {
+    case slices.Contains(c.Suppress, risk.RuleID):
+        c.suppressed++
+        return false
+    case len(c.Paths.Include) > 0 && !matchAnyGlob(c.Paths.Include, file, false):
+        return false
+    case excludedPath(c.Paths.Exclude, file):
+        return false
+    }
+    return true
+}
+
+// evaluateGate checks the risk counts against fail_on; nil when no thresholds are set
+func (c *RepoConfig) evaluateGate(response *AIAnalysisResponse) *PolicyGate {
+    if c == nil || len(c.FailOn) == 0 {
+        return nil
+    }
+    gate := &PolicyGate{}
+    atOrAbove := 0
+    for tier, risks := range riskTiers(response) {
+        atOrAbove += len(*risks)
+        severity := severityNames[tier]
+        if limit, set := c.FailOn[severity]; set && atOrAbove >= limit {
+            gate.Failed = true
+            gate.Reasons = append(gate.Reasons, fmt.Sprintf("%d risks at %s severity or above (fail_on.%s: %d)", atOrAbove, severity, severity, limit))
+        }
+    }
+    return gate
+}
+
+// aiDisabledCoverage reports every file as skipped when the AI analyzer is off
+func aiDisabledCoverage(codebase map[string]string, skipped []FileCoverage) *CoverageReport {
+    var files []FileCoverage
+    for _, file := range prioritizedFiles(codebase) {
+        files = append(files, FileCoverage{Path: file, Status: CoverageSkipped, Reason: "AI analysis disabled in " + repoConfigFile, TotalLines: countLines(codebase[file])})
+    }
+    return buildCoverageReport(nil, append(files, skipped...), nil, nil)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestParseRepoConfig(t *testing.T) {
+    config, problems := parseRepoConfig([]byte(`
+version: 1
+paths:
+  include: ["src/**", "src/[a-"]
+  exclude: [fixtures/]
+analyzers:
+  ai: false
+  linter: true
+compliance: [PCI-DSS, " GDPR ", ""]
+business_type: Fintech
+fail_on:
+  critical: 1
+  high: 0
+  low: 3
+suppress: [go-math-rand-secret]
+colour: blue
+`))
+    if config == nil {
+        t.Fatalf("Expected a partially valid config, got problems %v", problems)
+    }
+    expected := []string{
+        `line 16: unknown field "colour"`,
+        `analyzers: unknown analyzer "linter" (expected one of ai, secrets, dependencies, iac, go, taint)`,
+        `fail_on.high: must be at least 1, got 0`,
+        `fail_on: unknown severity "low" (expected one of critical, high, medium)`,
+        `paths.include: invalid file pattern "src/[a-"`,
+    }
+    if fmt.Sprint(problems) != fmt.Sprint(expected) {
+        t.Errorf("Unexpected validation errors:\n got      %q\n expected %q", problems, expected)
+    }
+
+    if fmt.Sprint(config.Paths.Include) != "[src/**]" || fmt.Sprint(config.Compliance) != "[PCI-DSS GDPR]" || config.BusinessType != "fintech" {
+        t.Errorf("Expected valid settings to be kept, got %+v", config)
+    }
+    if config.enabled(AnalyzerAI) || !config.enabled(AnalyzerSecrets) || fmt.Sprint(config.disabledAnalyzers()) != "[ai]" {
+        t.Errorf("Expected only the AI analyzer disabled, got %v", config.Analyzers)
+    }
+    if fmt.Sprint(config.FailOn) != "map[critical:1]" {
+        t.Errorf("Expected only the valid threshold kept, got %v", config.FailOn)
+    }
+
+    if config, problems := parseRepoConfig([]byte("paths: [unclosed")); config != nil || len(problems) != 1 || !strings.Contains(problems[0], "file ignored") {
+        t.Errorf("Expected malformed YAML to discard the file, got %+v %v", config, problems)
+    }
+    if config, problems := parseRepoConfig([]byte("business_type: bakery\n")); config == nil || config.BusinessType != "" || len(problems) != 1 {
+        t.Errorf("Expected an unknown business type to be dropped, got %+v %v", config, problems)
+    }
+    if config, problems := parseRepoConfig(nil); config == nil || len(problems) != 0 {
+        t.Errorf("Expected an empty file to be a valid config, got %+v %v", config, problems)
+    }
+}
+
+func TestLoadRepoConfigRefusesSymlink(t *testing.T) {
+    host := writeRepo(t, map[string]string{"secrets.txt": "business_type: db-password-hunter2\n"})
+    repoPath := writeRepo(t, map[string]string{"main.go": "package main\n"})
+    if err := os.Symlink(filepath.Join(host, "secrets.txt"), filepath.Join(repoPath, repoConfigFile)); err != nil {
+        t.Fatal(err)
+    }
+
+    config, report := loadRepoConfig(repoPath)
+    if config != nil || report == nil || len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "not a regular file") {
+        t.Fatalf("Expected the symlinked config to be refused, got %+v %+v", config, report)
+    }
+    if strings.Contains(fmt.Sprint(report.Errors), "hunter2") {
+        t.Errorf("Expected nothing from the link target in the report, got %v", report.Errors)
+    }
+}
+
+func TestRepoConfigGate(t *testing.T) {
+    response := &AIAnalysisResponse{
+        CriticalRisks: []Risk{{Title: "a"}},
+        HighRisks:     []Risk{{Title: "b"}, {Title: "c"}},
+    }
+    var missing *RepoConfig
+    if gate := missing.evaluateGate(response); gate != nil {
+        t.Errorf("Expected no gate without a config, got %+v", gate)
+    }
+
+    config := &RepoConfig{FailOn: map[string]int{"critical": 2, "high": 3, "medium": 10}}
+    gate := config.evaluateGate(response)
+    if gate == nil || !gate.Failed || len(gate.Reasons) != 1 || gate.Reasons[0] != "3 risks at high severity or above (fail_on.high: 3)" {
+        t.Errorf("Expected high-or-above risks to fail the gate, got %+v", gate)
+    }
+    response.Gate = gate
+    if comment := createGitHubComment(response, 1); !strings.Contains(comment, "Policy Check Failed") || !strings.Contains(comment, gate.Reasons[0]) {
+        t.Errorf("Expected the failed policy in the PR comment, got:\n%s", comment)
+    }
+
+    config.FailOn = map[string]int{"critical": 2}
+    if gate := config.evaluateGate(response); gate == nil || gate.Failed {
+        t.Errorf("Expected the gate to pass below the threshold, got %+v", gate)
+    }
+}
+
+func TestRepoConfigFilter(t *testing.T) {
+    var findings []ruleFinding
+    for _, file := range []string{"services/api/Dockerfile", "services/api/go.mod", "services/api/testdata/go.mod", "tools/Dockerfile"} {
+        findings = append(findings, ruleFinding{Risk: Risk{FilePath: file, RuleID: iacLatestTag}})
+    }
+    findings = append(findings, ruleFinding{Risk: Risk{FilePath: "services/api/Dockerfile", RuleID: iacRunAsRoot}})
+
+    config := &RepoConfig{Paths: FileFilter{Include: []string{"services/**"}, Exclude: []string{"testdata/"}}, Suppress: []string{iacRunAsRoot}}
+    var kept []string
+    for _, finding := range config.filter(findings) {
+        kept = append(kept, finding.Risk.FilePath)
+    }
+    if fmt.Sprint(kept) != "[services/api/Dockerfile services/api/go.mod]" || config.suppressed != 1 {
+        t.Errorf("Expected findings scoped to the included, non-excluded paths, got %v (%d suppressed)", kept, config.suppressed)
+    }
+
+    config.Suppress = []string{"aws-access-key"}
+    history := config.filterHistory([]HistorySecret{
+        {Risk: Risk{FilePath: "services/api/config.js", RuleID: "github-token"}},
+        {Risk: Risk{FilePath: "services/api/config.js", RuleID: "aws-access-key"}},
+        {Risk: Risk{FilePath: "services/api/testdata/keys.txt", RuleID: "github-token"}},
+        {Risk: Risk{FilePath: "scripts/deploy.sh", RuleID: "github-token"}},
+    })
+    if len(history) != 1 || history[0].FilePath != "services/api/config.js" || history[0].RuleID != "github-token" || config.suppressed != 2 {
+        t.Errorf("Expected history secrets scoped like rule findings, got %+v (%d suppressed)", history, config.suppressed)
+    }
+}
+
+func TestPostPolicyStatus(t *testing.T) {
+    var posted []map[string]string
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/payments/statuses/0123abc" || r.Header.Get("Authorization") != "Bearer ghp_test" {
+            http.Error(w, "unexpected request", http.StatusBadRequest)
+            return
+        }
+        var status map[string]string
+        json.NewDecoder(r.Body).Decode(&status)
+        posted = append(posted, status)
+        w.WriteHeader(http.StatusCreated)
+    }))
+    defer server.Close()
+    poster := &CommitStatusPoster{Token: "ghp_test", BaseURL: server.URL, Client: server.Client()}
+
+    failed := &PolicyGate{Failed: true, Reasons: []string{strings.Repeat("3 risks at high severity or above (fail_on.high: 3) ", 4)}}
+    if err := poster.PostPolicyStatus(context.Background(), "acme/payments", "0123abc", failed); err != nil {
+        t.Fatalf("PostPolicyStatus failed: %v", err)
+    }
+    if err := poster.PostPolicyStatus(context.Background(), "acme/payments", "0123abc", &PolicyGate{}); err != nil {
+        t.Fatalf("PostPolicyStatus failed: %v", err)
+    }
+    if len(posted) != 2 || posted[0]["state"] != "failure" || posted[1]["state"] != "success" || posted[0]["context"] != policyStatusContext {
+        t.Fatalf("Expected a failure then a success status, got %v", posted)
+    }
+    if len(posted[0]["description"]) > maxStatusDescription || !strings.HasPrefix(posted[0]["description"], "3 risks at high severity") {
+        t.Errorf("Expected the reasons truncated to GitHub's limit, got %q", posted[0]["description"])
+    }
+    if err := poster.PostPolicyStatus(context.Background(), "acme/other", "0123abc", failed); err == nil {
+        t.Error("Expected an API error to be returned")
+    }
+}
+
+func TestAnalyzeWithRepoConfig(t *testing.T) {
+    repoPath := writeRepo(t, map[string]string{
+        ".aegis.yml": `
+analyzers:
+  ai: false
+paths:
+  exclude: [examples/]
+business_type: healthcare
+compliance: [HIPAA]
+fail_on:
+  critical: 1
+suppress: [go-math-rand-secret]
+`,
+        "main.go": `package main
+
+import (
+    "math/rand"
+    "os/exec"
+)
+
+func Token() int { return rand.Int() }
+
+func Run(script string) { exec.Command("sh", "-c", script).Run() }
+`,
+        "examples/Dockerfile": "FROM node:latest\n",
+        "Dockerfile":          "FROM node:latest\nUSER node\n",
+    })
+
+    response, err := AnalyzeEntireCodebase(context.Background(), repoPath, nil)
+    if err != nil {
+        t.Fatalf("AnalyzeEntireCodebase failed: %v", err)
+    }
+
+    var rules []string
+    for _, risk := range combineAllRisks(response) {
+        rules = append(rules, fmt.Sprintf("%s %s", risk.FilePath, risk.RuleID))
+    }
+    if fmt.Sprint(rules) != "[main.go go-exec-shell-injection Dockerfile iac-latest-tag]" {
+        t.Errorf("Expected suppressed and excluded findings to be dropped, got %v", rules)
+    }
+    if response.Summary.BusinessType != "healthcare" || fmt.Sprint(response.Summary.Compliance) != "[HIPAA]" {
+        t.Errorf("Expected the configured business type and compliance, got %+v", response.Summary)
+    }
+    if report := response.RepoConfig; report == nil || report.SuppressedFindings != 1 || fmt.Sprint(report.DisabledAnalyzers) != "[ai]" {
+        t.Errorf("Expected the config report on the analysis, got %+v", report)
+    }
+    if response.Gate == nil || !response.Gate.Failed {
+        t.Errorf("Expected the critical finding to fail the gate, got %+v", response.Gate)
+    }
+    if response.Coverage == nil || response.Coverage.Skipped != response.Coverage.TotalFiles || response.Coverage.Files[0].Reason != "AI analysis disabled in .aegis.yml" {
+        t.Errorf("Expected every file reported as not sent to the AI, got %+v", response.Coverage)
+    }
+}
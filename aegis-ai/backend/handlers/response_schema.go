@@ -0,0 +1,309 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+const (
+    defaultRepairAttempts = 2
+    maxSchemaViolations   = 20
+    maxRepairEchoChars    = 12000
+)
+
+// JSON schema every model answer must satisfy - sent back to the model on repair.
+// validateAIResponse enforces it, plus the rule that files exist in the codebase.
+const analysisResponseSchema = `{
+  "type": "object",
+  "anyOf": [
+    {"required": ["critical_risks"]},
+    {"required": ["high_risks"]},
+    {"required": ["medium_risks"]}
+  ],
+  "properties": {
+    "critical_risks": {"type": "array", "items": {"$ref": "#/$defs/risk"}},
+    "high_risks": {"type": "array", "items": {"$ref": "#/$defs/risk"}},
+    "medium_risks": {"type": "array", "items": {"$ref": "#/$defs/risk"}},
+    "explanations": {"type": "array", "items": {"type": "string"}},
+    "summary": {"type": "object"},
+    "architecture": {"type": "object"},
+    "compliance": {"type": "object"}
+  },
+  "$defs": {
+    "risk": {
+      "type": "object",
+      "required": ["file", "line", "title", "description", "confidence"],
+      "properties": {
+        "file": {"type": "string", "minLength": 1, "description": "path of a file from the codebase"},
+        "line": {"type": "integer", "minimum": 1},
+        "title": {"type": "string", "minLength": 1},
+        "description": {"type": "string", "minLength": 1},
+        "impact": {"type": "string"},
+        "confidence": {"type": "number", "minimum": 0, "maximum": 1},
+        "code_snippet": {"type": "string"},
+        "cvss_score": {"type": "number", "minimum": 0, "maximum": 10},
+        "exploitation_complexity": {"enum": ["Low", "Medium", "High"]},
+        "remediation_priority": {"enum": ["Immediate", "High", "Medium", "Low"]},
+        "compliance_violations": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+// AIParseError is returned when a model's output never passed schema validation
+type AIParseError struct {
+    Violations []string
+}
+
+func (e *AIParseError) Error() string {
+    return fmt.Sprintf("AI response failed schema validation: %s", strings.Join(e.Violations, "; "))
+}
+
+// schemaCheck is the result of validating one model answer
+type schemaCheck struct {
+    Response   *AIAnalysisResponse // nil when the answer is unusable as a whole
+    Violations []string
+    Warnings   []string // optional fields that were cleared, see sanitizeRiskScoring
+    Dropped    int      // findings removed for failing validation
+}
+
+// riskSchema mirrors Risk with pointers so missing fields differ from zero values
+type riskSchema struct {
+    File        *string  `json:"file"`
+    FilePath    *string  `json:"file_path"`
+    Line        *int     `json:"line"`
+    LineNumber  *int     `json:"line_number"`
+    Title       *string  `json:"title"`
+    Description *string  `json:"description"`
+    Confidence  *float64 `json:"confidence"`
+}
+
+// repairAttempts reads AEGIS_REPAIR_ATTEMPTS; 0 disables repair and accepts the
+// first answer as far as it validates. Unset, negative or malformed values use the default.
+func repairAttempts() int {
+    if value, err := strconv.Atoi(os.Getenv("AEGIS_REPAIR_ATTEMPTS")); err == nil && value >= 0 {
+        return value
+    }
+    return defaultRepairAttempts
+}
+
+// extractJSONObject returns the first complete JSON object in the model's text,
+// ignoring prose and markdown fences around it
+func extractJSONObject(text string) ([]byte, error) {
+    offset := 0
+    for attempts := 0; attempts < 5; attempts++ {
+        start := strings.Index(text[offset:], "{")
+        if start == -1 {
+            break
+        }
+        offset += start
+
+        var raw json.RawMessage
+        if err := json.NewDecoder(strings.NewReader(text[offset:])).Decode(&raw); err == nil {
+            return raw, nil
+        }
+        offset++
+    }
+    return nil, fmt.Errorf("no valid JSON object found in response")
+}
+
+// normalizeRiskPath strips the prefixes models like to add to relative paths
+func normalizeRiskPath(path string) string {
+    return strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "./"), "/")
+}
+
+// validateAIResponse checks a model answer against analysisResponseSchema.
+// Structural problems make the whole answer unusable; invalid findings are
+// dropped and reported as violations. knownFiles nil skips the file check.
+func validateAIResponse(text string, knownFiles map[string]bool) schemaCheck {
+    raw, err := extractJSONObject(text)
+    if err != nil {
+        return schemaCheck{Violations: []string{err.Error()}}
+    }
+
+    var document map[string]json.RawMessage
+    if err := json.Unmarshal(raw, &document); err != nil {
+        return schemaCheck{Violations: []string{"response must be a JSON object"}}
+    }
+
+    var response AIAnalysisResponse
+    if err := json.Unmarshal(raw, &response); err != nil {
+        return schemaCheck{Violations: []string{fmt.Sprintf("response does not match the schema: %v", err)}}
+    }
+
+    tiers := []struct {
+        name  string
+        risks *[]Risk
+    }{
+        {"critical_risks", &response.CriticalRisks},
+        {"high_risks", &response.HighRisks},
+        {"medium_risks", &response.MediumRisks},
+    }
+
+    check := schemaCheck{}
+    present := false
+    for _, tier := range tiers {
+        rawTier, exists := document[tier.name]
+        if !exists || string(rawTier) == "null" {
+            *tier.risks = []Risk{}
+            continue
+        }
+        present = true
+
+        var rawRisks []riskSchema
+        if err := json.Unmarshal(rawTier, &rawRisks); err != nil {
+            return schemaCheck{Violations: []string{fmt.Sprintf("%s: %v", tier.name, err)}}
+        }
+
+        kept := []Risk{}
+        for i, rawRisk := range rawRisks {
+            problems := checkRiskSchema(rawRisk, knownFiles)
+            if len(problems) > 0 {
+                check.Dropped++
+                for _, problem := range problems {
+                    check.Violations = append(check.Violations, fmt.Sprintf("%s[%d].%s", tier.name, i, problem))
+                }
+                continue
+            }
+            risk := (*tier.risks)[i]
+            risk.File = normalizeRiskPath(risk.File)
+            risk.FilePath = normalizeRiskPath(risk.FilePath)
+            risk.Source = RiskSourceAI
+            for _, warning := range sanitizeRiskScoring(&risk) {
+                check.Warnings = append(check.Warnings, fmt.Sprintf("%s[%d].%s", tier.name, i, warning))
+            }
+            kept = append(kept, risk)
+        }
+        *tier.risks = kept
+    }
+
+    if !present {
+        return schemaCheck{Violations: []string{"response must contain critical_risks, high_risks or medium_risks"}}
+    }
+
+    if len(check.Violations) > maxSchemaViolations {
+        check.Violations = append(check.Violations[:maxSchemaViolations],
+            fmt.Sprintf("... and %d more", len(check.Violations)-maxSchemaViolations))
+    }
+
+    // Fill file_path/line_number for the API and the fix engine
+    response = enhanceRiskData(response)
+    check.Response = &response
+    return check
+}
+
+func checkRiskSchema(risk riskSchema, knownFiles map[string]bool) []string {
+    var problems []string
+
+    file := risk.File
+    if file == nil || *file == "" {
+        file = risk.FilePath
+    }
+    switch {
+    case file == nil || strings.TrimSpace(*file) == "":
+        problems = append(problems, "file: required")
+    case knownFiles != nil && !knownFiles[normalizeRiskPath(*file)]:
+        problems = append(problems, fmt.Sprintf("file: %q is not in the scanned codebase", *file))
+    }
+
+    line := risk.Line
+    if line == nil {
+        line = risk.LineNumber
+    }
+    switch {
+    case line == nil:
+        problems = append(problems, "line: required")
+    case *line < 1:
+        problems = append(problems, "line: must be >= 1")
+    }
+
+    if risk.Title == nil || strings.TrimSpace(*risk.Title) == "" {
+        problems = append(problems, "title: required")
+    }
+    if risk.Description == nil || strings.TrimSpace(*risk.Description) == "" {
+        problems = append(problems, "description: required")
+    }
+
+    switch {
+    case risk.Confidence == nil:
+        problems = append(problems, "confidence: required")
+    case *risk.Confidence < 0 || *risk.Confidence > 1:
+        problems = append(problems, "confidence: must be between 0 and 1")
+    }
+    return problems
+}
+
+// buildRepairPrompt asks the model to fix its previous answer
+func buildRepairPrompt(previous string, violations []string, knownFiles map[string]bool) string {
+    var prompt strings.Builder
+    prompt.WriteString("Your previous security analysis response failed validation:\n")
+    for _, violation := range violations {
+        prompt.WriteString("- " + violation + "\n")
+    }
+
+    prompt.WriteString("\nReturn ONLY the corrected JSON object - no prose, no markdown fences. It must match this JSON schema:\n")
+    prompt.WriteString(analysisResponseSchema + "\n")
+
+    if len(knownFiles) > 0 {
+        files := make([]string, 0, len(knownFiles))
+        for file := range knownFiles {
+            files = append(files, file)
+        }
+        sort.Strings(files)
+        if len(files) > 200 {
+            files = files[:200]
+        }
+        prompt.WriteString("\nThe \"file\" of every risk must be one of these paths (drop findings you cannot attribute):\n")
+        prompt.WriteString(strings.Join(files, "\n") + "\n")
+    }
+
+    if len(previous) > maxRepairEchoChars {
+        previous = previous[:maxRepairEchoChars]
+    }
+    prompt.WriteString("\nPREVIOUS RESPONSE:\n" + previous)
+    return prompt.String()
+}
+
+// completeWithRepair sends the request and re-prompts the same model with the
+// validation errors until the answer passes or the repair attempts run out.
+// Answers that stay structurally invalid fail with an *AIParseError.
+func completeWithRepair(ctx context.Context, provider LLMProvider, request CompletionRequest, knownFiles map[string]bool) (*AIAnalysisResponse, error) {
+    content, err := provider.Complete(ctx, request)
+    if err != nil {
+        return nil, err
+    }
+
+    maxAttempts := repairAttempts()
+    for attempt := 0; ; attempt++ {
+        check := validateAIResponse(content, knownFiles)
+        if len(check.Warnings) > 0 {
+            fmt.Printf("⚠️ [%s] Cleared invalid scoring fields: %v\n", provider.Name(), check.Warnings)
+        }
+        if len(check.Violations) == 0 {
+            return check.Response, nil
+        }
+
+        if attempt == maxAttempts {
+            if check.Response == nil {
+                return nil, &AIParseError{Violations: check.Violations}
+            }
+            fmt.Printf("⚠️ [%s] Discarding %d findings that failed validation: %v\n", provider.Name(), check.Dropped, check.Violations)
+            check.Response.Explanations = append(check.Response.Explanations,
+                fmt.Sprintf("%d AI findings were discarded because they failed schema validation", check.Dropped))
+            return check.Response, nil
+        }
+
+        fmt.Printf("🔧 [%s] Response failed validation (%d issues), requesting repair %d/%d\n",
+            provider.Name(), len(check.Violations), attempt+1, maxAttempts)
+        repair := request
+        repair.UserPrompt = buildRepairPrompt(content, check.Violations, knownFiles)
+        if content, err = provider.Complete(ctx, repair); err != nil {
+            return nil, err
+        }
+    }
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+    "context"
+    "strings"
+    "testing"
+)
+
+func TestValidateAIResponse(t *testing.T) {
+    knownFiles := map[string]bool{"config.js": true, "app.js": true, "package.json": true}
+
+    check := validateAIResponse(loadFixture(t, "vulnerable-app.json"), knownFiles)
+    if check.Response == nil || len(check.Violations) != 0 {
+        t.Fatalf("Expected the fixture to validate, got %v", check.Violations)
+    }
+    if check.Response.CriticalRisks[0].FilePath != "config.js" || check.Response.CriticalRisks[0].LineNumber != 6 {
+        t.Errorf("Expected file_path and line_number filled in: %+v", check.Response.CriticalRisks[0])
+    }
+
+    tests := []struct {
+        name      string
+        response  string
+        usable    bool
+        violation string
+    }{
+        {"prose only", "I could not find any issues.", false, "no valid JSON object"},
+        {"wrong type", `{"high_risks": [{"file": "app.js", "line": "7"}]}`, false, "does not match the schema"},
+        {"no risk tiers", `{"explanations": ["looks fine"]}`, false, "must contain"},
+        {"missing fields", `{"high_risks": [{"file": "app.js", "line": 7, "title": "XSS"}]}`, true, "high_risks[0].description: required"},
+        {"confidence range", `{"high_risks": [{"file": "app.js", "line": 7, "title": "XSS", "description": "d", "confidence": 1.5}]}`, true, "between 0 and 1"},
+        {"line zero", `{"medium_risks": [{"file": "app.js", "line": 0, "title": "XSS", "description": "d", "confidence": 0.5}]}`, true, "line: must be >= 1"},
+        {"unknown file", "```json\n" + `{"critical_risks": [{"file": "./secrets.yml", "line": 2, "title": "Key", "description": "d", "confidence": 0.9}]}` + "\n```", true, "not in the scanned codebase"},
+    }
+
+    for _, tt := range tests {
+        check := validateAIResponse(tt.response, knownFiles)
+        if (check.Response != nil) != tt.usable {
+            t.Errorf("%s: usable=%v, want %v", tt.name, check.Response != nil, tt.usable)
+        }
+        if !strings.Contains(strings.Join(check.Violations, "\n"), tt.violation) {
+            t.Errorf("%s: expected violation %q, got %v", tt.name, tt.violation, check.Violations)
+        }
+        if check.Response != nil && len(combineAllRisks(check.Response)) != 0 {
+            t.Errorf("%s: invalid finding was kept", tt.name)
+        }
+    }
+}
+
+// scriptedProvider returns its responses in order and records the prompts
+type scriptedProvider struct {
+    responses []string
+    prompts   []string
+}
+
+func (p *scriptedProvider) Name() string         { return "scripted" }
+func (p *scriptedProvider) ModelNames() []string { return []string{"mock"} }
+
+func (p *scriptedProvider) Complete(ctx context.Context, request CompletionRequest) (string, error) {
+    p.prompts = append(p.prompts, request.UserPrompt)
+    response := p.responses[0]
+    if len(p.responses) > 1 {
+        p.responses = p.responses[1:]
+    }
+    return response, nil
+}
+
+func TestRepairLoop(t *testing.T) {
+    knownFiles := map[string]bool{"app.js": true}
+    provider := &scriptedProvider{responses: []string{
+        "Sure! The app has an SQL injection in app.js line 7.",
+        `{"high_risks": [{"file": "app.js", "line": 7, "title": "SQL Injection", "description": "Concatenated query", "confidence": 0.9}]}`,
+    }}
+
+    response, _, err := callAIProviders(context.Background(), []LLMProvider{provider}, staticPrompt("audit this"), knownFiles)
+    if err != nil {
+        t.Fatalf("Expected the repaired answer to be accepted, got %v", err)
+    }
+    if len(provider.prompts) != 2 || !strings.Contains(provider.prompts[1], "no valid JSON object") || !strings.Contains(provider.prompts[1], "app.js") {
+        t.Errorf("Expected one repair prompt listing the violation and valid files, got %d prompts", len(provider.prompts))
+    }
+    if len(response.HighRisks) != 1 {
+        t.Errorf("Unexpected repaired response: %+v", response)
+    }
+
+    // Never valid: bounded attempts, then a parse error instead of a fake finding
+    t.Setenv("AEGIS_REPAIR_ATTEMPTS", "3")
+    broken := &scriptedProvider{responses: []string{"I am unable to output JSON."}}
+    _, _, err = callAIProviders(context.Background(), []LLMProvider{broken}, staticPrompt("audit this"), knownFiles)
+    if _, ok := err.(*AIParseError); !ok {
+        t.Fatalf("Expected *AIParseError, got %v", err)
+    }
+    if len(broken.prompts) != 4 {
+        t.Errorf("Expected 1 request + 3 repairs, got %d", len(broken.prompts))
+    }
+
+    t.Setenv("AEGIS_REPAIR_ATTEMPTS", "0")
+    unrepaired := &scriptedProvider{responses: []string{"I am unable to output JSON."}}
+    if _, _, err = callAIProviders(context.Background(), []LLMProvider{unrepaired}, staticPrompt("audit this"), knownFiles); err == nil || len(unrepaired.prompts) != 1 {
+        t.Errorf("Expected AEGIS_REPAIR_ATTEMPTS=0 to disable repair, got %d prompts (%v)", len(unrepaired.prompts), err)
+    }
+
+    // Findings that stay invalid are dropped and noted, valid ones are kept
+    partial := &scriptedProvider{responses: []string{
+        `{"high_risks": [{"file": "app.js", "line": 7, "title": "SQL Injection", "description": "d", "confidence": 0.9}, {"file": "ghost.js", "line": 1, "title": "Ghost", "description": "d", "confidence": 0.9}]}`,
+    }}
+    response, _, err = callAIProviders(context.Background(), []LLMProvider{partial}, staticPrompt("audit this"), knownFiles)
+    if err != nil || len(response.HighRisks) != 1 || response.HighRisks[0].Title != "SQL Injection" {
+        t.Fatalf("Expected the valid finding to survive, got %+v (%v)", response, err)
+    }
+    if !strings.Contains(strings.Join(response.Explanations, " "), "1 AI findings were discarded") {
+        t.Errorf("Expected a note about discarded findings, got %v", response.Explanations)
+    }
+}
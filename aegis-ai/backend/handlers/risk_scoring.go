@@ -0,0 +1,131 @@
+package handlers
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Allowed values for the per-risk ratings, in order of urgency
+var (
+    remediationPriorities    = []string{"Immediate", "High", "Medium", "Low"}
+    exploitationComplexities = []string{"Low", "Medium", "High"}
+)
+
+// normalizeRating matches a model-supplied rating case-insensitively
+func normalizeRating(value string, allowed []string) (string, bool) {
+    for _, candidate := range allowed {
+        if strings.EqualFold(strings.TrimSpace(value), candidate) {
+            return candidate, true
+        }
+    }
+    return "", false
+}
+
+// sanitizeRiskScoring validates the optional scoring fields. Invalid values are
+// cleared rather than dropping the finding; the returned warnings say what changed.
+func sanitizeRiskScoring(risk *Risk) []string {
+    var warnings []string
+
+    if risk.CVSSScore < 0 || risk.CVSSScore > 10 {
+        warnings = append(warnings, fmt.Sprintf("cvss_score: %.1f is outside 0-10", risk.CVSSScore))
+        risk.CVSSScore = 0
+    }
+
+    if risk.RemediationPriority != "" {
+        priority, valid := normalizeRating(risk.RemediationPriority, remediationPriorities)
+        if !valid {
+            warnings = append(warnings, fmt.Sprintf("remediation_priority: unknown value %q", risk.RemediationPriority))
+        }
+        risk.RemediationPriority = priority
+    }
+
+    if risk.ExploitationComplexity != "" {
+        complexity, valid := normalizeRating(risk.ExploitationComplexity, exploitationComplexities)
+        if !valid {
+            warnings = append(warnings, fmt.Sprintf("exploitation_complexity: unknown value %q", risk.ExploitationComplexity))
+        }
+        risk.ExploitationComplexity = complexity
+    }
+
+    var violations []string
+    for _, violation := range risk.ComplianceViolations {
+        if violation = strings.TrimSpace(violation); violation != "" {
+            violations = append(violations, violation)
+        }
+    }
+    if len(violations) > 0 {
+        risk.ComplianceViolations = unique(violations)
+    } else {
+        risk.ComplianceViolations = nil
+    }
+    return warnings
+}
+
+// priorityRank orders remediation priorities, unknown last
+func priorityRank(priority string) int {
+    for i, candidate := range remediationPriorities {
+        if candidate == priority {
+            return i
+        }
+    }
+    return len(remediationPriorities)
+}
+
+// sortRisks orders each tier by CVSS, then remediation priority, then confidence,
+// with file and line as a stable tie-break
+func sortRisks(response *AIAnalysisResponse) {
+    for _, risks := range [][]Risk{response.CriticalRisks, response.HighRisks, response.MediumRisks} {
+        sort.SliceStable(risks, func(i, j int) bool {
+            a, b := risks[i], risks[j]
+            if a.CVSSScore != b.CVSSScore {
+                return a.CVSSScore > b.CVSSScore
+            }
+            if priorityRank(a.RemediationPriority) != priorityRank(b.RemediationPriority) {
+                return priorityRank(a.RemediationPriority) < priorityRank(b.RemediationPriority)
+            }
+            if a.Confidence != b.Confidence {
+                return a.Confidence > b.Confidence
+            }
+            if a.FilePath != b.FilePath {
+                return a.FilePath < b.FilePath
+            }
+            return a.LineNumber < b.LineNumber
+        })
+    }
+}
+
+// highestCVSS is the worst CVSS score across all tiers, 0 if none were scored
+func highestCVSS(response *AIAnalysisResponse) float64 {
+    highest := 0.0
+    for _, risk := range combineAllRisks(response) {
+        if risk.CVSSScore > highest {
+            highest = risk.CVSSScore
+        }
+    }
+    return highest
+}
+
+// riskScoringSummary renders "CVSS 9.8 · Low complexity · Immediate priority" for comments
+func riskScoringSummary(risk Risk) string {
+    var parts []string
+    if risk.CVSSScore > 0 {
+        parts = append(parts, fmt.Sprintf("CVSS %.1f", risk.CVSSScore))
+    }
+    if risk.ExploitationComplexity != "" {
+        parts = append(parts, risk.ExploitationComplexity+" complexity")
+    }
+    if risk.RemediationPriority != "" {
+        parts = append(parts, risk.RemediationPriority+" priority")
+    }
+    return strings.Join(parts, " · ")
+}
+
+// complianceViolations lists every regulation cited by a finding, most severe tier first
+func complianceViolations(response *AIAnalysisResponse) []string {
+    var violations []string
+    for _, risk := range combineAllRisks(response) {
+        violations = append(violations, risk.ComplianceViolations...)
+    }
+    return unique(violations)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestSanitizeRiskScoring(t *testing.T) {
+    risk := Risk{
+        CVSSScore:              11.2,
+        ExploitationComplexity: "LOW",
+        RemediationPriority:    "asap",
+        ComplianceViolations:   []string{" GDPR Article 32", "", "GDPR Article 32"},
+    }
+
+    warnings := sanitizeRiskScoring(&risk)
+    if len(warnings) != 2 {
+        t.Errorf("Expected CVSS and priority warnings, got %v", warnings)
+    }
+    if risk.CVSSScore != 0 || risk.RemediationPriority != "" || risk.ExploitationComplexity != "Low" {
+        t.Errorf("Unexpected sanitized risk: %+v", risk)
+    }
+    if len(risk.ComplianceViolations) != 1 || risk.ComplianceViolations[0] != "GDPR Article 32" {
+        t.Errorf("Expected trimmed, de-duplicated violations: %v", risk.ComplianceViolations)
+    }
+
+    check := validateAIResponse(`{"high_risks": [{"file": "app.js", "line": 3, "title": "XSS", "description": "d", "confidence": 0.8, "cvss_score": 42}]}`, nil)
+    if len(check.Violations) != 0 || len(check.Warnings) != 1 || len(check.Response.HighRisks) != 1 {
+        t.Errorf("Expected a bad CVSS to be cleared without dropping the finding: %+v", check)
+    }
+}
+
+func TestSortRisksAndComment(t *testing.T) {
+    response := &AIAnalysisResponse{
+        CriticalRisks: []Risk{
+            {Title: "Unscored", FilePath: "a.js", Confidence: 0.99},
+            {Title: "Medium priority", FilePath: "b.js", CVSSScore: 9.1, RemediationPriority: "Medium"},
+            {Title: "Immediate", FilePath: "c.js", CVSSScore: 9.1, RemediationPriority: "Immediate",
+                ExploitationComplexity: "Low", ComplianceViolations: []string{"PCI-DSS Requirement 8"}},
+            {Title: "Highest", FilePath: "d.js", CVSSScore: 9.8},
+        },
+    }
+
+    sortRisks(response)
+    var order []string
+    for _, risk := range response.CriticalRisks {
+        order = append(order, risk.Title)
+    }
+    if strings.Join(order, ",") != "Highest,Immediate,Medium priority,Unscored" {
+        t.Errorf("Unexpected order: %v", order)
+    }
+
+    response.Compliance = &ComplianceAnalysis{Standards: []string{"PCI-DSS"}}
+    comment := createGitHubComment(response, 1)
+    for _, expected := range []string{"**Highest CVSS**: 9.8", "CVSS 9.1 · Low complexity · Immediate priority", "**Violations found**: PCI-DSS Requirement 8"} {
+        if !strings.Contains(comment, expected) {
+            t.Errorf("Comment missing %q", expected)
+        }
+    }
+}
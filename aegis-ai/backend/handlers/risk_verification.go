@@ -0,0 +1,244 @@
+package handlers
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Minimum similarity for a snippet line to count as found
+const snippetMatchThreshold = 0.8
+
+// VerificationStats counts what the hallucination guard did to the findings
+type VerificationStats struct {
+    Verified   int
+    Corrected  int
+    Unverified int
+    Downgraded int
+    Dropped    int
+}
+
+// normalizeCode collapses whitespace so formatting differences don't block a match
+func normalizeCode(code string) string {
+    return strings.Join(strings.Fields(code), " ")
+}
+
+// snippetAnchor picks the most distinctive line of a snippet (the longest one)
+// and its offset from the snippet's first line
+func snippetAnchor(snippet string) (string, int) {
+    anchor, offset := "", 0
+    for i, line := range strings.Split(snippet, "\n") {
+        normalized := normalizeCode(line)
+        if normalized == "..." || strings.HasPrefix(normalized, "// ...") {
+            continue
+        }
+        if len(normalized) > len(anchor) {
+            anchor, offset = normalized, i
+        }
+    }
+    return anchor, offset
+}
+
+// bigrams returns the character bigram counts of s
+func bigrams(s string) map[string]int {
+    counts := make(map[string]int)
+    for i := 0; i+1 < len(s); i++ {
+        counts[s[i:i+2]]++
+    }
+    return counts
+}
+
+// codeSimilarity is the Sørensen-Dice coefficient over character bigrams (0..1)
+func codeSimilarity(a, b string) float64 {
+    if a == b {
+        return 1
+    }
+    if len(a) < 2 || len(b) < 2 {
+        return 0
+    }
+
+    aBigrams, bBigrams := bigrams(a), bigrams(b)
+    overlap := 0
+    for bigram, count := range aBigrams {
+        if other := bBigrams[bigram]; other < count {
+            overlap += other
+        } else {
+            overlap += count
+        }
+    }
+    return 2 * float64(overlap) / float64(len(a)-1+len(b)-1)
+}
+
+// locateSnippet finds the 1-based line where snippet starts in lines. Exact
+// (whitespace-insensitive) matches win; otherwise the most similar line above
+// the threshold is used. Ties go to the line closest to hint. Returns 0 if not found.
+func locateSnippet(lines []string, snippet string, hint int) (int, float64) {
+    anchor, offset := snippetAnchor(snippet)
+    if anchor == "" {
+        return 0, 0
+    }
+
+    bestLine, bestScore := 0, 0.0
+    consider := func(index int, score float64) {
+        line := index + 1 - offset
+        if line < 1 {
+            line = 1
+        }
+        if score > bestScore || (score == bestScore && bestLine != 0 && absInt(line-hint) < absInt(bestLine-hint)) {
+            bestLine, bestScore = line, score
+        }
+    }
+
+    for i, line := range lines {
+        if strings.Contains(normalizeCode(line), anchor) {
+            consider(i, 1)
+        }
+    }
+    if bestLine != 0 {
+        return bestLine, bestScore
+    }
+
+    for i, line := range lines {
+        normalized := normalizeCode(line)
+        // Lines of very different length can't reach the threshold
+        if len(normalized) == 0 || len(normalized) > 2*len(anchor) || 2*len(normalized) < len(anchor) {
+            continue
+        }
+        if score := codeSimilarity(normalized, anchor); score >= snippetMatchThreshold {
+            consider(i, score)
+        }
+    }
+    return bestLine, bestScore
+}
+
+func absInt(value int) int {
+    if value < 0 {
+        return -value
+    }
+    return value
+}
+
+// verifyRisk checks one finding against the source. It returns false when the
+// finding must be dropped, and reports whether it should drop a tier.
+func verifyRisk(risk *Risk, codebase map[string]string) (keep bool, downgrade bool) {
+    path := normalizeRiskPath(risk.FilePath)
+    if path == "" {
+        path = normalizeRiskPath(risk.File)
+    }
+    content, exists := codebase[path]
+    if !exists {
+        return false, false
+    }
+    risk.File, risk.FilePath = path, path
+
+    lines := strings.Split(content, "\n")
+    claimed := risk.LineNumber
+    if claimed == 0 {
+        claimed = risk.Line
+    }
+
+    if strings.TrimSpace(risk.CodeSnippet) == "" {
+        risk.Verified = false
+        if claimed < 1 || claimed > len(lines) {
+            return false, false
+        }
+        risk.Correction = "no code snippet to verify"
+        return true, false
+    }
+
+    line, score := locateSnippet(lines, risk.CodeSnippet, claimed)
+    if line == 0 {
+        risk.Verified = false
+        risk.Correction = "code snippet not found in file"
+        return true, true
+    }
+
+    risk.Verified = true
+    if line != claimed {
+        risk.Correction = fmt.Sprintf("line corrected from %d to %d", claimed, line)
+    }
+    if score < 1 {
+        if risk.Correction != "" {
+            risk.Correction += "; "
+        }
+        risk.Correction += fmt.Sprintf("snippet matched approximately (%.0f%%)", score*100)
+    }
+    risk.Line, risk.LineNumber = line, line
+    return true, false
+}
+
+// verifyFindings is the hallucination guard: every finding must point at a file
+// in the codebase, and its snippet must be found there. Line numbers are
+// corrected to where the snippet actually is; findings whose snippet can't be
+// found drop one tier (medium ones are removed) and stay unverified.
+func verifyFindings(response *AIAnalysisResponse, codebase map[string]string) VerificationStats {
+    var stats VerificationStats
+    tiers := [][]Risk{response.CriticalRisks, response.HighRisks, response.MediumRisks}
+    verified := [][]Risk{{}, {}, {}}
+    seen := make(map[string]bool)
+
+    for level, risks := range tiers {
+        for _, risk := range risks {
+            keep, downgrade := verifyRisk(&risk, codebase)
+            if !keep {
+                fmt.Printf("🚫 Dropping finding %q: %s:%d does not exist\n", risk.Title, risk.FilePath, risk.LineNumber)
+                stats.Dropped++
+                continue
+            }
+
+            target := level
+            if downgrade {
+                if level == len(tiers)-1 {
+                    fmt.Printf("🚫 Dropping unverifiable finding %q in %s\n", risk.Title, risk.FilePath)
+                    stats.Dropped++
+                    continue
+                }
+                target = level + 1
+                risk.Correction += " - downgraded from " + tierNames[level]
+                stats.Downgraded++
+            }
+
+            switch {
+            case risk.Verified && risk.Correction != "":
+                stats.Corrected++
+                stats.Verified++
+            case risk.Verified:
+                stats.Verified++
+            default:
+                stats.Unverified++
+            }
+
+            // Corrected line numbers can turn two findings into the same one
+            key := riskKey(risk)
+            if seen[key] {
+                continue
+            }
+            seen[key] = true
+            verified[target] = append(verified[target], risk)
+        }
+    }
+
+    response.CriticalRisks, response.HighRisks, response.MediumRisks = verified[0], verified[1], verified[2]
+    response.Summary.TotalCritical = len(response.CriticalRisks)
+    response.Summary.TotalHigh = len(response.HighRisks)
+    response.Summary.TotalMedium = len(response.MediumRisks)
+
+    if stats.Corrected+stats.Unverified+stats.Dropped > 0 {
+        response.Explanations = append(response.Explanations, fmt.Sprintf(
+            "Finding verification: %d verified against the source (%d with corrected locations), %d unverified, %d downgraded, %d dropped",
+            stats.Verified, stats.Corrected, stats.Unverified, stats.Downgraded, stats.Dropped))
+    }
+    return stats
+}
+
+// fixableRisks returns the AI findings whose snippet was located in the source.
+// Rule findings are left out: secret snippets are redacted, and dependency and
+// IaC findings point at manifests the code fix templates would corrupt.
+func fixableRisks(response *AIAnalysisResponse) []Risk {
+    var risks []Risk
+    for _, risk := range combineAllRisks(response) {
+        if risk.Verified && risk.Source != RiskSourceRule {
+            risks = append(risks, risk)
+        }
+    }
+    return risks
+}
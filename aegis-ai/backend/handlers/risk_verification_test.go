@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+const verificationSource = `const express = require('express');
+const app = express();
+
+app.get('/user', (req, res) => {
+  const query = "SELECT * FROM users WHERE name = '" + req.query.name + "'";
+  db.query(query);
+});
+
+app.listen(3000);`
+
+func TestLocateSnippet(t *testing.T) {
+    lines := strings.Split(verificationSource, "\n")
+
+    if line, score := locateSnippet(lines, `const query = "SELECT * FROM users WHERE name = '" + req.query.name + "'";`, 12); line != 5 || score != 1 {
+        t.Errorf("Expected exact match on line 5, got %d (%.2f)", line, score)
+    }
+    // Multi-line snippets report where the snippet starts, not the anchor line
+    if line, _ := locateSnippet(lines, "app.get('/user', (req, res) => {\n  const query = \"SELECT * FROM users WHERE name = '\" + req.query.name + \"'\";", 1); line != 4 {
+        t.Errorf("Expected multi-line snippet to start on line 4, got %d", line)
+    }
+    if line, score := locateSnippet(lines, `const query = "SELECT * FROM users WHERE name='" + req.query.name + "'"`, 1); line != 5 || score >= 1 || score < snippetMatchThreshold {
+        t.Errorf("Expected approximate match on line 5, got %d (%.2f)", line, score)
+    }
+    if line, _ := locateSnippet(lines, `eval(req.body.code)`, 5); line != 0 {
+        t.Errorf("Expected invented snippet not to be found, got line %d", line)
+    }
+}
+
+func TestVerifyFindings(t *testing.T) {
+    codebase := map[string]string{"app.js": verificationSource}
+    sqlSnippet := `const query = "SELECT * FROM users WHERE name = '" + req.query.name + "'";`
+    response := &AIAnalysisResponse{
+        CriticalRisks: []Risk{
+            {Title: "SQL injection", FilePath: "app.js", LineNumber: 9, CodeSnippet: sqlSnippet},
+            {Title: "Invented eval", FilePath: "app.js", LineNumber: 2, CodeSnippet: "eval(req.body.code)"},
+            {Title: "Missing file", FilePath: "server.js", LineNumber: 1, CodeSnippet: "app.listen(3000);"},
+        },
+        MediumRisks: []Risk{
+            {Title: "Invented header", FilePath: "app.js", LineNumber: 3, CodeSnippet: "res.set('X-Powered-By', 'PHP')"},
+            {Title: "No snippet", FilePath: "app.js", LineNumber: 9},
+            {Title: "No snippet past end", FilePath: "app.js", LineNumber: 40},
+        },
+    }
+
+    stats := verifyFindings(response, codebase)
+
+    if len(response.CriticalRisks) != 1 {
+        t.Fatalf("Expected only the real critical finding to stay critical, got %+v", response.CriticalRisks)
+    }
+    sql := response.CriticalRisks[0]
+    if !sql.Verified || sql.LineNumber != 5 || sql.Line != 5 || sql.Correction != "line corrected from 9 to 5" {
+        t.Errorf("Expected line corrected to 5: %+v", sql)
+    }
+
+    if len(response.HighRisks) != 1 || response.HighRisks[0].Title != "Invented eval" || response.HighRisks[0].Verified {
+        t.Fatalf("Expected unverifiable critical finding downgraded to high: %+v", response.HighRisks)
+    }
+    if correction := response.HighRisks[0].Correction; !strings.Contains(correction, "not found") || !strings.Contains(correction, "downgraded from critical") {
+        t.Errorf("Unexpected correction: %q", correction)
+    }
+
+    if len(response.MediumRisks) != 1 || response.MediumRisks[0].Title != "No snippet" || response.MediumRisks[0].Verified {
+        t.Errorf("Expected only the in-range snippet-less finding kept as unverified: %+v", response.MediumRisks)
+    }
+    if stats.Dropped != 3 || stats.Downgraded != 1 || stats.Verified != 1 || stats.Corrected != 1 {
+        t.Errorf("Unexpected stats: %+v", stats)
+    }
+    if response.Summary.TotalCritical != 1 || response.Summary.TotalHigh != 1 || response.Summary.TotalMedium != 1 {
+        t.Errorf("Summary totals not recomputed: %+v", response.Summary)
+    }
+    if fixes := NewAutoFixEngine().GenerateFixes(fixableRisks(response), codebase); len(fixes) != 1 || fixes[0].LineNumber != 5 || fixes[0].FilePath != "app.js" {
+        t.Errorf("Expected one fix at the corrected location, got %+v", fixes)
+    }
+}
+
+func TestApplyFixRefusesMissingOriginal(t *testing.T) {
+    dir := t.TempDir()
+    if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte(verificationSource), 0644); err != nil {
+        t.Fatal(err)
+    }
+    applier := &GitHubFixApplier{}
+
+    fix := AutoFix{RiskTitle: "Eval", Original: "eval(req.body.code)", Fixed: "// removed"}
+    if err := applier.applyFixToFile(dir, "app.js", 5, fix); err == nil {
+        t.Error("Expected a fix whose original code is missing to be refused")
+    }
+
+    fix = AutoFix{RiskTitle: "Port", Original: "app.listen(3000);", Fixed: "app.listen(process.env.PORT);"}
+    if err := applier.applyFixToFile(dir, "app.js", 2, fix); err != nil {
+        t.Fatalf("Expected fix to apply at the located line: %v", err)
+    }
+    content, _ := os.ReadFile(filepath.Join(dir, "app.js"))
+    lines := strings.Split(string(content), "\n")
+    if lines[1] != "const app = express();" || lines[8] != fix.Fixed {
+        t.Errorf("Fix applied to the wrong line:\n%s", content)
+    }
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Where a finding came from
+const (
+    RiskSourceAI   = "ai"
+    RiskSourceRule = "rule"
+)
+
+// Risk tiers, most severe first - indexes into riskTiers
+const (
+    TierCritical = iota
+    TierHigh
+    TierMedium
+)
+
+var tierNames = []string{"critical", "high", "medium"}
+
+// riskTiers returns pointers to the response's tiers, indexed by Tier*
+func riskTiers(response *AIAnalysisResponse) []*[]Risk {
+    return []*[]Risk{&response.CriticalRisks, &response.HighRisks, &response.MediumRisks}
+}
+
+// ruleFinding is a deterministic finding and the tier it belongs in
+type ruleFinding struct {
+    Risk  Risk
+    Tier  int
+    Match string // the text the rule matched, e.g. the secret itself
+}
+
+// staticRule describes one rule of a static analyzer (IaC, Go AST, ...)
+type staticRule struct {
+    Title       string
+    Description string
+    Impact      string
+    Tier        int
+    CVSS        float64
+    Compliance  []string
+}
+
+// newStaticFinding builds a finding located at a line of content; detail
+// names the offending resource or expression
+func newStaticFinding(path string, content string, line int, ruleID string, rule staticRule, detail string) ruleFinding {
+    snippet := ""
+    if lines := strings.Split(content, "\n"); line >= 1 && line <= len(lines) {
+        snippet = strings.TrimSpace(lines[line-1])
+    }
+    priority := "Medium"
+    switch rule.Tier {
+    case TierCritical:
+        priority = "Immediate"
+    case TierHigh:
+        priority = "High"
+    }
+
+    description := rule.Description
+    if detail != "" {
+        description += " " + detail
+    }
+    return ruleFinding{
+        Tier:  rule.Tier,
+        Match: snippet,
+        Risk: Risk{
+            File:                   path,
+            FilePath:               path,
+            Line:                   line,
+            LineNumber:             line,
+            Title:                  rule.Title,
+            Description:            description,
+            Impact:                 rule.Impact,
+            Confidence:             0.9,
+            CodeSnippet:            snippet,
+            CVSSScore:              rule.CVSS,
+            ExploitationComplexity: "Medium",
+            RemediationPriority:    priority,
+            ComplianceViolations:   rule.Compliance,
+            Source:                 RiskSourceRule,
+            RuleID:                 ruleID,
+            Verified:               true,
+        },
+    }
+}
+
+// mergeRuleFindings adds rule findings to the response. When the AI already
+// reported the same file and line, the two become one risk: the AI's title,
+// impact and compliance context with the rule's ID, source and (redacted)
+// snippet, raised to the rule's tier if that is more severe. Rule findings
+// never replace each other - one line can hold several secrets or a dependency
+// with several advisories - so only the first one on a line absorbs the AI
+// finding. A taint finding also matches an AI finding on any line of its path,
+// since the AI usually flags the source or the concatenation rather than the
+// sink. Returns how many rule findings overlapped an AI finding.
+func mergeRuleFindings(response *AIAnalysisResponse, findings []ruleFinding) int {
+    tiers := riskTiers(response)
+    location := func(risk Risk) string {
+        return fmt.Sprintf("%s:%d", normalizeRiskPath(risk.FilePath), risk.LineNumber)
+    }
+
+    existing := make(map[string]int)
+    for tier, risks := range tiers {
+        for _, risk := range *risks {
+            if risk.Source == RiskSourceRule {
+                continue
+            }
+            if _, seen := existing[location(risk)]; !seen {
+                existing[location(risk)] = tier
+            }
+        }
+    }
+
+    overlaps := 0
+    for _, finding := range findings {
+        key := location(finding.Risk)
+        tier, exists := existing[key]
+        for _, step := range finding.Risk.TaintPath {
+            if exists {
+                break
+            }
+            key = fmt.Sprintf("%s:%d", normalizeRiskPath(finding.Risk.FilePath), step.Line)
+            tier, exists = existing[key]
+        }
+        if !exists {
+            *tiers[finding.Tier] = append(*tiers[finding.Tier], finding.Risk)
+            continue
+        }
+
+        overlaps++
+        delete(existing, key)
+        risks := *tiers[tier]
+        for i, risk := range risks {
+            if location(risk) != key || risk.Source == RiskSourceRule {
+                continue
+            }
+            merged := absorbRuleFinding(risk, finding.Risk)
+            if finding.Tier < tier {
+                // Move the merged finding up to the rule's tier
+                *tiers[tier] = append(append([]Risk{}, risks[:i]...), risks[i+1:]...)
+                *tiers[finding.Tier] = append(*tiers[finding.Tier], merged)
+            } else {
+                risks[i] = merged
+            }
+            break
+        }
+    }
+
+    response.Summary.TotalCritical = len(response.CriticalRisks)
+    response.Summary.TotalHigh = len(response.HighRisks)
+    response.Summary.TotalMedium = len(response.MediumRisks)
+    return overlaps
+}
+
+// absorbRuleFinding gives an AI finding the identity of the rule finding at its
+// location, so suppressions and redaction apply to it like any rule finding
+func absorbRuleFinding(aiRisk Risk, rule Risk) Risk {
+    aiRisk.Source = rule.Source
+    aiRisk.RuleID = rule.RuleID
+    aiRisk.TaintPath = rule.TaintPath
+    aiRisk.Verified = true
+    // The advisory's CVEs and fixed versions feed the SBOM; its score beats the AI's guess
+    if rule.Advisory != nil {
+        aiRisk.Advisory = rule.Advisory
+        aiRisk.CVSSScore = rule.CVSSScore
+    }
+    // The AI's snippet may hold the secret the rule redacted. A taint finding
+    // matched on its path describes another line, so its snippet doesn't fit.
+    if aiRisk.LineNumber == rule.LineNumber {
+        aiRisk.CodeSnippet = rule.CodeSnippet
+    }
+    return aiRisk
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+    "crypto/sha1"
+    "fmt"
+    "net/http"
+    "net/url"
+    "slices"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// SBOM formats served by GetAnalysisSBOM
+const (
+    SBOMFormatCycloneDX = "cyclonedx"
+    SBOMFormatSPDX      = "spdx"
+)
+
+const sbomToolName = "aegis-ai"
+
+// purlTypes maps OSV ecosystems to package URL types
+var purlTypes = map[string]string{
+    EcosystemGo:       "golang",
+    EcosystemNPM:      "npm",
+    EcosystemPyPI:     "pypi",
+    EcosystemMaven:    "maven",
+    EcosystemRubyGems: "gem",
+}
+
+// packageURL builds the purl (https://github.com/package-url/purl-spec) of a dependency
+func packageURL(dependency Dependency) string {
+    name := dependency.Name
+    switch dependency.Ecosystem {
+    case EcosystemPyPI:
+        name = pypiNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+    case EcosystemMaven:
+        name = strings.Replace(name, ":", "/", 1)
+    }
+
+    segments := strings.Split(name, "/")
+    for i, segment := range segments {
+        segments[i] = purlEscape(segment)
+    }
+    return fmt.Sprintf("pkg:%s/%s@%s", purlTypes[dependency.Ecosystem], strings.Join(segments, "/"), purlEscape(dependency.Version))
+}
+
+// purlEscape percent-encodes a purl segment; "@" separates the version so it is encoded too (npm scopes)
+func purlEscape(segment string) string {
+    return strings.ReplaceAll(url.PathEscape(segment), "@", "%40")
+}
+
+// sbomPackage is one component - a package version found in one or more manifests
+type sbomPackage struct {
+    Dependency
+    PURL      string
+    Manifests []string
+}
+
+// sbomPackages dedupes the inventory by purl, ordered by purl for stable documents
+func sbomPackages(dependencies []Dependency) []*sbomPackage {
+    byPURL := make(map[string]*sbomPackage)
+    var packages []*sbomPackage
+    for _, dependency := range dependencies {
+        purl := packageURL(dependency)
+        if existing, seen := byPURL[purl]; seen {
+            existing.Direct = existing.Direct || dependency.Direct
+            existing.Manifests = append(existing.Manifests, dependency.Manifest)
+            continue
+        }
+        pkg := &sbomPackage{Dependency: dependency, PURL: purl, Manifests: []string{dependency.Manifest}}
+        byPURL[purl] = pkg
+        packages = append(packages, pkg)
+    }
+    sort.Slice(packages, func(i, j int) bool { return packages[i].PURL < packages[j].PURL })
+    return packages
+}
+
+// sbomTimestamp is when the scan finished, so a document can be regenerated identically
+func sbomTimestamp(record *ScanRecord) string {
+    for _, value := range []string{record.CompletedAt, record.UpdatedAt, record.CreatedAt} {
+        if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+            return parsed.UTC().Format(time.RFC3339)
+        }
+    }
+    return time.Now().UTC().Format(time.RFC3339)
+}
+
+// sbomUUID derives a stable name-based UUID (version 5 layout) from the analysis ID
+func sbomUUID(analysisID string) string {
+    sum := sha1.Sum([]byte("aegis-ai/sbom/" + analysisID))
+    sum[6] = (sum[6] & 0x0f) | 0x50
+    sum[8] = (sum[8] & 0x3f) | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func sbomSubjectName(record *ScanRecord) string {
+    if record.RepoName != "" {
+        return record.RepoName
+    }
+    return extractRepoName(record.RepoURL)
+}
+
+// CycloneDX 1.5 JSON (https://cyclonedx.org/docs/1.5/json/)
+type cycloneDXDocument struct {
+    BOMFormat       string                   `json:"bomFormat"`
+    SpecVersion     string                   `json:"specVersion"`
+    SerialNumber    string                   `json:"serialNumber"`
+    Version         int                      `json:"version"`
+    Metadata        cycloneDXMetadata        `json:"metadata"`
+    Components      []cycloneDXComponent     `json:"components"`
+    Dependencies    []cycloneDXDependency    `json:"dependencies"`
+    Vulnerabilities []cycloneDXVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type cycloneDXMetadata struct {
+    Timestamp string `json:"timestamp"`
+    Tools     struct {
+        Components []cycloneDXComponent `json:"components"`
+    } `json:"tools"`
+    Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+    Type       string              `json:"type"`
+    BOMRef     string              `json:"bom-ref,omitempty"`
+    Group      string              `json:"group,omitempty"`
+    Name       string              `json:"name"`
+    Version    string              `json:"version,omitempty"`
+    PURL       string              `json:"purl,omitempty"`
+    Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+    Name  string `json:"name"`
+    Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+    Ref       string   `json:"ref"`
+    DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+type cycloneDXVulnerability struct {
+    ID             string                `json:"id"`
+    Source         cycloneDXSource       `json:"source"`
+    References     []cycloneDXReference  `json:"references,omitempty"`
+    Ratings        []cycloneDXRating     `json:"ratings,omitempty"`
+    Description    string                `json:"description,omitempty"`
+    Recommendation string                `json:"recommendation,omitempty"`
+    Affects        []cycloneDXDependency `json:"affects"`
+}
+
+type cycloneDXSource struct {
+    Name string `json:"name"`
+    URL  string `json:"url,omitempty"`
+}
+
+type cycloneDXReference struct {
+    ID     string          `json:"id"`
+    Source cycloneDXSource `json:"source"`
+}
+
+type cycloneDXRating struct {
+    Score    float64 `json:"score,omitempty"`
+    Severity string  `json:"severity"`
+    Method   string  `json:"method,omitempty"`
+    Vector   string  `json:"vector,omitempty"`
+}
+
+// cvssSeverity maps a CVSS base score to its qualitative rating
+func cvssSeverity(score float64) string {
+    switch {
+    case score >= 9:
+        return "critical"
+    case score >= 7:
+        return "high"
+    case score >= 4:
+        return "medium"
+    case score > 0:
+        return "low"
+    }
+    return "unknown"
+}
+
+// cvssMethod names the CycloneDX rating method for a vector; "other" when the
+// score was derived from a severity label rather than computed from a vector
+func cvssMethod(vector string) string {
+    switch {
+    case strings.HasPrefix(vector, "CVSS:3.1/"):
+        return "CVSSv31"
+    case strings.HasPrefix(vector, "CVSS:3.0/"):
+        return "CVSSv3"
+    }
+    return "other"
+}
+
+// buildCycloneDX renders the scan's dependency inventory, including the known
+// vulnerabilities found by the dependency scan
+func buildCycloneDX(record *ScanRecord) cycloneDXDocument {
+    subject := sbomSubjectName(record)
+    document := cycloneDXDocument{
+        BOMFormat:    "CycloneDX",
+        SpecVersion:  "1.5",
+        SerialNumber: "urn:uuid:" + sbomUUID(record.ID),
+        Version:      1,
+        Components:   []cycloneDXComponent{},
+    }
+    document.Metadata.Timestamp = sbomTimestamp(record)
+    document.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: sbomToolName}}
+    document.Metadata.Component = cycloneDXComponent{Type: "application", BOMRef: "root", Name: subject, Version: record.CommitSHA}
+
+    root := cycloneDXDependency{Ref: "root"}
+    for _, pkg := range sbomPackages(record.Dependencies) {
+        component := cycloneDXComponent{Type: "library", BOMRef: pkg.PURL, Name: pkg.Name, Version: pkg.Version, PURL: pkg.PURL}
+        if pkg.Ecosystem == EcosystemMaven {
+            component.Group, component.Name, _ = strings.Cut(pkg.Name, ":")
+        }
+        for _, manifest := range pkg.Manifests {
+            component.Properties = append(component.Properties, cycloneDXProperty{Name: "aegis:manifest", Value: manifest})
+        }
+        if pkg.Direct {
+            root.DependsOn = append(root.DependsOn, pkg.PURL)
+        }
+        document.Components = append(document.Components, component)
+    }
+    document.Dependencies = []cycloneDXDependency{root}
+
+    // One entry per advisory, affecting every package version it was found in
+    byID := make(map[string]int)
+    for _, risk := range combineAllRisks(&record.AIAnalysisResponse) {
+        if risk.Advisory == nil {
+            continue
+        }
+        advisory := risk.Advisory
+        affected := cycloneDXDependency{Ref: packageURL(Dependency{Ecosystem: advisory.Ecosystem, Name: advisory.Package, Version: advisory.Version})}
+        if index, seen := byID[advisory.ID]; seen {
+            vulnerability := &document.Vulnerabilities[index]
+            if !slices.ContainsFunc(vulnerability.Affects, func(other cycloneDXDependency) bool { return other.Ref == affected.Ref }) {
+                vulnerability.Affects = append(vulnerability.Affects, affected)
+            }
+            continue
+        }
+        rating := cycloneDXRating{Score: risk.CVSSScore, Severity: cvssSeverity(risk.CVSSScore), Method: cvssMethod(advisory.CVSSVector), Vector: advisory.CVSSVector}
+        vulnerability := cycloneDXVulnerability{
+            ID:             advisory.ID,
+            Source:         cycloneDXSource{Name: "OSV", URL: "https://osv.dev/vulnerability/" + advisory.ID},
+            Ratings:        []cycloneDXRating{rating},
+            Description:    risk.Description,
+            Recommendation: risk.Impact,
+            Affects:        []cycloneDXDependency{affected},
+        }
+        for _, cve := range advisory.CVEs {
+            if cve != advisory.ID {
+                vulnerability.References = append(vulnerability.References, cycloneDXReference{
+                    ID: cve, Source: cycloneDXSource{Name: "NVD", URL: "https://nvd.nist.gov/vuln/detail/" + cve},
+                })
+            }
+        }
+        byID[advisory.ID] = len(document.Vulnerabilities)
+        document.Vulnerabilities = append(document.Vulnerabilities, vulnerability)
+    }
+    return document
+}
+
+// SPDX 2.3 JSON (https://spdx.github.io/spdx-spec/v2.3/)
+type spdxDocument struct {
+    SPDXVersion       string             `json:"spdxVersion"`
+    DataLicense       string             `json:"dataLicense"`
+    SPDXID            string             `json:"SPDXID"`
+    Name              string             `json:"name"`
+    DocumentNamespace string             `json:"documentNamespace"`
+    CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+    DocumentDescribes []string           `json:"documentDescribes"`
+    Packages          []spdxPackage      `json:"packages"`
+    Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+    Created  string   `json:"created"`
+    Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+    Name             string            `json:"name"`
+    SPDXID           string            `json:"SPDXID"`
+    VersionInfo      string            `json:"versionInfo,omitempty"`
+    DownloadLocation string            `json:"downloadLocation"`
+    FilesAnalyzed    bool              `json:"filesAnalyzed"`
+    LicenseConcluded string            `json:"licenseConcluded"`
+    LicenseDeclared  string            `json:"licenseDeclared"`
+    CopyrightText    string            `json:"copyrightText"`
+    SourceInfo       string            `json:"sourceInfo,omitempty"`
+    ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+    ReferenceCategory string `json:"referenceCategory"`
+    ReferenceType     string `json:"referenceType"`
+    ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+    SPDXElementID      string `json:"spdxElementId"`
+    RelationshipType   string `json:"relationshipType"`
+    RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// buildSPDX renders the scan's dependency inventory. Licenses aren't read
+// from the manifests, so they are NOASSERTION.
+func buildSPDX(record *ScanRecord) spdxDocument {
+    subject := sbomSubjectName(record)
+    rootLocation := "NOASSERTION"
+    if record.RepoURL != "" {
+        rootLocation = "git+" + record.RepoURL
+    }
+    root := spdxPackage{
+        Name: subject, SPDXID: "SPDXRef-RootPackage", VersionInfo: record.CommitSHA, DownloadLocation: rootLocation,
+        LicenseConcluded: "NOASSERTION", LicenseDeclared: "NOASSERTION", CopyrightText: "NOASSERTION",
+    }
+    document := spdxDocument{
+        SPDXVersion:       "SPDX-2.3",
+        DataLicense:       "CC0-1.0",
+        SPDXID:            "SPDXRef-DOCUMENT",
+        Name:              subject,
+        DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/%s-%s", url.PathEscape(strings.ReplaceAll(subject, "/", "-")), sbomUUID(record.ID)),
+        CreationInfo:      spdxCreationInfo{Created: sbomTimestamp(record), Creators: []string{"Tool: " + sbomToolName}},
+        DocumentDescribes: []string{root.SPDXID},
+        Packages:          []spdxPackage{root},
+        Relationships: []spdxRelationship{
+            {SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: root.SPDXID},
+        },
+    }
+
+    for i, pkg := range sbomPackages(record.Dependencies) {
+        id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+        document.Packages = append(document.Packages, spdxPackage{
+            Name: pkg.Name, SPDXID: id, VersionInfo: pkg.Version, DownloadLocation: "NOASSERTION",
+            LicenseConcluded: "NOASSERTION", LicenseDeclared: "NOASSERTION", CopyrightText: "NOASSERTION",
+            SourceInfo:   "declared in " + strings.Join(pkg.Manifests, ", "),
+            ExternalRefs: []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: pkg.PURL}},
+        })
+        document.Relationships = append(document.Relationships, spdxRelationship{
+            SPDXElementID: root.SPDXID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id,
+        })
+    }
+    return document
+}
+
+// GetAnalysisSBOM downloads the scan's software bill of materials
+// (GET /api/analysis/:id/sbom?format=cyclonedx|spdx)
+func GetAnalysisSBOM(c *gin.Context) {
+    analysisID := c.Param("id")
+    format := strings.ToLower(c.DefaultQuery("format", SBOMFormatCycloneDX))
+    if format != SBOMFormatCycloneDX && format != SBOMFormatSPDX {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported SBOM format, use cyclonedx or spdx"})
+        return
+    }
+
+    analysis, err := analysisService.Get(analysisID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Analysis not found"})
+        return
+    }
+    if analysis.Status != StatusCompleted {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Analysis not completed"})
+        return
+    }
+
+    filename := fmt.Sprintf("%s-%s.%s.json", strings.ReplaceAll(sbomSubjectName(analysis), "/", "-"), analysisID, format)
+    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+    if format == SBOMFormatSPDX {
+        c.JSON(http.StatusOK, buildSPDX(analysis))
+        return
+    }
+    c.Header("Content-Type", "application/vnd.cyclonedx+json; version=1.5")
+    c.JSON(http.StatusOK, buildCycloneDX(analysis))
+}
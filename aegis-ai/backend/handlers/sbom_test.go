@@ -0,0 +1,120 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestPackageURL(t *testing.T) {
+    cases := map[string]Dependency{
+        "pkg:golang/golang.org/x/net@v0.10.0":                  {Ecosystem: EcosystemGo, Name: "golang.org/x/net", Version: "v0.10.0"},
+        "pkg:npm/%40babel/core@7.22.0":                         {Ecosystem: EcosystemNPM, Name: "@babel/core", Version: "7.22.0"},
+        "pkg:pypi/typing-extensions@4.7.1":                     {Ecosystem: EcosystemPyPI, Name: "Typing_Extensions", Version: "4.7.1"},
+        "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1": {Ecosystem: EcosystemMaven, Name: "org.apache.logging.log4j:log4j-core", Version: "2.14.1"},
+        "pkg:gem/rack@2.2.3":                                   {Ecosystem: EcosystemRubyGems, Name: "rack", Version: "2.2.3"},
+    }
+    for expected, dependency := range cases {
+        if purl := packageURL(dependency); purl != expected {
+            t.Errorf("packageURL(%+v) = %s, expected %s", dependency, purl, expected)
+        }
+    }
+}
+
+// qsRisk is a qs finding whose advisory only carries a severity label, no CVSS vector
+func qsRisk(manifest string, line int) Risk {
+    return Risk{
+        FilePath: manifest, LineNumber: line, Title: "Vulnerable dependency", CVSSScore: 5.5, Source: RiskSourceRule,
+        Advisory: &DependencyAdvisory{ID: "GHSA-hrpp-h998-j3pp", Ecosystem: EcosystemNPM, Package: "qs", Version: "6.11.0"},
+    }
+}
+
+func TestAnalysisSBOM(t *testing.T) {
+    service := useTestServices(t, nil)
+    record, err := service.Create(TriggerManual, "https://github.com/acme/payments.git", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    log4j := Dependency{Ecosystem: EcosystemMaven, Name: "org.apache.logging.log4j:log4j-core", Version: "2.14.1", Manifest: "pom.xml", Line: 9, Direct: true}
+    analysis := &AIAnalysisResponse{
+        Dependencies: []Dependency{
+            log4j,
+            {Ecosystem: EcosystemNPM, Name: "qs", Version: "6.11.0", Manifest: "web/package-lock.json", Line: 7},
+            {Ecosystem: EcosystemNPM, Name: "qs", Version: "6.11.0", Manifest: "admin/package-lock.json", Line: 12},
+        },
+        CriticalRisks: []Risk{{
+            FilePath: "pom.xml", LineNumber: 9, Title: "Vulnerable dependency", CVSSScore: 10, Source: RiskSourceRule,
+            Advisory: &DependencyAdvisory{ID: "GHSA-jfh8-c2jp-5v3q", CVEs: []string{"CVE-2021-44228"}, Ecosystem: EcosystemMaven,
+                Package: log4j.Name, Version: log4j.Version, Fixed: []string{"2.15.0"}, CVSSVector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H"},
+        }},
+        MediumRisks: []Risk{qsRisk("web/package-lock.json", 7), qsRisk("admin/package-lock.json", 12)},
+    }
+    service.SetStatus(record.ID, StatusAnalyzing)
+    if err := service.Complete(record.ID, analysis); err != nil {
+        t.Fatal(err)
+    }
+    router := newTestRouter()
+
+    get := func(query string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/analysis/"+record.ID+"/sbom"+query, nil))
+        return w
+    }
+
+    w := get("")
+    if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/vnd.cyclonedx+json") {
+        t.Fatalf("Expected a CycloneDX document, got %d %s: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+    }
+    if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "acme-payments-"+record.ID+".cyclonedx.json") {
+        t.Errorf("Unexpected download name %q", disposition)
+    }
+    var bom cycloneDXDocument
+    if err := json.Unmarshal(w.Body.Bytes(), &bom); err != nil {
+        t.Fatal(err)
+    }
+    if bom.BOMFormat != "CycloneDX" || bom.Metadata.Component.Name != "acme/payments" || len(bom.Components) != 2 {
+        t.Fatalf("Expected 2 deduplicated components for acme/payments, got %+v", bom)
+    }
+    if qs := bom.Components[1]; qs.PURL != "pkg:npm/qs@6.11.0" || len(qs.Properties) != 2 {
+        t.Errorf("Expected qs with both manifests, got %+v", qs)
+    }
+    if log := bom.Components[0]; log.Group != "org.apache.logging.log4j" || log.Name != "log4j-core" {
+        t.Errorf("Expected the Maven group to be split out, got %+v", log)
+    }
+    if deps := bom.Dependencies[0].DependsOn; len(deps) != 1 || deps[0] != "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1" {
+        t.Errorf("Expected the root to depend directly on log4j only, got %v", deps)
+    }
+    if len(bom.Vulnerabilities) != 2 || bom.Vulnerabilities[0].Affects[0].Ref != bom.Components[0].BOMRef ||
+        bom.Vulnerabilities[0].Ratings[0].Severity != "critical" || bom.Vulnerabilities[0].References[0].ID != "CVE-2021-44228" {
+        t.Fatalf("Expected the log4j vulnerability to reference its component, got %+v", bom.Vulnerabilities)
+    }
+    if rating := bom.Vulnerabilities[0].Ratings[0]; rating.Method != "CVSSv31" || rating.Vector == "" {
+        t.Errorf("Expected the log4j rating computed from its CVSS vector, got %+v", rating)
+    }
+    // qs was found in two lockfiles: one vulnerability, one affected component
+    if qs := bom.Vulnerabilities[1]; qs.ID != "GHSA-hrpp-h998-j3pp" || len(qs.Affects) != 1 || qs.Ratings[0].Method != "other" || qs.Ratings[0].Vector != "" {
+        t.Errorf("Expected one qs entry rated by severity label, got %+v", qs)
+    }
+    if again := get("?format=cyclonedx"); again.Body.String() != w.Body.String() {
+        t.Error("Expected the same document on every download")
+    }
+
+    w = get("?format=spdx")
+    var spdx spdxDocument
+    if err := json.Unmarshal(w.Body.Bytes(), &spdx); err != nil || w.Code != http.StatusOK {
+        t.Fatalf("Expected an SPDX document, got %d: %s", w.Code, w.Body.String())
+    }
+    if spdx.SPDXVersion != "SPDX-2.3" || len(spdx.Packages) != 3 || len(spdx.Relationships) != 3 {
+        t.Fatalf("Expected a root and 2 packages with relationships, got %+v", spdx)
+    }
+    if ref := spdx.Packages[2].ExternalRefs[0]; ref.ReferenceType != "purl" || ref.ReferenceLocator != "pkg:npm/qs@6.11.0" {
+        t.Errorf("Expected the purl external reference, got %+v", ref)
+    }
+
+    if w := get("?format=swid"); w.Code != http.StatusBadRequest {
+        t.Errorf("Expected unsupported formats to be rejected, got %d", w.Code)
+    }
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+    "time"
+)
+
+// Scan status lifecycle: queued -> cloning -> analyzing -> completed/failed/failed_parse/cancelled/timed_out
+const (
+    StatusQueued      = "queued"
+    StatusCloning     = "cloning"
+    StatusAnalyzing   = "analyzing"
+    StatusCompleted   = "completed"
+    StatusFailed      = "failed"
+    StatusFailedParse = "failed_parse" // the AI never returned valid JSON
+    StatusCancelled   = "cancelled"
+    StatusTimedOut    = "timed_out"
+)
+
+func timestamp() string {
+    return time.Now().UTC().Format(time.RFC3339)
+}
+
+// isFinalStatus reports whether a scan has stopped running
+func isFinalStatus(status string) bool {
+    switch status {
+    case StatusCompleted, StatusFailed, StatusFailedParse, StatusCancelled, StatusTimedOut:
+        return true
+    }
+    return false
+}
+
+// newScanRecord builds a queued record for a scan that is about to be scheduled
+func newScanRecord(id, trigger, repoURL string) *ScanRecord {
+    now := timestamp()
+    return &ScanRecord{
+        ID:        id,
+        RepoURL:   repoURL,
+        RepoName:  extractRepoName(repoURL),
+        Trigger:   trigger,
+        Status:    StatusQueued,
+        CreatedAt: now,
+        UpdatedAt: now,
+    }
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+    "context"
+    "sync"
+)
+
+const defaultScanWorkers = 2
+
+// ScanJob is a queued scan - Key groups jobs for the same repo (and PR)
+type ScanJob struct {
+    AnalysisID string
+    Key        string
+    Process    func(ctx context.Context, analysisID string) error
+}
+
+// QueueStats exposes queue depth for the API
+type QueueStats struct {
+    Workers int `json:"workers"`
+    Active  int `json:"active"`
+    Queued  int `json:"queued"`
+}
+
+// ScanQueue runs scans on a fixed number of workers so bursts of
+// webhook events can't launch unbounded clones and AI calls
+type ScanQueue struct {
+    mu      sync.Mutex
+    cond    *sync.Cond
+    pending []*ScanJob
+    workers int
+    active  int
+    start   sync.Once
+}
+
+// NewScanQueue creates a queue with the given number of workers
+// (workers <= 0 uses the default). Workers start with the first job.
+func NewScanQueue(workers int) *ScanQueue {
+    if workers <= 0 {
+        workers = defaultScanWorkers
+    }
+
+    q := &ScanQueue{workers: workers}
+    q.cond = sync.NewCond(&q.mu)
+    return q
+}
+
+// Enqueue adds a job and returns the queued job it superseded, if any.
+// A newer job with the same key drops the queued one from the queue.
+func (q *ScanQueue) Enqueue(job *ScanJob) *ScanJob {
+    q.start.Do(func() {
+        for i := 0; i < q.workers; i++ {
+            go q.worker()
+        }
+    })
+
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    var superseded *ScanJob
+    if job.Key != "" {
+        for i, queued := range q.pending {
+            if queued.Key == job.Key {
+                superseded = queued
+                q.pending = append(q.pending[:i], q.pending[i+1:]...)
+                break
+            }
+        }
+    }
+
+    q.pending = append(q.pending, job)
+    q.cond.Signal()
+    return superseded
+}
+
+// Remove drops a queued job so it never runs, reporting whether it was queued
+func (q *ScanQueue) Remove(analysisID string) bool {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    for i, job := range q.pending {
+        if job.AnalysisID == analysisID {
+            q.pending = append(q.pending[:i], q.pending[i+1:]...)
+            return true
+        }
+    }
+    return false
+}
+
+// Position returns the 1-based queue position of an analysis, or 0 if not queued
+func (q *ScanQueue) Position(analysisID string) int {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    for i, job := range q.pending {
+        if job.AnalysisID == analysisID {
+            return i + 1
+        }
+    }
+    return 0
+}
+
+func (q *ScanQueue) Stats() QueueStats {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    return QueueStats{
+        Workers: q.workers,
+        Active:  q.active,
+        Queued:  len(q.pending),
+    }
+}
+
+func (q *ScanQueue) worker() {
+    for {
+        q.mu.Lock()
+        for len(q.pending) == 0 {
+            q.cond.Wait()
+        }
+        job := q.pending[0]
+        q.pending = q.pending[1:]
+        q.active++
+        q.mu.Unlock()
+
+        job.Process(context.Background(), job.AnalysisID)
+
+        q.mu.Lock()
+        q.active--
+        q.mu.Unlock()
+    }
+}
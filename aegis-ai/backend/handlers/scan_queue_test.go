@@ -0,0 +1,57 @@
+package handlers
+
+import (
+    "context"
+    "sync"
+    "testing"
+)
+
+func TestScanQueueSupersedesQueuedJob(t *testing.T) {
+    queue := NewScanQueue(1)
+
+    // Block the only worker so later jobs stay queued
+    release := make(chan struct{})
+    started := make(chan struct{})
+    queue.Enqueue(&ScanJob{AnalysisID: "running", Key: "repo#1", Process: func(context.Context, string) error {
+        close(started)
+        <-release
+        return nil
+    }})
+    <-started
+
+    var mu sync.Mutex
+    var ran []string
+    var wg sync.WaitGroup
+    record := func(_ context.Context, analysisID string) error {
+        mu.Lock()
+        ran = append(ran, analysisID)
+        mu.Unlock()
+        wg.Done()
+        return nil
+    }
+
+    wg.Add(2)
+    if superseded := queue.Enqueue(&ScanJob{AnalysisID: "old", Key: "repo#1", Process: record}); superseded != nil {
+        t.Fatalf("Running job must not be superseded, got %s", superseded.AnalysisID)
+    }
+    queue.Enqueue(&ScanJob{AnalysisID: "other", Key: "repo#2", Process: record})
+    superseded := queue.Enqueue(&ScanJob{AnalysisID: "new", Key: "repo#1", Process: record})
+    if superseded == nil || superseded.AnalysisID != "old" {
+        t.Fatalf("Expected queued job 'old' to be superseded, got %+v", superseded)
+    }
+
+    stats := queue.Stats()
+    if stats.Workers != 1 || stats.Active != 1 || stats.Queued != 2 {
+        t.Errorf("Unexpected stats: %+v", stats)
+    }
+    if pos := queue.Position("new"); pos != 2 {
+        t.Errorf("Expected 'new' at position 2, got %d", pos)
+    }
+
+    close(release)
+    wg.Wait()
+
+    if len(ran) != 2 || ran[0] != "other" || ran[1] != "new" {
+        t.Errorf("Unexpected run order: %v", ran)
+    }
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+    "sync"
+)
+
+// SessionStore keeps OAuth sessions and tokens behind a lock since the
+// callback writes them while other requests read them
+type SessionStore struct {
+    mu       sync.RWMutex
+    sessions map[string]*GitHubUser
+    tokens   map[string]string // user login -> access token
+}
+
+func NewSessionStore() *SessionStore {
+    return &SessionStore{
+        sessions: make(map[string]*GitHubUser),
+        tokens:   make(map[string]string),
+    }
+}
+
+// Create stores the user and token and returns a new session ID
+func (s *SessionStore) Create(user *GitHubUser, token string) string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    sessionID := newSessionToken()
+    s.sessions[sessionID] = user
+    s.tokens[user.Login] = token
+    return sessionID
+}
+
+func (s *SessionStore) User(sessionID string) (*GitHubUser, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    user, exists := s.sessions[sessionID]
+    return user, exists
+}
+
+func (s *SessionStore) Token(login string) (string, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    token, exists := s.tokens[login]
+    return token, exists
+}
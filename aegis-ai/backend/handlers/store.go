@@ -0,0 +1,30 @@
+package handlers
+
+import (
+    "errors"
+    "sort"
+)
+
+// ErrAnalysisNotFound is returned by an AnalysisStore when the ID is unknown
+var ErrAnalysisNotFound = errors.New("analysis not found")
+
+// AnalysisStore persists analyses so scans and fix indexes survive a restart.
+// Implementations must be safe for concurrent use.
+type AnalysisStore interface {
+    Get(id string) (*ScanRecord, error)
+    Put(analysis *ScanRecord) error
+    List() ([]*ScanRecord, error)
+    UpdateStatus(id string, status string) error
+    // Update applies fn to the stored record atomically and saves the result
+    Update(id string, fn func(analysis *ScanRecord) error) error
+}
+
+// sortAnalyses orders analyses oldest first so listings are stable
+func sortAnalyses(list []*ScanRecord) {
+    sort.Slice(list, func(i, j int) bool {
+        if list[i].CreatedAt != list[j].CreatedAt {
+            return list[i].CreatedAt < list[j].CreatedAt
+        }
+        return list[i].ID < list[j].ID
+    })
+}
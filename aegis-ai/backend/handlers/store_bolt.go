@@ -0,0 +1,116 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    bolt "go.etcd.io/bbolt"
+)
+
+var analysesBucket = []byte("analyses")
+
+// BoltAnalysisStore is the embedded on-disk store backed by a bbolt file
+type BoltAnalysisStore struct {
+    db *bolt.DB
+}
+
+// NewBoltAnalysisStore opens (or creates) the database file at path
+func NewBoltAnalysisStore(path string) (*BoltAnalysisStore, error) {
+    db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+    if err != nil {
+        return nil, fmt.Errorf("failed to open analysis database %s: %v", path, err)
+    }
+
+    err = db.Update(func(tx *bolt.Tx) error {
+        _, err := tx.CreateBucketIfNotExists(analysesBucket)
+        return err
+    })
+    if err != nil {
+        db.Close()
+        return nil, fmt.Errorf("failed to initialize analysis database: %v", err)
+    }
+
+    return &BoltAnalysisStore{db: db}, nil
+}
+
+func (s *BoltAnalysisStore) Close() error {
+    return s.db.Close()
+}
+
+func (s *BoltAnalysisStore) Get(id string) (*ScanRecord, error) {
+    var analysis ScanRecord
+    err := s.db.View(func(tx *bolt.Tx) error {
+        data := tx.Bucket(analysesBucket).Get([]byte(id))
+        if data == nil {
+            return ErrAnalysisNotFound
+        }
+        return json.Unmarshal(data, &analysis)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return &analysis, nil
+}
+
+func (s *BoltAnalysisStore) Put(analysis *ScanRecord) error {
+    data, err := json.Marshal(analysis)
+    if err != nil {
+        return fmt.Errorf("failed to encode analysis %s: %v", analysis.ID, err)
+    }
+
+    return s.db.Update(func(tx *bolt.Tx) error {
+        return tx.Bucket(analysesBucket).Put([]byte(analysis.ID), data)
+    })
+}
+
+func (s *BoltAnalysisStore) List() ([]*ScanRecord, error) {
+    var list []*ScanRecord
+    err := s.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(analysesBucket).ForEach(func(_, data []byte) error {
+            var analysis ScanRecord
+            if err := json.Unmarshal(data, &analysis); err != nil {
+                return err
+            }
+            list = append(list, &analysis)
+            return nil
+        })
+    })
+    if err != nil {
+        return nil, err
+    }
+    sortAnalyses(list)
+    return list, nil
+}
+
+func (s *BoltAnalysisStore) UpdateStatus(id string, status string) error {
+    return s.Update(id, func(analysis *ScanRecord) error {
+        analysis.Status = status
+        analysis.UpdatedAt = timestamp()
+        return nil
+    })
+}
+
+func (s *BoltAnalysisStore) Update(id string, fn func(analysis *ScanRecord) error) error {
+    return s.db.Update(func(tx *bolt.Tx) error {
+        bucket := tx.Bucket(analysesBucket)
+        data := bucket.Get([]byte(id))
+        if data == nil {
+            return ErrAnalysisNotFound
+        }
+
+        var analysis ScanRecord
+        if err := json.Unmarshal(data, &analysis); err != nil {
+            return err
+        }
+        if err := fn(&analysis); err != nil {
+            return err
+        }
+
+        updated, err := json.Marshal(&analysis)
+        if err != nil {
+            return err
+        }
+        return bucket.Put([]byte(id), updated)
+    })
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+    "sync"
+)
+
+// MemoryAnalysisStore keeps analyses in a map - used for tests and as a fallback
+type MemoryAnalysisStore struct {
+    mu       sync.RWMutex
+    analyses map[string]*ScanRecord
+}
+
+func NewMemoryAnalysisStore() *MemoryAnalysisStore {
+    return &MemoryAnalysisStore{
+        analyses: make(map[string]*ScanRecord),
+    }
+}
+
+func (s *MemoryAnalysisStore) Get(id string) (*ScanRecord, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    analysis, exists := s.analyses[id]
+    if !exists {
+        return nil, ErrAnalysisNotFound
+    }
+    copied := *analysis
+    return &copied, nil
+}
+
+func (s *MemoryAnalysisStore) Put(analysis *ScanRecord) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    copied := *analysis
+    s.analyses[analysis.ID] = &copied
+    return nil
+}
+
+func (s *MemoryAnalysisStore) List() ([]*ScanRecord, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    list := make([]*ScanRecord, 0, len(s.analyses))
+    for _, analysis := range s.analyses {
+        copied := *analysis
+        list = append(list, &copied)
+    }
+    sortAnalyses(list)
+    return list, nil
+}
+
+func (s *MemoryAnalysisStore) UpdateStatus(id string, status string) error {
+    return s.Update(id, func(analysis *ScanRecord) error {
+        analysis.Status = status
+        analysis.UpdatedAt = timestamp()
+        return nil
+    })
+}
+
+func (s *MemoryAnalysisStore) Update(id string, fn func(analysis *ScanRecord) error) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    analysis, exists := s.analyses[id]
+    if !exists {
+        return ErrAnalysisNotFound
+    }
+    copied := *analysis
+    if err := fn(&copied); err != nil {
+        return err
+    }
+    s.analyses[id] = &copied
+    return nil
+}
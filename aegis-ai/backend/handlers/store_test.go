@@ -0,0 +1,89 @@
+package handlers
+
+import (
+    "path/filepath"
+    "testing"
+)
+
+func TestAnalysisStores(t *testing.T) {
+    bolt, err := NewBoltAnalysisStore(filepath.Join(t.TempDir(), "aegis.db"))
+    if err != nil {
+        t.Fatalf("Failed to open bolt store: %v", err)
+    }
+    defer bolt.Close()
+
+    stores := map[string]AnalysisStore{
+        "memory": NewMemoryAnalysisStore(),
+        "bolt":   bolt,
+    }
+
+    for name, store := range stores {
+        t.Run(name, func(t *testing.T) {
+            if _, err := store.Get("missing"); err != ErrAnalysisNotFound {
+                t.Errorf("Expected ErrAnalysisNotFound, got %v", err)
+            }
+
+            second := &ScanRecord{ID: "analysis_2", Status: StatusQueued, CreatedAt: "2024-01-02T00:00:00Z"}
+            first := &ScanRecord{
+                ID:        "analysis_1",
+                Status:    StatusCompleted,
+                CreatedAt: "2024-01-01T00:00:00Z",
+                AIAnalysisResponse: AIAnalysisResponse{
+                    AutoFixes: []AutoFix{{RiskTitle: "Hardcoded Password"}},
+                },
+            }
+            for _, analysis := range []*ScanRecord{second, first} {
+                if err := store.Put(analysis); err != nil {
+                    t.Fatalf("Put failed: %v", err)
+                }
+            }
+
+            got, err := store.Get("analysis_1")
+            if err != nil {
+                t.Fatalf("Get failed: %v", err)
+            }
+            if len(got.AutoFixes) != 1 || got.AutoFixes[0].RiskTitle != "Hardcoded Password" {
+                t.Errorf("Auto-fixes not round-tripped: %+v", got.AutoFixes)
+            }
+
+            if err := store.UpdateStatus("analysis_2", StatusFailed); err != nil {
+                t.Fatalf("UpdateStatus failed: %v", err)
+            }
+            if err := store.UpdateStatus("missing", "failed"); err != ErrAnalysisNotFound {
+                t.Errorf("Expected ErrAnalysisNotFound, got %v", err)
+            }
+
+            list, err := store.List()
+            if err != nil {
+                t.Fatalf("List failed: %v", err)
+            }
+            if len(list) != 2 || list[0].ID != "analysis_1" || list[1].ID != "analysis_2" {
+                t.Fatalf("Unexpected listing order: %+v", list)
+            }
+            if list[1].Status != StatusFailed {
+                t.Errorf("Expected status failed, got %s", list[1].Status)
+            }
+        })
+    }
+}
+
+func TestBoltStorePersistsAcrossReopen(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "aegis.db")
+
+    store, err := NewBoltAnalysisStore(path)
+    if err != nil {
+        t.Fatalf("Failed to open bolt store: %v", err)
+    }
+    store.Put(&ScanRecord{ID: "analysis_1", Status: StatusCompleted})
+    store.Close()
+
+    reopened, err := NewBoltAnalysisStore(path)
+    if err != nil {
+        t.Fatalf("Failed to reopen bolt store: %v", err)
+    }
+    defer reopened.Close()
+
+    if _, err := reopened.Get("analysis_1"); err != nil {
+        t.Errorf("Analysis lost after reopen: %v", err)
+    }
+}
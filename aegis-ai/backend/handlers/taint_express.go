@@ -0,0 +1,536 @@
+package handlers
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+)
+
+// JavaScript token kinds
+const (
+    jsIdent = iota
+    jsString
+    jsTemplate
+    jsNumber
+    jsPunct
+)
+
+type jsToken struct {
+    Text string
+    Kind int
+    Line int
+}
+
+var jsOperators = []string{"===", "!==", "...", "**=", "=>", "==", "!=", "<=", ">=", "+=", "-=", "*=", "/=", "&&", "||", "??", "?.", "++", "--"}
+
+// jsRegexPrefix holds the tokens after which a slash starts a regex literal
+var jsRegexPrefix = map[string]bool{
+    "(": true, ",": true, "=": true, ":": true, "[": true, "!": true, "&&": true, "||": true, "?": true,
+    "{": true, "}": true, ";": true, "return": true, "typeof": true, "=>": true, "==": true, "===": true,
+}
+
+// tokenizeJavaScript splits source into tokens, dropping comments and
+// keeping string, template and regex literals whole
+func tokenizeJavaScript(source string) []jsToken {
+    var tokens []jsToken
+    line := 1
+    for index := 0; index < len(source); {
+        char := source[index]
+        start, startLine := index, line
+        switch {
+        case char == '\n':
+            line++
+            index++
+            continue
+        case char == ' ' || char == '\t' || char == '\r':
+            index++
+            continue
+        case strings.HasPrefix(source[index:], "//"):
+            for index < len(source) && source[index] != '\n' {
+                index++
+            }
+            continue
+        case strings.HasPrefix(source[index:], "/*"):
+            end := strings.Index(source[index+2:], "*/")
+            if end < 0 {
+                end = len(source) - index - 4
+            }
+            line += strings.Count(source[index:index+end+4], "\n")
+            index += end + 4
+            continue
+        case char == '"' || char == '\'':
+            index++
+            for index < len(source) && source[index] != char && source[index] != '\n' {
+                if source[index] == '\\' {
+                    index++
+                }
+                index++
+            }
+            index++
+            tokens = append(tokens, jsToken{Text: source[start:min(index, len(source))], Kind: jsString, Line: startLine})
+        case char == '`':
+            index = skipTemplateLiteral(source, index)
+            line += strings.Count(source[start:index], "\n")
+            tokens = append(tokens, jsToken{Text: source[start:index], Kind: jsTemplate, Line: startLine})
+        case isJSIdentStart(char):
+            for index < len(source) && (isJSIdentStart(source[index]) || (source[index] >= '0' && source[index] <= '9')) {
+                index++
+            }
+            tokens = append(tokens, jsToken{Text: source[start:index], Kind: jsIdent, Line: startLine})
+        case char >= '0' && char <= '9':
+            for index < len(source) && (isJSIdentStart(source[index]) || (source[index] >= '0' && source[index] <= '9') || source[index] == '.') {
+                index++
+            }
+            tokens = append(tokens, jsToken{Text: source[start:index], Kind: jsNumber, Line: startLine})
+        case char == '/' && (len(tokens) == 0 || jsRegexPrefix[tokens[len(tokens)-1].Text]):
+            index = skipRegexLiteral(source, index)
+            tokens = append(tokens, jsToken{Text: source[start:index], Kind: jsString, Line: startLine})
+        default:
+            text := string(char)
+            for _, operator := range jsOperators {
+                if strings.HasPrefix(source[index:], operator) {
+                    text = operator
+                    break
+                }
+            }
+            index += len(text)
+            tokens = append(tokens, jsToken{Text: text, Kind: jsPunct, Line: startLine})
+        }
+    }
+    return tokens
+}
+
+func isJSIdentStart(char byte) bool {
+    return char == '_' || char == '$' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+// skipTemplateLiteral returns the index after a `...${...}...` literal
+func skipTemplateLiteral(source string, index int) int {
+    depth := 0
+    for index++; index < len(source); index++ {
+        switch {
+        case source[index] == '\\':
+            index++
+        case depth == 0 && source[index] == '`':
+            return index + 1
+        case strings.HasPrefix(source[index:], "${"):
+            depth++
+            index++
+        case depth > 0 && source[index] == '{':
+            depth++
+        case depth > 0 && source[index] == '}':
+            depth--
+        }
+    }
+    return len(source)
+}
+
+// skipRegexLiteral returns the index after a /.../flags literal
+func skipRegexLiteral(source string, index int) int {
+    inClass := false
+    for index++; index < len(source) && source[index] != '\n'; index++ {
+        switch source[index] {
+        case '\\':
+            index++
+        case '[':
+            inClass = true
+        case ']':
+            inClass = false
+        case '/':
+            if !inClass {
+                index++
+                for index < len(source) && isJSIdentStart(source[index]) {
+                    index++
+                }
+                return index
+            }
+        }
+    }
+    return index
+}
+
+// Express request properties that carry client input
+var jsRequestSources = map[string]bool{
+    "query": true, "params": true, "body": true, "headers": true, "cookies": true, "signedCookies": true,
+    "get": true, "header": true, "param": true, "files": true, "file": true, "originalUrl": true, "url": true, "path": true,
+}
+
+// jsSanitizers turn input into a value that is safe for the sinks tracked here
+var jsSanitizers = map[string]bool{
+    "parseInt": true, "parseFloat": true, "Number": true, "Boolean": true, "encodeURIComponent": true,
+    "basename": true, "escape": true, "escapeId": true, "isValidObjectId": true,
+}
+
+// jsSink is a call that must not receive request input; an empty receiver
+// matches any, "()" only a bare call, and argument -1 checks every argument
+type jsSink struct {
+    receivers []string
+    rule      string
+    argument  int
+}
+
+var jsSinks = map[string]jsSink{
+    "query":             {nil, taintSQL, 0},
+    "execute":           {nil, taintSQL, 0},
+    "raw":               {nil, taintSQL, 0},
+    "$queryRawUnsafe":   {nil, taintSQL, 0},
+    "$executeRawUnsafe": {nil, taintSQL, 0},
+    "exec":              {[]string{"()", "child_process", "childProcess", "cp"}, taintCommand, 0},
+    "execSync":          {nil, taintCommand, 0},
+    "spawn":             {nil, taintCommand, -1},
+    "spawnSync":         {nil, taintCommand, -1},
+    "execFile":          {nil, taintCommand, -1},
+    "execFileSync":      {nil, taintCommand, -1},
+    "readFile":          {nil, taintPath, 0},
+    "readFileSync":      {nil, taintPath, 0},
+    "writeFile":         {nil, taintPath, 0},
+    "writeFileSync":     {nil, taintPath, 0},
+    "appendFile":        {nil, taintPath, 0},
+    "createReadStream":  {nil, taintPath, 0},
+    "createWriteStream": {nil, taintPath, 0},
+    "unlink":            {nil, taintPath, 0},
+    "unlinkSync":        {nil, taintPath, 0},
+    "readdir":           {nil, taintPath, 0},
+    "rm":                {nil, taintPath, 0},
+    "sendFile":          {nil, taintPath, 0},
+    "download":          {nil, taintPath, 0},
+    "render":            {[]string{"res", "response", "ejs", "pug", "nunjucks"}, taintTemplate, 0},
+    "renderString":      {nil, taintTemplate, 0},
+    "compile":           {[]string{"ejs", "pug", "Handlebars", "handlebars", "nunjucks"}, taintTemplate, 0},
+    "template":          {[]string{"_", "lodash"}, taintTemplate, 0},
+}
+
+// jsContinuation holds tokens that carry an expression over a line break
+var jsContinuation = map[string]bool{
+    "+": true, "-": true, "*": true, "/": true, "=": true, "(": true, "[": true, "{": true, ",": true,
+    "?": true, ":": true, "&&": true, "||": true, "??": true, "=>": true, ".": true, "?.": true, "+=": true,
+}
+
+var jsInterpolation = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// jsTaint tracks request input through one Express handler
+type jsTaint struct {
+    tokens   []jsToken
+    content  string
+    request  string
+    response string
+    tainted  map[string][]TaintStep
+}
+
+// analyzeExpressTaint finds Express handlers in the JavaScript/TypeScript
+// files of the codebase and reports request input reaching sinks
+func analyzeExpressTaint(codebase map[string]string) []ruleFinding {
+    paths := make([]string, 0, len(codebase))
+    for path := range codebase {
+        switch strings.ToLower(path[strings.LastIndex(path, ".")+1:]) {
+        case "js", "mjs", "cjs", "ts":
+            if !strings.HasSuffix(path, ".min.js") && !strings.HasSuffix(path, ".d.ts") {
+                paths = append(paths, path)
+            }
+        }
+    }
+    sort.Strings(paths)
+
+    var findings []ruleFinding
+    for _, path := range paths {
+        content := codebase[path]
+        tokens := tokenizeJavaScript(content)
+        for _, handler := range findExpressHandlers(tokens) {
+            taint := &jsTaint{tokens: tokens, content: content, request: handler.request, response: handler.response, tainted: make(map[string][]TaintStep)}
+            findings = append(findings, taint.track(path, handler.start, handler.end)...)
+        }
+    }
+    return findings
+}
+
+type jsHandler struct {
+    request, response string
+    start, end        int // token range of the body, braces excluded
+}
+
+var jsClosers = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+// matchingToken returns the index of the bracket closing the one at index
+func matchingToken(tokens []jsToken, index int) int {
+    open, close := tokens[index].Text, jsClosers[tokens[index].Text]
+    depth := 0
+    for position := index; position < len(tokens); position++ {
+        switch tokens[position].Text {
+        case open:
+            depth++
+        case close:
+            depth--
+            if depth == 0 {
+                return position
+            }
+        }
+    }
+    return len(tokens) - 1
+}
+
+// splitArguments returns the token ranges of the comma-separated items
+// between an opening bracket and its match
+func splitArguments(tokens []jsToken, open int, close int) [][2]int {
+    var ranges [][2]int
+    depth, start := 0, open+1
+    for position := open + 1; position < close; position++ {
+        switch tokens[position].Text {
+        case "(", "[", "{":
+            depth++
+        case ")", "]", "}":
+            depth--
+        case ",":
+            if depth == 0 {
+                ranges = append(ranges, [2]int{start, position})
+                start = position + 1
+            }
+        }
+    }
+    if start < close {
+        ranges = append(ranges, [2]int{start, close})
+    }
+    return ranges
+}
+
+// findExpressHandlers finds functions whose parameters are (req, res[, next])
+// or (err, req, res, next) - function declarations, expressions, arrows and methods
+func findExpressHandlers(tokens []jsToken) []jsHandler {
+    var handlers []jsHandler
+    for index, token := range tokens {
+        if token.Text != "(" {
+            continue
+        }
+        closing := matchingToken(tokens, index)
+        body := closing + 1
+        if body < len(tokens) && tokens[body].Text == "=>" {
+            body++
+        } else if index == 0 || tokens[index-1].Kind != jsIdent || map[string]bool{"if": true, "for": true, "while": true, "switch": true, "catch": true}[tokens[index-1].Text] {
+            continue
+        }
+        if body >= len(tokens) || tokens[body].Text != "{" {
+            continue
+        }
+
+        var names []string
+        for _, argument := range splitArguments(tokens, index, closing) {
+            names = append(names, tokens[argument[0]].Text)
+        }
+        if len(names) == 4 && names[0] == "err" {
+            names = names[1:]
+        }
+        if len(names) >= 2 && (names[0] == "req" || names[0] == "request") && (names[1] == "res" || names[1] == "response") {
+            handlers = append(handlers, jsHandler{request: names[0], response: names[1], start: body + 1, end: matchingToken(tokens, body)})
+        }
+    }
+    return handlers
+}
+
+// expressionEnd returns where the expression starting at start ends: at a
+// semicolon or top-level comma, a bracket closing an outer one, or a line
+// break that no operator carries over
+func (taint *jsTaint) expressionEnd(start int, end int) int {
+    depth := 0
+    for position := start; position < end; position++ {
+        token := taint.tokens[position]
+        if depth == 0 && position > start && token.Line > taint.tokens[position-1].Line &&
+            !jsContinuation[taint.tokens[position-1].Text] && !jsContinuation[token.Text] && token.Text != ")" {
+            return position
+        }
+        switch token.Text {
+        case "(", "[", "{":
+            depth++
+        case ")", "]", "}":
+            depth--
+            if depth < 0 {
+                return position
+            }
+        case ";", ",":
+            if depth == 0 {
+                return position
+            }
+        }
+    }
+    return end
+}
+
+// sourceExpression returns the member chain req.query.id starting at index
+func (taint *jsTaint) sourceExpression(index int) (string, bool) {
+    tokens := taint.tokens
+    if index+2 >= len(tokens) || tokens[index].Text != taint.request || tokens[index+1].Text != "." || !jsRequestSources[tokens[index+2].Text] {
+        return "", false
+    }
+    if index > 0 && (tokens[index-1].Text == "." || tokens[index-1].Text == "?.") {
+        return "", false
+    }
+    text := tokens[index].Text + "." + tokens[index+2].Text
+    for position := index + 3; position+1 < len(tokens); {
+        switch tokens[position].Text {
+        case ".", "?.":
+            text += tokens[position].Text + tokens[position+1].Text
+            position += 2
+        case "[", "(":
+            closing := matchingToken(tokens, position)
+            for _, token := range tokens[position : closing+1] {
+                text += token.Text
+            }
+            position = closing + 1
+        default:
+            return text, true
+        }
+    }
+    return text, true
+}
+
+// flow returns how request input reaches the tokens in [start, end), or nil
+func (taint *jsTaint) flow(start int, end int) []TaintStep {
+    position := start
+    if position < end && taint.tokens[position].Text == "await" {
+        position++
+    }
+    // A sanitizer wrapping the whole expression cleans it
+    for call := position; call+1 < end && taint.tokens[call].Kind == jsIdent; call += 2 {
+        if taint.tokens[call+1].Text == "(" {
+            if jsSanitizers[taint.tokens[call].Text] && matchingToken(taint.tokens, call+1) == end-1 {
+                return nil
+            }
+            break
+        }
+        if taint.tokens[call+1].Text != "." {
+            break
+        }
+    }
+
+    for index := start; index < end; index++ {
+        token := taint.tokens[index]
+        if expression, ok := taint.sourceExpression(index); ok {
+            return []TaintStep{taintStep(TaintKindSource, taint.content, token.Line, expression)}
+        }
+        previous := ""
+        if index > 0 {
+            previous = taint.tokens[index-1].Text
+        }
+        if token.Kind == jsIdent && previous != "." && previous != "?." && taint.tainted[token.Text] != nil {
+            // An object literal key, not a use of the variable
+            if index+1 < end && taint.tokens[index+1].Text == ":" && (previous == "{" || previous == ",") {
+                continue
+            }
+            return taint.tainted[token.Text]
+        }
+        if token.Kind == jsTemplate {
+            for _, interpolation := range jsInterpolation.FindAllStringSubmatch(token.Text, -1) {
+                inner := &jsTaint{tokens: tokenizeJavaScript(interpolation[1]), content: taint.content, request: taint.request, tainted: taint.tainted}
+                if flow := inner.flow(0, len(inner.tokens)); flow != nil {
+                    if flow[0].Kind == TaintKindSource && len(flow) == 1 {
+                        flow = []TaintStep{taintStep(TaintKindSource, taint.content, token.Line, flow[0].Expression)}
+                    }
+                    return flow
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// bindingNames returns the names a declaration binds: x, {a, b: c}, [d, e]
+func (taint *jsTaint) bindingNames(index int) ([]string, int) {
+    tokens := taint.tokens
+    if tokens[index].Kind == jsIdent {
+        return []string{tokens[index].Text}, index + 1
+    }
+    if tokens[index].Text != "{" && tokens[index].Text != "[" {
+        return nil, index + 1
+    }
+    closing := matchingToken(tokens, index)
+    var names []string
+    for position := index + 1; position < closing; position++ {
+        next := tokens[position+1].Text
+        if tokens[position].Kind == jsIdent && tokens[position-1].Text != "=" && (next == "," || next == "}" || next == "]" || next == "=") {
+            names = append(names, tokens[position].Text)
+        }
+    }
+    return names, closing + 1
+}
+
+func (taint *jsTaint) assign(names []string, line int, start int, end int, compound bool) {
+    flow := taint.flow(start, end)
+    for _, name := range names {
+        if flow == nil {
+            if !compound {
+                delete(taint.tainted, name)
+            }
+            continue
+        }
+        taint.tainted[name] = extendTaint(flow, taintStep(TaintKindStep, taint.content, line, name))
+    }
+}
+
+// track walks the handler body in order, following assignments and checking sink calls
+func (taint *jsTaint) track(path string, start int, end int) []ruleFinding {
+    var findings []ruleFinding
+    tokens := taint.tokens
+    for index := start; index < end; index++ {
+        token := tokens[index]
+        previous := ""
+        if index > start {
+            previous = tokens[index-1].Text
+        }
+
+        switch {
+        case (token.Text == "const" || token.Text == "let" || token.Text == "var") && index+1 < end:
+            // const x = ..., and the loop variable of for (const x of ...)
+            names, next := taint.bindingNames(index + 1)
+            if next < end && (tokens[next].Text == "=" || tokens[next].Text == "of") {
+                taint.assign(names, token.Line, next+1, taint.expressionEnd(next+1, end), false)
+            }
+        case token.Kind == jsIdent && previous != "." && previous != "?." && index+1 < end &&
+            (tokens[index+1].Text == "=" || tokens[index+1].Text == "+="):
+            if previous != "const" && previous != "let" && previous != "var" {
+                taint.assign([]string{token.Text}, token.Line, index+2, taint.expressionEnd(index+2, end), tokens[index+1].Text == "+=")
+            }
+        case token.Kind == jsIdent && index+1 < end && tokens[index+1].Text == "(":
+            if finding, ok := taint.checkSink(path, index); ok {
+                findings = append(findings, finding)
+            }
+        }
+    }
+    return findings
+}
+
+// checkSink reports request input reaching the call at index
+func (taint *jsTaint) checkSink(path string, index int) (ruleFinding, bool) {
+    tokens := taint.tokens
+    sink, ok := jsSinks[tokens[index].Text]
+    if !ok {
+        return ruleFinding{}, false
+    }
+    receiver, name := "()", tokens[index].Text
+    if index >= 2 && (tokens[index-1].Text == "." || tokens[index-1].Text == "?.") {
+        receiver = tokens[index-2].Text
+        name = receiver + "." + name
+    }
+    if sink.receivers != nil {
+        allowed := false
+        for _, candidate := range sink.receivers {
+            allowed = allowed || candidate == receiver || (candidate == "res" && receiver == taint.response)
+        }
+        if !allowed {
+            return ruleFinding{}, false
+        }
+    }
+
+    closing := matchingToken(tokens, index+1)
+    arguments := splitArguments(tokens, index+1, closing)
+    if sink.argument >= 0 {
+        if sink.argument >= len(arguments) {
+            return ruleFinding{}, false
+        }
+        arguments = arguments[sink.argument : sink.argument+1]
+    }
+    for _, argument := range arguments {
+        if flow := taint.flow(argument[0], argument[1]); flow != nil {
+            sinkStep := taintStep(TaintKindSink, taint.content, tokens[index].Line, name)
+            return newTaintFinding(path, taint.content, sink.rule, flow, sinkStep), true
+        }
+    }
+    return ruleFinding{}, false
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+    "fmt"
+    "go/ast"
+    "go/token"
+    "go/types"
+    "strings"
+)
+
+// Request accessors that return client-controlled data
+var (
+    httpRequestSources = map[string]bool{
+        "URL": true, "Form": true, "PostForm": true, "MultipartForm": true, "Header": true, "Body": true,
+        "FormValue": true, "PostFormValue": true, "FormFile": true, "Cookie": true, "Cookies": true,
+        "PathValue": true, "RequestURI": true, "Referer": true, "UserAgent": true, "Host": true,
+    }
+    ginContextSources = map[string]bool{
+        "Query": true, "DefaultQuery": true, "GetQuery": true, "QueryArray": true, "QueryMap": true,
+        "Param": true, "PostForm": true, "DefaultPostForm": true, "GetPostForm": true, "PostFormArray": true,
+        "PostFormMap": true, "GetHeader": true, "Cookie": true, "FormFile": true, "MultipartForm": true, "GetRawData": true,
+    }
+    goRouterSources = map[string]bool{
+        "github.com/gorilla/mux.Vars":    true,
+        "github.com/go-chi/chi.URLParam": true,
+    }
+)
+
+// goSanitizers return values that are safe for every sink tracked here
+var goSanitizers = map[string]bool{
+    "strconv.Atoi": true, "strconv.ParseInt": true, "strconv.ParseUint": true, "strconv.ParseFloat": true,
+    "strconv.ParseBool": true, "path/filepath.Base": true, "path.Base": true, "net/url.QueryEscape": true,
+    "net/url.PathEscape": true, "html.EscapeString": true, "html/template.HTMLEscapeString": true,
+    "html/template.JSEscapeString": true, "github.com/google/uuid.Parse": true,
+}
+
+// Go sinks by callee, with the index of the argument that must not be tainted
+// (-1 for any argument)
+var goTaintSinks = map[string]struct {
+    rule     string
+    argument int
+}{
+    "os/exec.Command":                        {taintCommand, -1},
+    "os/exec.CommandContext":                 {taintCommand, -1},
+    "os.Open":                                {taintPath, 0},
+    "os.OpenFile":                            {taintPath, 0},
+    "os.ReadFile":                            {taintPath, 0},
+    "os.WriteFile":                           {taintPath, 0},
+    "os.Create":                              {taintPath, 0},
+    "os.Remove":                              {taintPath, 0},
+    "os.RemoveAll":                           {taintPath, 0},
+    "os.Mkdir":                               {taintPath, 0},
+    "os.MkdirAll":                            {taintPath, 0},
+    "os.ReadDir":                             {taintPath, 0},
+    "io/ioutil.ReadFile":                     {taintPath, 0},
+    "io/ioutil.WriteFile":                    {taintPath, 0},
+    "net/http.ServeFile":                     {taintPath, 2},
+    "html/template.HTML":                     {taintTemplate, 0},
+    "html/template.HTMLAttr":                 {taintTemplate, 0},
+    "html/template.JS":                       {taintTemplate, 0},
+    "html/template.CSS":                      {taintTemplate, 0},
+    "html/template.URL":                      {taintTemplate, 0},
+    "html/template.Template.Parse":           {taintTemplate, 0},
+    "text/template.Template.Parse":           {taintTemplate, 0},
+    "text/template.Template.Execute":         {taintTemplate, 1},
+    "text/template.Template.ExecuteTemplate": {taintTemplate, 2},
+}
+
+// ginTaintSinks are *gin.Context methods that take a file path
+var ginTaintSinks = map[string]int{"File": 0, "FileAttachment": 0, "SaveUploadedFile": 1}
+
+// taintSupersedes lists the syntactic rules a taint flow explains better
+var taintSupersedes = map[string][]string{
+    taintSQL:     {goSQLConcat},
+    taintCommand: {goShellInjection, goCommandArguments},
+}
+
+// goTaint tracks request input through one net/http or Gin handler
+type goTaint struct {
+    analyzer *goAnalyzer
+    fn       *goFunction
+    content  string
+    request  types.Object // r *http.Request
+    context  types.Object // c *gin.Context
+    tainted  map[types.Object][]TaintStep
+}
+
+// handlerParams finds the *http.Request and *gin.Context parameters of a function
+func (analyzer *goAnalyzer) handlerParams(fields *ast.FieldList) (request types.Object, context types.Object) {
+    if fields == nil {
+        return nil, nil
+    }
+    for _, field := range fields.List {
+        star, ok := field.Type.(*ast.StarExpr)
+        if !ok || len(field.Names) == 0 {
+            continue
+        }
+        selector, ok := star.X.(*ast.SelectorExpr)
+        if !ok {
+            continue
+        }
+        ident, ok := selector.X.(*ast.Ident)
+        if !ok {
+            continue
+        }
+        pkgName, ok := analyzer.object(ident).(*types.PkgName)
+        if !ok {
+            continue
+        }
+        switch pkgName.Imported().Path() + "." + selector.Sel.Name {
+        case "net/http.Request":
+            request = analyzer.object(field.Names[0])
+        case "github.com/gin-gonic/gin.Context":
+            context = analyzer.object(field.Names[0])
+        }
+    }
+    return request, context
+}
+
+// trackTaint follows request input through a handler body in source order.
+// Handlers nested inside are tracked on their own.
+func (analyzer *goAnalyzer) trackTaint(fn *goFunction, body *ast.BlockStmt, request types.Object, context types.Object) {
+    taint := &goTaint{
+        analyzer: analyzer,
+        fn:       fn,
+        content:  analyzer.codebase[analyzer.fset.Position(body.Pos()).Filename],
+        request:  request,
+        context:  context,
+        tainted:  make(map[types.Object][]TaintStep),
+    }
+
+    ast.Inspect(body, func(node ast.Node) bool {
+        switch node := node.(type) {
+        case *ast.FuncLit:
+            if request, context := analyzer.handlerParams(node.Type.Params); request != nil || context != nil {
+                return false
+            }
+        case *ast.AssignStmt:
+            for index, target := range node.Lhs {
+                value := node.Rhs[0]
+                if len(node.Rhs) == len(node.Lhs) {
+                    value = node.Rhs[index]
+                }
+                taint.assign(target, value, node.Tok != token.ASSIGN && node.Tok != token.DEFINE)
+            }
+        case *ast.ValueSpec:
+            for index, name := range node.Names {
+                if index < len(node.Values) {
+                    taint.assign(name, node.Values[index], false)
+                } else if len(node.Values) == 1 {
+                    taint.assign(name, node.Values[0], false)
+                }
+            }
+        case *ast.RangeStmt:
+            for _, target := range []ast.Expr{node.Key, node.Value} {
+                if target != nil {
+                    taint.assign(target, node.X, false)
+                }
+            }
+        case *ast.CallExpr:
+            taint.bindTargets(node)
+            taint.checkSink(node)
+        }
+        return true
+    })
+}
+
+func (taint *goTaint) line(node ast.Node) int {
+    return taint.analyzer.fset.Position(node.Pos()).Line
+}
+
+// assign updates a variable's taint; a clean value clears it unless the
+// operator only appends to it (+=)
+func (taint *goTaint) assign(target ast.Expr, value ast.Expr, compound bool) {
+    ident, ok := target.(*ast.Ident)
+    if !ok || ident.Name == "_" {
+        return
+    }
+    object := taint.analyzer.object(ident)
+    if object == nil {
+        return
+    }
+    flow := taint.flow(value)
+    if flow == nil {
+        if !compound {
+            delete(taint.tainted, object)
+        }
+        return
+    }
+    taint.tainted[object] = extendTaint(flow, taintStep(TaintKindStep, taint.content, taint.line(ident), ident.Name))
+}
+
+// rootSelectors unwinds x.A(...).B[...] to x and its selectors, outermost last
+func (taint *goTaint) rootSelectors(expr ast.Expr) (types.Object, []string) {
+    var selectors []string
+    for {
+        switch node := expr.(type) {
+        case *ast.SelectorExpr:
+            selectors = append([]string{node.Sel.Name}, selectors...)
+            expr = node.X
+        case *ast.CallExpr:
+            expr = node.Fun
+        case *ast.IndexExpr:
+            expr = node.X
+        case *ast.ParenExpr:
+            expr = node.X
+        case *ast.StarExpr:
+            expr = node.X
+        case *ast.Ident:
+            return taint.analyzer.object(node), selectors
+        default:
+            return nil, nil
+        }
+    }
+}
+
+func (taint *goTaint) isSource(expr ast.Expr) bool {
+    if call, ok := expr.(*ast.CallExpr); ok && goRouterSources[taint.analyzer.callee(call)] {
+        return true
+    }
+    root, selectors := taint.rootSelectors(expr)
+    if root == nil || len(selectors) == 0 {
+        return false
+    }
+    switch root {
+    case taint.request:
+        return httpRequestSources[selectors[0]]
+    case taint.context:
+        return ginContextSources[selectors[0]] || (selectors[0] == "Request" && len(selectors) > 1 && httpRequestSources[selectors[1]])
+    }
+    return false
+}
+
+// flow returns how request input reaches an expression, or nil if it does not
+func (taint *goTaint) flow(expr ast.Expr) []TaintStep {
+    var found []TaintStep
+    ast.Inspect(expr, func(node ast.Node) bool {
+        if found != nil {
+            return false
+        }
+        switch node := node.(type) {
+        case *ast.FuncLit:
+            return false
+        case *ast.CallExpr:
+            if goSanitizers[taint.analyzer.callee(node)] {
+                return false
+            }
+        case *ast.Ident:
+            if object := taint.analyzer.object(node); object != nil && taint.tainted[object] != nil {
+                found = taint.tainted[object]
+                return false
+            }
+        }
+        if expr, ok := node.(ast.Expr); ok && taint.isSource(expr) {
+            found = []TaintStep{taintStep(TaintKindSource, taint.content, taint.line(expr), taint.analyzer.source(expr))}
+            return false
+        }
+        return true
+    })
+    return found
+}
+
+// bindTargets taints the variables request data is decoded into:
+// c.ShouldBindJSON(&req), json.NewDecoder(r.Body).Decode(&req), json.Unmarshal(body, &req)
+func (taint *goTaint) bindTargets(call *ast.CallExpr) {
+    selector, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+    if !ok {
+        return
+    }
+    var flow []TaintStep
+    root, selectors := taint.rootSelectors(selector)
+    switch {
+    case root != nil && root == taint.context && len(selectors) == 1 &&
+        (strings.HasPrefix(selectors[0], "Bind") || strings.HasPrefix(selectors[0], "ShouldBind")):
+        flow = []TaintStep{taintStep(TaintKindSource, taint.content, taint.line(call), taint.analyzer.source(call))}
+    case selector.Sel.Name == "Decode" || selector.Sel.Name == "Unmarshal":
+        flow = taint.flow(selector.X)
+        for _, argument := range call.Args {
+            if _, pointer := argument.(*ast.UnaryExpr); flow == nil && !pointer {
+                flow = taint.flow(argument)
+            }
+        }
+    }
+    if flow == nil {
+        return
+    }
+
+    for _, argument := range call.Args {
+        unary, ok := argument.(*ast.UnaryExpr)
+        if !ok || unary.Op != token.AND {
+            continue
+        }
+        if ident, ok := unary.X.(*ast.Ident); ok {
+            if object := taint.analyzer.object(ident); object != nil {
+                taint.tainted[object] = extendTaint(flow, taintStep(TaintKindStep, taint.content, taint.line(ident), ident.Name))
+            }
+        }
+    }
+}
+
+// checkSink reports tainted data reaching a sink call
+func (taint *goTaint) checkSink(call *ast.CallExpr) {
+    rule, arguments := "", []ast.Expr(nil)
+    if query := taint.analyzer.sqlQuery(taint.fn, call); query != nil && !taint.analyzer.isConstant(query) {
+        rule, arguments = taintSQL, []ast.Expr{query}
+    } else if sink, ok := goTaintSinks[taint.analyzer.callee(call)]; ok {
+        rule, arguments = sink.rule, call.Args
+        if sink.argument >= 0 {
+            arguments = nil
+            if sink.argument < len(call.Args) {
+                arguments = call.Args[sink.argument : sink.argument+1]
+            }
+        }
+    } else if root, selectors := taint.rootSelectors(call.Fun); root != nil && root == taint.context && len(selectors) == 1 {
+        if index, ok := ginTaintSinks[selectors[0]]; ok && index < len(call.Args) {
+            rule, arguments = taintPath, call.Args[index:index+1]
+        }
+    }
+
+    for _, argument := range arguments {
+        flow := taint.flow(argument)
+        if flow == nil {
+            continue
+        }
+        filePath := taint.analyzer.fset.Position(call.Pos()).Filename
+        sink := taintStep(TaintKindSink, taint.content, taint.line(call), taint.analyzer.source(call.Fun))
+        taint.analyzer.findings = append(taint.analyzer.findings, newTaintFinding(filePath, taint.content, rule, flow, sink))
+        return
+    }
+}
+
+// dropSupersededFindings removes syntactic findings on lines where a taint
+// flow reports the same sink
+func dropSupersededFindings(findings []ruleFinding) []ruleFinding {
+    key := func(risk Risk, ruleID string) string {
+        return fmt.Sprintf("%s:%d %s", risk.FilePath, risk.LineNumber, ruleID)
+    }
+    superseded := make(map[string]bool)
+    for _, finding := range findings {
+        for _, ruleID := range taintSupersedes[finding.Risk.RuleID] {
+            superseded[key(finding.Risk, ruleID)] = true
+        }
+    }
+    var kept []ruleFinding
+    for _, finding := range findings {
+        if !superseded[key(finding.Risk, finding.Risk.RuleID)] {
+            kept = append(kept, finding)
+        }
+    }
+    return kept
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "testing"
+)
+
+var taintSources = map[string]string{
+    "api/users.go": `package api
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "html/template"
+    "net/http"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strconv"
+)
+
+type Server struct {
+    db *sql.DB
+}
+
+func (s *Server) Search(w http.ResponseWriter, r *http.Request) {
+    name := r.URL.Query().Get("name")
+    pattern := "%" + name + "%"
+    query := fmt.Sprintf("SELECT * FROM users WHERE name LIKE '%s'", pattern)
+    s.db.Query(query)
+
+    id, _ := strconv.Atoi(r.FormValue("id"))
+    s.db.QueryRow(fmt.Sprintf("SELECT * FROM users WHERE id = %d", id))
+    s.db.Query("SELECT * FROM users WHERE name = ?", name)
+}
+
+func (s *Server) Download(w http.ResponseWriter, r *http.Request) {
+    file := r.PathValue("file")
+    http.ServeFile(w, r, filepath.Join("/srv/files", file))
+
+    safe := filepath.Base(r.PathValue("file"))
+    os.ReadFile(filepath.Join("/srv/files", safe))
+}
+
+type request struct {
+    Host string
+    Bio  string
+}
+
+func Ping(w http.ResponseWriter, r *http.Request) {
+    var body request
+    json.NewDecoder(r.Body).Decode(&body)
+    exec.Command("ping", "-c", "1", body.Host).Run()
+    fmt.Fprint(w, template.HTML(body.Bio))
+}
+
+func Helper(host string) {
+    exec.Command("ping", host).Run()
+}
+`,
+    "api/routes.go": `package api
+
+import (
+    "os/exec"
+
+    "github.com/gin-gonic/gin"
+)
+
+func Register(router *gin.Engine) {
+    router.GET("/export", func(c *gin.Context) {
+        format := c.DefaultQuery("format", "csv")
+        args := []string{"export"}
+        args = append(args, "--format="+format)
+        exec.Command("report", args...).Run()
+        c.File("/tmp/report." + c.Param("ext"))
+    })
+}
+`,
+    "web/server.js": `const express = require('express');
+const { exec } = require('child_process');
+const fs = require('fs');
+const app = express();
+
+app.get('/users', async (req, res) => {
+  const { name } = req.query;
+  const filter = "name = '" + name + "'";
+  const rows = await db.query(` + "`SELECT * FROM users WHERE ${filter}`" + `);
+  await db.query('SELECT * FROM users WHERE name = ?', [name]);
+  const id = parseInt(req.params.id);
+  await db.query('SELECT * FROM users WHERE id = ' + id);
+  res.json(rows);
+});
+
+function download(req, res) {
+  const file = req.params.file;
+  res.sendFile('/srv/files/' + file);
+}
+app.get('/files/:file', download);
+
+router.post('/ping', function (req, res) {
+  exec('ping -c 1 ' + req.body.host, (err, out) => res.send(out));
+  const pattern = /\/ping/g;
+  pattern.exec(req.body.host);
+  res.render(req.query.view, { title: 'x' });
+});
+
+function notAHandler(user, options) {
+  fs.readFileSync(user.path);
+}
+`,
+}
+
+func TestTaintTracking(t *testing.T) {
+    findings := append(analyzeGoCode(taintSources, true), analyzeExpressTaint(taintSources)...)
+
+    var got []string
+    paths := make(map[string]Risk)
+    for _, finding := range findings {
+        key := fmt.Sprintf("%s:%d %s", finding.Risk.FilePath, finding.Risk.LineNumber, finding.Risk.RuleID)
+        got = append(got, key)
+        paths[key] = finding.Risk
+    }
+    sort.Strings(got)
+
+    expected := []string{
+        "api/routes.go:14 taint-command-injection",
+        "api/routes.go:15 taint-path-traversal",
+        "api/users.go:23 taint-sql-injection",
+        "api/users.go:26 go-sql-string-concat",
+        "api/users.go:32 taint-path-traversal",
+        "api/users.go:46 taint-command-injection",
+        "api/users.go:47 taint-template-injection",
+        "api/users.go:51 go-exec-tainted-arguments",
+        "web/server.js:9 taint-sql-injection",
+        "web/server.js:18 taint-path-traversal",
+        "web/server.js:23 taint-command-injection",
+        "web/server.js:26 taint-template-injection",
+    }
+    sort.Strings(expected)
+    if fmt.Sprint(got) != fmt.Sprint(expected) {
+        t.Fatalf("Unexpected taint findings:\n got      %v\n expected %v", got, expected)
+    }
+
+    describe := func(steps []TaintStep) string {
+        var parts []string
+        for _, step := range steps {
+            parts = append(parts, fmt.Sprintf("%s:%d:%s", step.Kind, step.Line, step.Expression))
+        }
+        return strings.Join(parts, " ")
+    }
+    flows := map[string]string{
+        "api/users.go:23 taint-sql-injection":      `source:20:r.URL.Query().Get("name") step:21:pattern step:22:query sink:23:s.db.Query`,
+        "api/users.go:46 taint-command-injection":  "source:45:r.Body sink:46:exec.Command",
+        "api/routes.go:14 taint-command-injection": `source:11:c.DefaultQuery("format", "csv") step:13:args sink:14:exec.Command`,
+        "web/server.js:9 taint-sql-injection":      "source:7:req.query step:8:filter sink:9:db.query",
+        "web/server.js:23 taint-command-injection": "source:23:req.body.host sink:23:exec",
+    }
+    for key, want := range flows {
+        if flow := describe(paths[key].TaintPath); flow != want {
+            t.Errorf("%s: expected flow %q, got %q", key, want, flow)
+        }
+    }
+    if risk := paths["api/users.go:23 taint-sql-injection"]; !strings.Contains(risk.Description, "through 2 assignment(s)") || risk.TaintPath[1].Code != `pattern := "%" + name + "%"` {
+        t.Errorf("Expected the description and steps to show the path, got %q %+v", risk.Description, risk.TaintPath)
+    }
+}
+
+func TestTokenizeJavaScript(t *testing.T) {
+    tokens := tokenizeJavaScript("const re = /a\\/b[/]/g; // comment\nlet s = `x ${a + `y`} z` /* block\n */ + 'it\\'s';")
+    var texts []string
+    for _, token := range tokens {
+        texts = append(texts, token.Text)
+    }
+    expected := []string{"const", "re", "=", `/a\/b[/]/g`, ";", "let", "s", "=", "`x ${a + `y`} z`", "+", `'it\'s'`, ";"}
+    if fmt.Sprint(texts) != fmt.Sprint(expected) {
+        t.Errorf("Unexpected tokens:\n got      %q\n expected %q", texts, expected)
+    }
+    if last := tokens[len(tokens)-1]; last.Line != 3 {
+        t.Errorf("Expected line tracking through comments, got line %d", last.Line)
+    }
+}
+
+func TestMergeTaintFindingOnPath(t *testing.T) {
+    // The AI flagged the concatenation; the taint engine reports the sink one line later
+    response := &AIAnalysisResponse{
+        HighRisks: []Risk{{File: "web/server.js", FilePath: "web/server.js", Line: 8, LineNumber: 8, Title: "SQL Injection", Source: RiskSourceAI}},
+    }
+    findings := analyzeExpressTaint(map[string]string{"web/server.js": taintSources["web/server.js"]})
+    if overlaps := mergeRuleFindings(response, findings); overlaps != 1 {
+        t.Fatalf("Expected the SQL flow to overlap the AI finding on its path, got %d overlaps", overlaps)
+    }
+    merged := response.CriticalRisks[0]
+    if merged.RuleID != taintSQL || merged.LineNumber != 8 || merged.Source != RiskSourceRule {
+        t.Errorf("Expected the AI finding to carry the taint rule at its own line, got %+v", merged)
+    }
+    var steps []string
+    for _, step := range merged.TaintPath {
+        steps = append(steps, fmt.Sprintf("%s:%d", step.Kind, step.Line))
+    }
+    if fmt.Sprint(steps) != "[source:7 step:8 sink:9]" {
+        t.Errorf("Expected the source-to-sink path on the merged finding, got %v", steps)
+    }
+    if len(response.HighRisks) != 2 || response.CriticalRisks[0].Title != "SQL Injection" {
+        t.Errorf("Expected the AI finding raised to critical beside the path and template flows, got %+v", response)
+    }
+}
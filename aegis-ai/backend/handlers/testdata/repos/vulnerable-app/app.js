@@ -0,0 +1,11 @@
+const express = require("express");
+const db = require("./db");
+
+const app = express();
+
+app.get("/users", (req, res) => {
+  const query = "SELECT * FROM users WHERE name = '" + req.query.name + "'";
+  db.query(query, (err, rows) => res.json(rows));
+});
+
+app.listen(3000);
@@ -0,0 +1,8 @@
+module.exports = {
+  port: 3000,
+  db: {
+    host: "localhost",
+    user: "admin",
+    password = "super_secret_123"
+  }
+};
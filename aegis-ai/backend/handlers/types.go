@@ -12,6 +12,26 @@ type Risk struct {
     CodeSnippet string  `json:"code_snippet"`
     FilePath    string  `json:"file_path,omitempty"`
     LineNumber  int     `json:"line_number,omitempty"`
+
+    // Scoring the audit prompt asks for - validated in sanitizeRiskScoring
+    CVSSScore              float64  `json:"cvss_score,omitempty"`
+    ExploitationComplexity string   `json:"exploitation_complexity,omitempty"`
+    RemediationPriority    string   `json:"remediation_priority,omitempty"`
+    ComplianceViolations   []string `json:"compliance_violations,omitempty"`
+
+    // Set by verifyFindings after checking the snippet against the source
+    Verified   bool   `json:"verified"`
+    Correction string `json:"correction,omitempty"`
+
+    // "ai" for model findings, "rule" for deterministic scanners (RuleID says which rule)
+    Source string `json:"source,omitempty"`
+    RuleID string `json:"rule_id,omitempty"`
+
+    // Set on vulnerable dependency findings
+    Advisory *DependencyAdvisory `json:"advisory,omitempty"`
+
+    // Set by the taint engine - how request input reaches the sink
+    TaintPath []TaintStep `json:"taint_path,omitempty"`
 }
 
 // Unified AutoFix type with all required fields - SIMPLIFIED to match ai_core.go
@@ -26,15 +46,30 @@ type AutoFix struct {
     CommitMessage string `json:"commit_message,omitempty"`
 }
 
-// Analysis storage structure
-type Analysis struct {
-    ID        string    `json:"id"`
-    RepoURL   string    `json:"repo_url"`
-    RepoName  string    `json:"repo_name"`
-    Risks     []Risk    `json:"risks"`
-    AutoFixes []AutoFix `json:"auto_fixes"`
-    Summary   AnalysisSummary `json:"summary"`
-    CreatedAt string    `json:"created_at"`
+// Scan trigger sources
+const (
+    TriggerManual  = "manual"
+    TriggerWebhook = "webhook"
+)
+
+// ScanRecord is the canonical stored scan - read by the API and by ApplyFix
+type ScanRecord struct {
+    ID          string `json:"id"`
+    RepoURL     string `json:"repo_url"`
+    RepoName    string `json:"repo_name"`
+    Ref         string `json:"ref,omitempty"`
+    CommitSHA   string `json:"commit_sha,omitempty"`
+    PRNumber    int    `json:"pr_number,omitempty"`
+    PRURL       string `json:"pr_url,omitempty"`
+    Trigger     string `json:"trigger"`
+    Status      string `json:"status"`
+    Error       string `json:"error,omitempty"`
+    CreatedAt   string `json:"created_at"`
+    UpdatedAt   string `json:"updated_at,omitempty"`
+    CompletedAt string `json:"completed_at,omitempty"`
+
+    // All risk tiers, fixes, summary and reports from the AI analysis
+    AIAnalysisResponse
 }
 
 // Analysis Summary
@@ -56,12 +91,50 @@ type AIAnalysisResponse struct {
     Summary       AnalysisSummary `json:"summary"`
     Architecture  *ArchitectureAnalysis `json:"architecture,omitempty"`
     Compliance    *ComplianceAnalysis   `json:"compliance,omitempty"`
+    Coverage      *CoverageReport       `json:"coverage,omitempty"`
+    PromptVersion string                `json:"prompt_version,omitempty"` // e.g. "v1-3fa2b1c9/audit.fintech.tmpl"
+    SecretHistory []HistorySecret       `json:"secret_history,omitempty"`  // only set in history mode
+    Dependencies  []Dependency          `json:"dependencies,omitempty"`    // manifest inventory, exported as SBOM
+    RepoConfig    *RepoConfigReport     `json:"repo_config,omitempty"`     // only when the repo has a .aegis.yml
+    Gate          *PolicyGate           `json:"gate,omitempty"`            // only when .aegis.yml sets fail_on
+}
+
+// File coverage states
+const (
+    CoverageFull    = "full"
+    CoveragePartial = "partial"
+    CoverageSkipped = "skipped"
+)
+
+// FileCoverage records how much of a file the AI actually analyzed
+type FileCoverage struct {
+    Path         string `json:"path"`
+    Status       string `json:"status"`
+    Reason       string `json:"reason,omitempty"`
+    TotalLines   int    `json:"total_lines"`
+    ScannedLines int    `json:"scanned_lines"`
+}
+
+// CoverageReport lists which files were scanned fully, partially or skipped
+type CoverageReport struct {
+    TotalFiles       int            `json:"total_files"`
+    FullyScanned     int            `json:"fully_scanned"`
+    PartiallyScanned int            `json:"partially_scanned"`
+    Skipped          int            `json:"skipped"`
+    Batches          int            `json:"batches"`
+    FailedBatches    int            `json:"failed_batches"`
+    Files            []FileCoverage `json:"files"`
 }
 
 // AI Analysis Request
 type AIAnalysisRequest struct {
-    Codebase map[string]string `json:"codebase"`
-    Context  AnalysisContext   `json:"context"`
+    Codebase     map[string]string `json:"codebase"`
+    Context      AnalysisContext   `json:"context"`
+    Batch        int               `json:"batch,omitempty"`
+    TotalBatches int               `json:"total_batches,omitempty"`
+
+    // Pre-sized file segments from the batch planner - used instead of Codebase
+    segments []fileSegment
 }
 
 type AnalysisContext struct {
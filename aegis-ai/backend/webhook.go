@@ -5,6 +5,7 @@ import (
     "log"
     "os"
     "path/filepath"
+    "strconv"
     "strings"
     "time"
     "aegis-ai/handlers"
@@ -70,6 +71,98 @@ func main() {
         fmt.Println("✅ GitHub OAuth credentials are loaded")
     }
     
+    // Persistent analysis store so scans survive a restart
+    dbPath := os.Getenv("AEGIS_DB_PATH")
+    if dbPath == "" {
+        dbPath = "aegis.db"
+    }
+    var store handlers.AnalysisStore
+    boltStore, err := handlers.NewBoltAnalysisStore(dbPath)
+    if err != nil {
+        fmt.Printf("⚠️  Could not open analysis database, using in-memory store: %v\n", err)
+        store = handlers.NewMemoryAnalysisStore()
+    } else {
+        fmt.Println("✅ Analysis database opened at:", dbPath)
+        store = boltStore
+        defer boltStore.Close()
+    }
+    
+    // Versioned audit prompt templates (AEGIS_PROMPT_DIR, AEGIS_PROMPT_VERSION)
+    if prompts, err := handlers.LoadPromptSetFromEnv(); err != nil {
+        fmt.Printf("⚠️  Could not load prompt templates, using embedded defaults: %v\n", err)
+    } else {
+        handlers.SetPromptSet(prompts)
+        fmt.Printf("📝 Prompt templates %s loaded from %s\n", prompts.ID(), prompts.Source)
+    }
+    
+    // Cache of per-file findings so rescans only analyze changed files
+    if os.Getenv("AEGIS_FINDING_CACHE") == "off" {
+        handlers.SetFindingCache(nil)
+        fmt.Println("⚠️  Finding cache disabled")
+    } else if boltStore != nil {
+        if backend, err := boltStore.FindingCacheBackend(); err != nil {
+            fmt.Printf("⚠️  Could not open finding cache, using in-memory cache: %v\n", err)
+        } else {
+            cache := handlers.NewFindingCache(backend)
+            handlers.SetFindingCache(cache)
+            if removed, err := cache.Invalidate(false); err == nil && removed > 0 {
+                fmt.Printf("🧹 Dropped %d cached findings from older prompt versions\n", removed)
+            }
+        }
+    }
+    
+    // Secret scanning of past commits (AEGIS_HISTORY_SCAN, AEGIS_HISTORY_DEPTH, AEGIS_HISTORY_SINCE)
+    if history, err := handlers.HistoryScanFromEnv(); err != nil {
+        fmt.Printf("⚠️  Ignoring history scan settings: %v\n", err)
+    } else if history != nil {
+        handlers.SetHistoryScan(history)
+        fmt.Printf("🕰️ History secret scanning enabled (depth %d, since %q)\n", history.Depth, history.Since)
+    }
+    
+    // Include/exclude globs for the files sent to analysis (AEGIS_INCLUDE, AEGIS_EXCLUDE)
+    if filter, err := handlers.FileFilterFromEnv(); err != nil {
+        fmt.Printf("⚠️  Ignoring file filter settings: %v\n", err)
+    } else if filter != nil {
+        handlers.SetFileFilter(filter)
+        fmt.Printf("🗂️ Scanning files matching %v, excluding %v\n", filter.Include, filter.Exclude)
+    }
+    
+    // Offline OSV advisories for dependency checks (AEGIS_OSV_DB)
+    if database, err := handlers.LoadAdvisoryDatabaseFromEnv(); err != nil {
+        fmt.Printf("⚠️  Could not load advisory database, dependencies won't be checked: %v\n", err)
+    } else if database != nil {
+        handlers.SetAdvisoryDatabase(database)
+        fmt.Printf("📦 Loaded %d OSV advisories from %s\n", database.Advisories, database.Source)
+    }
+    
+    // Commit statuses for .aegis.yml fail_on verdicts (GITHUB_TOKEN, GITHUB_API_URL)
+    if poster := handlers.CommitStatusPosterFromEnv(); poster != nil {
+        handlers.SetCommitStatusPoster(poster)
+        fmt.Printf("🚦 Posting policy commit statuses to %s\n", poster.BaseURL)
+    }
+    
+    // Bounded worker pool for scans
+    scanWorkers, _ := strconv.Atoi(os.Getenv("AEGIS_SCAN_WORKERS"))
+    scanTimeout, _ := time.ParseDuration(os.Getenv("AEGIS_SCAN_TIMEOUT"))
+    service := handlers.NewAnalysisService(store, scanWorkers, scanTimeout)
+    handlers.SetAnalysisService(service)
+    fmt.Printf("✅ Scan queue configured with %d workers\n", service.QueueStats().Workers)
+    
+    // Context window overrides for local or new models (AEGIS_MODEL_SPECS)
+    if err := handlers.LoadModelSpecsFromEnv(); err != nil {
+        fmt.Printf("⚠️  Ignoring model specs: %v\n", err)
+    }
+    
+    // AI providers in failover order (AEGIS_LLM_PROVIDERS)
+    providers := handlers.LoadLLMProvidersFromEnv()
+    handlers.SetLLMProviders(providers)
+    for i, provider := range providers {
+        fmt.Printf("🤖 LLM provider %d: %s %v\n", i+1, provider.Name(), provider.ModelNames())
+    }
+    if len(providers) == 0 {
+        fmt.Println("⚠️  No LLM provider configured - set GROQ_API_KEY, OPENROUTER_API_KEY or LOCAL_LLM_URL")
+    }
+    
     router := gin.Default()
     
     // Add CORS debug middleware
@@ -153,7 +246,13 @@ func main() {
     router.POST("/api/analyze", handlers.HandleManualAnalysis)
     router.GET("/api/analysis/:id", handlers.GetAnalysis)
     router.GET("/api/analysis/:id/status", handlers.GetAnalysisStatus)
+    router.GET("/api/analysis/:id/sbom", handlers.GetAnalysisSBOM)
+    router.DELETE("/api/analysis/:id", handlers.CancelAnalysis)
+    router.POST("/api/analysis/:id/cancel", handlers.CancelAnalysis)
     router.GET("/api/analyses", handlers.GetAllAnalyses)
+    router.GET("/api/queue", handlers.GetQueueStats)
+    router.GET("/api/cache", handlers.GetCacheStats)
+    router.DELETE("/api/cache", handlers.AuthMiddleware(), handlers.InvalidateCache)
     
     // FIXED: Changed auth endpoints to /api/auth/ prefix to avoid conflicts with NextAuth
     router.GET("/api/auth/github", handlers.HandleGitHubAuth)
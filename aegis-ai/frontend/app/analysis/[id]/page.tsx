@@ -2,16 +2,22 @@
 
 import { useState, useEffect } from 'react';
 import { useParams } from 'next/navigation';
-import { AegisApi } from '@/lib/api';
+import { AegisApi, AnalysisStatus } from '@/lib/api';
 import { AIAnalysisResponse, Risk, AutoFix } from '@/types';
 import { ApiError } from '@/lib/api';
 
+const isInProgress = (status: AnalysisStatus) =>
+  status === 'queued' || status === 'cloning' || status === 'analyzing';
+
+const isFinished = (status: AnalysisStatus) =>
+  status === 'failed' || status === 'failed_parse' || status === 'cancelled' || status === 'timed_out';
+
 export default function AnalysisPage() {
   const params = useParams();
   const [analysis, setAnalysis] = useState<AIAnalysisResponse | null>(null);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
-  const [status, setStatus] = useState<'processing' | 'completed' | 'failed'>('processing');
+  const [status, setStatus] = useState<AnalysisStatus>('queued');
   const [selectedRisk, setSelectedRisk] = useState<Risk | null>(null);
   const [selectedFix, setSelectedFix] = useState<AutoFix | null>(null);
 
@@ -28,13 +34,13 @@ export default function AnalysisPage() {
           const data = await AegisApi.getAnalysis(analysisId);
           setAnalysis(data);
           setLoading(false);
-        } else if (statusData.status === 'failed') {
-          setError('Analysis failed. Please try again.');
+        } else if (isFinished(statusData.status)) {
+          setError(statusData.error || 'Analysis failed. Please try again.');
           setLoading(false);
         }
 
         // If still processing, set up polling
-        if (statusData.status === 'processing') {
+        if (isInProgress(statusData.status)) {
           const interval = setInterval(async () => {
             try {
               const newStatus = await AegisApi.getAnalysisStatus(analysisId);
@@ -45,8 +51,8 @@ export default function AnalysisPage() {
                 setAnalysis(data);
                 clearInterval(interval);
                 setLoading(false);
-              } else if (newStatus.status === 'failed') {
-                setError('Analysis failed. Please try again.');
+              } else if (isFinished(newStatus.status)) {
+                setError(newStatus.error || 'Analysis failed. Please try again.');
                 clearInterval(interval);
                 setLoading(false);
               }
@@ -129,7 +135,7 @@ export default function AnalysisPage() {
     );
   }
 
-  if (status === 'processing') {
+  if (isInProgress(status)) {
     return (
       <div className="min-h-screen flex items-center justify-center">
         <div className="text-center space-y-4">
@@ -176,9 +182,38 @@ export default function AnalysisPage() {
         <div className="text-right">
           <div className="text-2xl font-bold text-white">{complianceScore}/100</div>
           <div className="text-sm text-gray-400">Compliance Score</div>
+          {(analysis.dependencies || []).length > 0 && (
+            <div className="text-xs text-gray-400 mt-2 space-x-2">
+              <span>SBOM:</span>
+              <a href={AegisApi.sbomUrl(params.id as string, 'cyclonedx')} className="text-purple-300 hover:text-purple-200">CycloneDX</a>
+              <a href={AegisApi.sbomUrl(params.id as string, 'spdx')} className="text-purple-300 hover:text-purple-200">SPDX</a>
+            </div>
+          )}
         </div>
       </div>
 
+      {/* Repository policy (.aegis.yml) */}
+      {analysis.gate && (
+        <div className={`rounded-xl p-4 mb-6 border ${analysis.gate.failed ? 'bg-red-500/20 border-red-500/30' : 'bg-green-500/20 border-green-500/30'}`}>
+          <div className="font-semibold text-white">
+            {analysis.gate.failed ? '❌ Policy check failed' : '✅ Policy check passed'}
+          </div>
+          {(analysis.gate.reasons || []).map((reason, index) => (
+            <div key={index} className="text-sm text-gray-300 mt-1">{reason}</div>
+          ))}
+        </div>
+      )}
+      {analysis.repo_config && (analysis.repo_config.errors || []).length > 0 && (
+        <div className="bg-yellow-500/20 border border-yellow-500/30 rounded-xl p-4 mb-6">
+          <div className="font-semibold text-white">⚠️ {analysis.repo_config.path} problems - these settings were ignored</div>
+          <ul className="list-disc list-inside text-sm text-gray-300 mt-1">
+            {(analysis.repo_config.errors || []).map((error, index) => (
+              <li key={index}>{error}</li>
+            ))}
+          </ul>
+        </div>
+      )}
+
       {/* Summary Cards */}
       <div className="grid grid-cols-1 md:grid-cols-4 gap-6 mb-8">
         <div className="bg-red-500/20 border border-red-500/30 rounded-xl p-6">
@@ -225,6 +260,11 @@ export default function AnalysisPage() {
                     <p className="text-gray-300 text-sm mb-2">{risk.description}</p>
                     <div className="text-xs text-gray-400">
                       {risk.file}:{risk.line} • {risk.impact}
+                      {risk.cvss_score !== undefined && ` • CVSS ${risk.cvss_score.toFixed(1)}`}
+                      {risk.remediation_priority && ` • ${risk.remediation_priority} priority`}
+                      {risk.verified === false && ` • unverified`}
+                      {risk.correction && ` (${risk.correction})`}
+                      {risk.source === 'rule' && ` • rule: ${risk.rule_id}`}
                     </div>
                   </div>
                 ))}
@@ -249,6 +289,7 @@ export default function AnalysisPage() {
                     <h3 className="font-semibold text-white text-sm">{risk.title}</h3>
                     <div className="text-xs text-gray-400 mt-1">
                       {risk.file}:{risk.line}
+                      {risk.cvss_score !== undefined && ` • CVSS ${risk.cvss_score.toFixed(1)}`}
                     </div>
                   </div>
                 ))}
@@ -273,6 +314,32 @@ export default function AnalysisPage() {
                     <h3 className="font-semibold text-white text-sm">{risk.title}</h3>
                     <div className="text-xs text-gray-400 mt-1">
                       {risk.file}:{risk.line}
+                      {risk.cvss_score !== undefined && ` • CVSS ${risk.cvss_score.toFixed(1)}`}
+                    </div>
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
+
+          {/* Secrets found in git history */}
+          {(analysis.secret_history || []).length > 0 && (
+            <div className="bg-white/5 backdrop-blur-lg rounded-2xl p-6 border border-purple-500/30">
+              <h2 className="text-xl font-bold text-white mb-4 flex items-center">
+                <span className="w-3 h-3 bg-purple-500 rounded-full mr-2"></span>
+                Secrets in Git History ({(analysis.secret_history || []).length})
+              </h2>
+              <div className="space-y-3">
+                {(analysis.secret_history || []).map((secret, index) => (
+                  <div
+                    key={index}
+                    className="p-3 bg-purple-500/10 rounded-lg border border-purple-500/20 cursor-pointer hover:bg-purple-500/15 transition-colors"
+                    onClick={() => setSelectedRisk(secret)}
+                  >
+                    <h3 className="font-semibold text-white text-sm">{secret.title}</h3>
+                    <div className="text-xs text-gray-400 mt-1">
+                      {secret.file}:{secret.line} • {secret.commit.slice(0, 7)} by {secret.author}
+                      {secret.still_present ? ' • still at HEAD' : ' • removed, rotate the key'}
                     </div>
                   </div>
                 ))}
@@ -402,6 +469,32 @@ export default function AnalysisPage() {
                 <h4 className="font-semibold text-white mb-2">Impact</h4>
                 <p className="text-gray-300">{selectedRisk.impact}</p>
               </div>
+              {selectedRisk.advisory && (
+                <div>
+                  <h4 className="font-semibold text-white mb-2">Advisory</h4>
+                  <p className="text-gray-300 text-sm">
+                    {selectedRisk.advisory.id}
+                    {(selectedRisk.advisory.cves || []).length > 0 && ` (${selectedRisk.advisory.cves!.join(', ')})`}
+                  </p>
+                  <p className="text-gray-400 text-sm">
+                    {selectedRisk.advisory.package} {selectedRisk.advisory.version} • affected {selectedRisk.advisory.affected}
+                    {(selectedRisk.advisory.fixed || []).length > 0 && ` • fixed in ${selectedRisk.advisory.fixed!.join(', ')}`}
+                  </p>
+                </div>
+              )}
+              {(selectedRisk.taint_path || []).length > 0 && (
+                <div>
+                  <h4 className="font-semibold text-white mb-2">Data Flow</h4>
+                  <ol className="space-y-1">
+                    {selectedRisk.taint_path!.map((step, index) => (
+                      <li key={index} className="text-sm">
+                        <span className="text-gray-400">{step.kind} • line {step.line}{step.expression && ` • ${step.expression}`}</span>
+                        <pre className="bg-slate-900 rounded px-2 py-1 text-gray-300 overflow-x-auto">{step.code}</pre>
+                      </li>
+                    ))}
+                  </ol>
+                </div>
+              )}
               <div>
                 <h4 className="font-semibold text-white mb-2">Confidence</h4>
                 <div className="flex items-center space-x-2">
@@ -6,14 +6,26 @@ import {
 } from '@/types';
 
 // Response interfaces
+export type AnalysisStatus =
+  | 'queued'
+  | 'cloning'
+  | 'analyzing'
+  | 'completed'
+  | 'failed'
+  | 'failed_parse'
+  | 'cancelled'
+  | 'timed_out';
+
 export interface AnalysisTriggerResponse {
   analysis_id: string;
-  status: 'processing' | 'completed' | 'failed';
+  status: AnalysisStatus;
   message: string;
 }
 
 export interface AnalysisStatusResponse {
-  status: 'processing' | 'completed' | 'failed';
+  status: AnalysisStatus;
+  queue_position?: number;
+  error?: string;
 }
 
 export interface ApplyFixResponse {
@@ -204,6 +216,14 @@ export class AegisApi {
     );
   }
 
+  static async cancelAnalysis(analysisId: string, accessToken?: string): Promise<{ status: AnalysisStatus }> {
+    return this.fetchWithErrorHandling<{ status: AnalysisStatus }>(
+      `${API_BASE_URL}/api/analysis/${analysisId}`,
+      { method: 'DELETE' },
+      accessToken
+    );
+  }
+
   static async applyFix(analysisId: string, fixIndex: number, accessToken?: string): Promise<ApplyFixResponse> {
     return this.fetchWithErrorHandling<ApplyFixResponse>(
       `${API_BASE_URL}/api/analysis/${analysisId}/fix/${fixIndex}`,
@@ -212,6 +232,11 @@ export class AegisApi {
     );
   }
 
+  // Download URL for the scan's SBOM - served as a file attachment
+  static sbomUrl(analysisId: string, format: 'cyclonedx' | 'spdx' = 'cyclonedx'): string {
+    return `${API_BASE_URL}/api/analysis/${analysisId}/sbom?format=${format}`;
+  }
+
   // Health check method
   static async healthCheck(): Promise<{ message: string; status: string }> {
     return this.fetchWithErrorHandling<{ message: string; status: string }>(
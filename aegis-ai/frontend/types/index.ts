@@ -6,6 +6,33 @@ export interface Risk {
   impact: string;
   confidence: number;
   code_snippet: string;
+  cvss_score?: number;
+  exploitation_complexity?: 'Low' | 'Medium' | 'High';
+  remediation_priority?: 'Immediate' | 'High' | 'Medium' | 'Low';
+  compliance_violations?: string[];
+  verified?: boolean;
+  correction?: string;
+  source?: 'ai' | 'rule';
+  rule_id?: string;
+  advisory?: DependencyAdvisory;
+  taint_path?: TaintStep[];
+}
+
+export interface TaintStep {
+  kind: 'source' | 'step' | 'sink';
+  line: number;
+  code: string;
+  expression?: string;
+}
+
+export interface DependencyAdvisory {
+  id: string;
+  cves?: string[];
+  ecosystem: string;
+  package: string;
+  version: string;
+  affected: string;
+  fixed?: string[];
 }
 
 export interface AutoFix {
@@ -46,6 +73,58 @@ export interface AIAnalysisResponse {
   summary: AnalysisSummary;
   architecture?: ArchitectureAnalysis;
   compliance?: ComplianceAnalysis;
+  coverage?: CoverageReport;
+  prompt_version?: string;
+  secret_history?: HistorySecret[];
+  dependencies?: Dependency[];
+  repo_config?: RepoConfigReport;
+  gate?: PolicyGate;
+}
+
+export interface RepoConfigReport {
+  path: string;
+  errors?: string[];
+  disabled_analyzers?: string[];
+  suppressed_findings: number;
+}
+
+export interface PolicyGate {
+  failed: boolean;
+  reasons?: string[];
+}
+
+export interface Dependency {
+  ecosystem: 'Go' | 'npm' | 'PyPI' | 'Maven' | 'RubyGems';
+  name: string;
+  version: string;
+  manifest: string;
+  line: number;
+  direct: boolean;
+}
+
+export interface HistorySecret extends Risk {
+  commit: string;
+  author: string;
+  committed_at: string;
+  still_present: boolean;
+}
+
+export interface FileCoverage {
+  path: string;
+  status: 'full' | 'partial' | 'skipped';
+  reason?: string;
+  total_lines: number;
+  scanned_lines: number;
+}
+
+export interface CoverageReport {
+  total_files: number;
+  fully_scanned: number;
+  partially_scanned: number;
+  skipped: number;
+  batches: number;
+  failed_batches: number;
+  files: FileCoverage[];
 }
 
 export interface PullRequestEvent {